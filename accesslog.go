@@ -0,0 +1,240 @@
+package zap
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yimi-go/logging"
+)
+
+// AccessLogOptions configures AccessLog.
+type AccessLogOptions struct {
+	// Logger is the name of the logger of access entries. "http.access" as default.
+	Logger string `json:"logger,omitempty" yaml:"logger,omitempty"`
+	// Levels are the levels of access entries mapped by status classes, "1xx" to "5xx".
+	// InfoLevel as default, WarnLevel for "4xx" and ErrorLevel for "5xx". OffLevel to skip a class.
+	Levels map[string]logging.Level `json:"levels,omitempty" yaml:"levels,omitempty"`
+	// ExcludePaths are the paths of requests not logged, e.g. "/healthz".
+	// Paths ending with "*" are prefixes, e.g. "/debug/*".
+	ExcludePaths []string `json:"exclude_paths,omitempty" yaml:"exclude_paths,omitempty,flow"`
+	// Headers are the request headers logged in the "headers" group.
+	Headers []string `json:"headers,omitempty" yaml:"headers,omitempty,flow"`
+	// RedactHeaders are the headers whose values are logged as "[REDACTED]".
+	// "Authorization", "Proxy-Authorization" and "Cookie" as default.
+	RedactHeaders []string `json:"redact_headers,omitempty" yaml:"redact_headers,omitempty,flow"`
+	// RequestIDHeader is the header of request IDs, which are generated for requests without it,
+	// and set to responses. "X-Request-Id" as default.
+	RequestIDHeader string `json:"request_id_header,omitempty" yaml:"request_id_header,omitempty"`
+}
+
+// Defaulted returns a new AccessLogOptions filling blank items with default values.
+func (a AccessLogOptions) Defaulted() AccessLogOptions {
+	a.Logger = strings.TrimSpace(a.Logger)
+	if a.Logger == "" {
+		a.Logger = "http.access"
+	}
+	levels := map[string]logging.Level{
+		"1xx": logging.InfoLevel,
+		"2xx": logging.InfoLevel,
+		"3xx": logging.InfoLevel,
+		"4xx": logging.WarnLevel,
+		"5xx": logging.ErrorLevel,
+	}
+	for class, level := range a.Levels {
+		levels[strings.ToLower(strings.TrimSpace(class))] = level
+	}
+	a.Levels = levels
+	a.ExcludePaths = trimStrings(a.ExcludePaths)
+	a.Headers = trimStrings(a.Headers)
+	a.RedactHeaders = trimStrings(a.RedactHeaders)
+	if len(a.RedactHeaders) == 0 {
+		a.RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+	}
+	a.RequestIDHeader = strings.TrimSpace(a.RequestIDHeader)
+	if a.RequestIDHeader == "" {
+		a.RequestIDHeader = "X-Request-Id"
+	}
+	return a
+}
+
+// trimStrings returns the strings trimmed, dropping empty ones.
+func trimStrings(ss []string) []string {
+	res := make([]string, 0, len(ss))
+	for _, s := range ss {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// AccessLog returns a middleware logging requests by a logger of the factory.
+//
+// The request ID is added to request contexts by logging.NewContext,
+// so that loggers of handlers created by logging.WithContextField carry it as well.
+//
+// Requests whose handlers panic are logged with status 500 if no status is written,
+// and "panic" true, before the panic goes on.
+func AccessLog(factory logging.Factory, options AccessLogOptions) func(http.Handler) http.Handler {
+	options = options.Defaulted()
+	logger := factory.Logger(options.Logger)
+	redact := make(map[string]bool, len(options.RedactHeaders))
+	for _, h := range options.RedactHeaders {
+		redact[http.CanonicalHeaderKey(h)] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if excludedPath(options.ExcludePaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			id := r.Header.Get(options.RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(options.RequestIDHeader, id)
+			idField := logging.String("request_id", id)
+			r = r.WithContext(logging.NewContext(r.Context(), idField))
+			rw := &accessLogWriter{ResponseWriter: w}
+			panicked := true
+			// Logged in a defer so that requests whose handlers panic are logged as well.
+			defer func() {
+				if panicked && rw.code == 0 {
+					rw.code = http.StatusInternalServerError
+				}
+				level := options.Levels[statusClass(rw.status())]
+				if !logger.Enabled(level) {
+					return
+				}
+				fields := []logging.Field{
+					logging.String("method", r.Method),
+					logging.String("path", r.URL.Path),
+					logging.Int("status", rw.status()),
+					logging.Int64("bytes", rw.bytes),
+					logging.Duration("duration", time.Since(start)),
+					logging.String("remote_addr", r.RemoteAddr),
+					logging.String("user_agent", r.UserAgent()),
+					idField,
+				}
+				if panicked {
+					fields = append(fields, logging.Bool("panic", true))
+				}
+				if len(options.Headers) != 0 {
+					headers := make([]logging.Field, 0, len(options.Headers))
+					for _, h := range options.Headers {
+						value := r.Header.Get(h)
+						if value != "" && redact[http.CanonicalHeaderKey(h)] {
+							value = "[REDACTED]"
+						}
+						headers = append(headers, logging.String(h, value))
+					}
+					fields = append(fields, Group("headers", headers...))
+				}
+				Logw(logger, level, "http request", fields...)
+			}()
+			next.ServeHTTP(rw.exposed(), r)
+			panicked = false
+		})
+	}
+}
+
+func excludedPath(excludes []string, path string) bool {
+	for _, e := range excludes {
+		if e == path || strings.HasSuffix(e, "*") && strings.HasPrefix(path, e[:len(e)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "5xx"
+	}
+	return string(rune('0'+status/100)) + "xx"
+}
+
+// newRequestID returns a random 16 bytes request ID in hex.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// accessLogWriter records the status and the size of responses.
+type accessLogWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (w *accessLogWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Hijack hijacks the connection if supported, used by libraries like gorilla/websocket.
+// Requests hijacked without a status written are logged with status 101.
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.code == 0 {
+		w.code = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying ResponseWriter, used by http.ResponseController.
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// exposed returns the writer handlers are served with, which is an http.Flusher
+// only if the underlying ResponseWriter is, so that handlers checking it do not flush in vain.
+func (w *accessLogWriter) exposed() http.ResponseWriter {
+	if _, ok := w.ResponseWriter.(http.Flusher); ok {
+		return flushLogWriter{w}
+	}
+	return w
+}
+
+func (w *accessLogWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// flushLogWriter is an accessLogWriter of a ResponseWriter supporting flushing.
+type flushLogWriter struct {
+	*accessLogWriter
+}
+
+// Flush flushes the response, which writes the status 200 if not written yet.
+func (w flushLogWriter) Flush() {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
@@ -0,0 +1,235 @@
+package zap
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAccessLogOptions_Defaulted(t *testing.T) {
+	got := AccessLogOptions{
+		Levels:       map[string]logging.Level{" 2XX ": logging.DebugLevel},
+		ExcludePaths: []string{" /healthz ", " "},
+	}.Defaulted()
+	assert.Equal(t, AccessLogOptions{
+		Logger: "http.access",
+		Levels: map[string]logging.Level{
+			"1xx": logging.InfoLevel,
+			"2xx": logging.DebugLevel,
+			"3xx": logging.InfoLevel,
+			"4xx": logging.WarnLevel,
+			"5xx": logging.ErrorLevel,
+		},
+		ExcludePaths:    []string{"/healthz"},
+		Headers:         []string{},
+		RedactHeaders:   []string{"Authorization", "Proxy-Authorization", "Cookie"},
+		RequestIDHeader: "X-Request-Id",
+	}, got)
+}
+
+func TestExcludedPath(t *testing.T) {
+	excludes := []string{"/healthz", "/debug/*"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/healthz", want: true},
+		{path: "/healthz/foo", want: false},
+		{path: "/debug/pprof", want: true},
+		{path: "/debug", want: false},
+		{path: "/", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, excludedPath(excludes, tt.path))
+		})
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: 101, want: "1xx"},
+		{status: 200, want: "2xx"},
+		{status: 304, want: "3xx"},
+		{status: 404, want: "4xx"},
+		{status: 503, want: "5xx"},
+		{status: 0, want: "5xx"},
+		{status: 600, want: "5xx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, statusClass(tt.status))
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-accesslog", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(OutputPaths("test-accesslog://"), Encoding("logfmt")))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	middleware := AccessLog(factory, AccessLogOptions{
+		ExcludePaths: []string{"/healthz"},
+		Headers:      []string{"Authorization", "X-Tenant"},
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.WithContextField(r.Context(), factory.Logger("handler")).Infow("handling")
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			_, _ = w.Write([]byte("hello"))
+		}
+	}))
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		r.Header.Set("User-Agent", "test")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/hello", http.Header{
+		"X-Request-Id":  {"req-1"},
+		"Authorization": {"Bearer secret"},
+		"X-Tenant":      {"acme"},
+	})
+	assert.Equal(t, "req-1", w.Header().Get("X-Request-Id"))
+	assert.Len(t, sink.entries, 2)
+	assert.Equal(t, "handler", sink.entries[0].LoggerName)
+	assert.Contains(t, sink.lines[0], "request_id=req-1")
+	assert.Equal(t, "http.access", sink.entries[1].LoggerName)
+	assert.Equal(t, zapcore.InfoLevel, sink.entries[1].Level)
+	for _, s := range []string{
+		"method=GET", "path=/hello", "status=200", "bytes=5", "remote_addr=192.0.2.1:1234",
+		"user_agent=test", "request_id=req-1", "headers.Authorization=[REDACTED]", "headers.X-Tenant=acme",
+	} {
+		assert.Contains(t, sink.lines[1], s)
+	}
+	assert.NotContains(t, sink.lines[1], "secret")
+
+	w = serve("/missing", nil)
+	id := w.Header().Get("X-Request-Id")
+	assert.Len(t, id, 32)
+	assert.Len(t, sink.entries, 4)
+	assert.Equal(t, zapcore.WarnLevel, sink.entries[3].Level)
+	assert.Contains(t, sink.lines[3], "status=404")
+	assert.Contains(t, sink.lines[3], "request_id="+id)
+
+	serve("/healthz", nil)
+	assert.Len(t, sink.entries, 5)
+	assert.False(t, strings.Contains(sink.lines[4], "http request"))
+}
+
+func TestAccessLog_off(t *testing.T) {
+	var written bool
+	factory := logging.NewNopLoggerFactory()
+	handler := AccessLog(factory, AccessLogOptions{Levels: map[string]logging.Level{"2xx": logging.OffLevel}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+			written = true
+		}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(context.Background()))
+	assert.True(t, written)
+}
+
+func TestAccessLog_panic(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-accesslog-panic", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(OutputPaths("test-accesslog-panic://"), Encoding("logfmt")))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	handler := AccessLog(factory, AccessLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}))
+	assert.PanicsWithValue(t, "oops", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	assert.Len(t, sink.entries, 1)
+	assert.Equal(t, zapcore.ErrorLevel, sink.entries[0].Level)
+	assert.Contains(t, sink.lines[0], "status=500")
+	assert.Contains(t, sink.lines[0], "panic=true")
+}
+
+type tHijackWriter struct {
+	http.ResponseWriter
+	conn net.Conn
+}
+
+func (w *tHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
+
+func TestAccessLogWriter_Hijack(t *testing.T) {
+	w := &accessLogWriter{ResponseWriter: httptest.NewRecorder()}
+	_, _, err := w.Hijack()
+	assert.Equal(t, http.ErrNotSupported, err)
+
+	conn, peer := net.Pipe()
+	defer func() {
+		_ = conn.Close()
+		_ = peer.Close()
+	}()
+	w = &accessLogWriter{ResponseWriter: &tHijackWriter{ResponseWriter: httptest.NewRecorder(), conn: conn}}
+	var h http.Hijacker = w
+	got, rw, err := h.Hijack()
+	assert.Nil(t, err)
+	assert.Same(t, conn, got)
+	assert.NotNil(t, rw)
+	assert.Equal(t, http.StatusSwitchingProtocols, w.status())
+}
+
+func TestAccessLogWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &accessLogWriter{ResponseWriter: rec}
+	assert.Equal(t, http.StatusOK, w.status())
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusInternalServerError)
+	n, err := w.Write([]byte("abc"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, http.StatusCreated, w.status())
+	assert.Equal(t, int64(3), w.bytes)
+	assert.Same(t, rec, w.Unwrap())
+}
+
+type tPlainWriter struct {
+	http.ResponseWriter
+}
+
+func TestAccessLogWriter_exposed(t *testing.T) {
+	// Flushers only if the underlying ResponseWriter is.
+	w := &accessLogWriter{ResponseWriter: tPlainWriter{httptest.NewRecorder()}}
+	_, ok := w.exposed().(http.Flusher)
+	assert.False(t, ok)
+	assert.Same(t, w, w.exposed())
+
+	rec := httptest.NewRecorder()
+	w = &accessLogWriter{ResponseWriter: rec}
+	f, ok := w.exposed().(http.Flusher)
+	assert.True(t, ok)
+	f.Flush()
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, http.StatusOK, w.code)
+	_, ok = w.exposed().(http.Hijacker)
+	assert.True(t, ok)
+}
@@ -0,0 +1,447 @@
+package zap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var binaryPool = buffer.NewPool()
+
+// binaryFormat writes values in a binary serialization format.
+//
+// Maps and arrays are written by their starts and ends, the ends get the offsets of the starts in the buffer
+// and the numbers of their pairs or elements, so that formats can write the numbers in the headers.
+type binaryFormat interface {
+	appendMapStart(buf *buffer.Buffer)
+	appendMapEnd(buf *buffer.Buffer, start, count int)
+	appendArrayStart(buf *buffer.Buffer)
+	appendArrayEnd(buf *buffer.Buffer, start, count int)
+	appendString(buf *buffer.Buffer, s string)
+	appendBytes(buf *buffer.Buffer, b []byte)
+	appendInt(buf *buffer.Buffer, i int64)
+	appendUint(buf *buffer.Buffer, u uint64)
+	appendFloat64(buf *buffer.Buffer, f float64)
+	appendFloat32(buf *buffer.Buffer, f float32)
+	appendBool(buf *buffer.Buffer, b bool)
+	appendTime(buf *buffer.Buffer, t time.Time)
+}
+
+// binaryMap is a map being written.
+type binaryMap struct {
+	// start is the offset of the start of the map in the buffer.
+	start int
+	// count is the number of pairs written.
+	count int
+}
+
+// binaryEncoder writes entries as maps in a binary format, keeping the types of fields.
+//
+// Times are written as times of the format, durations as integers of nanoseconds, and binaries as byte strings.
+// Entries are not terminated by line endings, as they are self-delimited.
+type binaryEncoder struct {
+	*zapcore.EncoderConfig
+	format binaryFormat
+	buf    *buffer.Buffer
+	// maps are the maps being written, the root map first and the innermost open namespace or object last.
+	// The start of the root map is not in the buffer of accumulated fields.
+	maps []binaryMap
+}
+
+func newBinaryEncoder(config *zapcore.EncoderConfig, format binaryFormat) *binaryEncoder {
+	return &binaryEncoder{
+		EncoderConfig: config,
+		format:        format,
+		buf:           binaryPool.Get(),
+		maps:          []binaryMap{{start: -1}},
+	}
+}
+
+func (enc *binaryEncoder) Clone() zapcore.Encoder {
+	clone := &binaryEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		format:        enc.format,
+		buf:           binaryPool.Get(),
+		maps:          make([]binaryMap, len(enc.maps)),
+	}
+	copy(clone.maps, enc.maps)
+	_, _ = clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *binaryEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &binaryEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		format:        enc.format,
+		buf:           binaryPool.Get(),
+		maps:          []binaryMap{{start: 0}},
+	}
+	final.format.appendMapStart(final.buf)
+	if final.TimeKey != "" {
+		final.addKey(final.TimeKey)
+		final.format.appendTime(final.buf, ent.Time)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		arr := &flatArrayEncoder{config: final.EncoderConfig}
+		final.EncodeLevel(ent.Level, arr)
+		level := arr.value()
+		if level == "" {
+			level = ent.Level.String()
+		}
+		final.AddString(final.LevelKey, level)
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		nameEncoder := final.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		arr := &flatArrayEncoder{config: final.EncoderConfig}
+		nameEncoder(ent.LoggerName, arr)
+		name := arr.value()
+		if name == "" {
+			name = ent.LoggerName
+		}
+		final.AddString(final.NameKey, name)
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			arr := &flatArrayEncoder{config: final.EncoderConfig}
+			final.EncodeCaller(ent.Caller, arr)
+			caller := arr.value()
+			if caller == "" {
+				caller = ent.Caller.String()
+			}
+			final.AddString(final.CallerKey, caller)
+		}
+		if final.FunctionKey != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+	// Maps of accumulated fields start in the buffer after the fields of the entry.
+	offset := final.buf.Len()
+	_, _ = final.buf.Write(enc.buf.Bytes())
+	final.maps[0].count += enc.maps[0].count
+	for _, m := range enc.maps[1:] {
+		final.maps = append(final.maps, binaryMap{start: offset + m.start, count: m.count})
+	}
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.closeMaps(1)
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+	final.closeMaps(0)
+	return final.buf, nil
+}
+
+// addKey appends the key to the innermost map.
+func (enc *binaryEncoder) addKey(key string) {
+	enc.format.appendString(enc.buf, key)
+	enc.maps[len(enc.maps)-1].count++
+}
+
+// openMap starts a map nested in the innermost map.
+func (enc *binaryEncoder) openMap() {
+	enc.maps = append(enc.maps, binaryMap{start: enc.buf.Len()})
+	enc.format.appendMapStart(enc.buf)
+}
+
+// closeMaps ends the maps till there are depth maps left.
+func (enc *binaryEncoder) closeMaps(depth int) {
+	for len(enc.maps) > depth {
+		m := enc.maps[len(enc.maps)-1]
+		enc.maps = enc.maps[:len(enc.maps)-1]
+		enc.format.appendMapEnd(enc.buf, m.start, m.count)
+	}
+}
+
+func (enc *binaryEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	enc.addKey(key)
+	return (&binaryArrayEncoder{enc: enc}).appendArray(marshaler)
+}
+
+func (enc *binaryEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	enc.addKey(key)
+	depth := len(enc.maps)
+	enc.openMap()
+	err := marshaler.MarshalLogObject(enc)
+	enc.closeMaps(depth)
+	return err
+}
+
+func (enc *binaryEncoder) AddBinary(key string, value []byte) {
+	enc.addKey(key)
+	enc.format.appendBytes(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddByteString(key string, value []byte) {
+	enc.addKey(key)
+	enc.format.appendString(enc.buf, string(value))
+}
+
+func (enc *binaryEncoder) AddBool(key string, value bool) {
+	enc.addKey(key)
+	enc.format.appendBool(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddComplex128(key string, value complex128) {
+	enc.addKey(key)
+	(&binaryArrayEncoder{enc: enc}).AppendComplex128(value)
+}
+
+func (enc *binaryEncoder) AddComplex64(key string, value complex64) {
+	enc.AddComplex128(key, complex128(value))
+}
+
+func (enc *binaryEncoder) AddDuration(key string, value time.Duration) {
+	enc.AddInt64(key, int64(value))
+}
+
+func (enc *binaryEncoder) AddFloat64(key string, value float64) {
+	enc.addKey(key)
+	enc.format.appendFloat64(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddFloat32(key string, value float32) {
+	enc.addKey(key)
+	enc.format.appendFloat32(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddInt(key string, value int) { enc.AddInt64(key, int64(value)) }
+
+func (enc *binaryEncoder) AddInt64(key string, value int64) {
+	enc.addKey(key)
+	enc.format.appendInt(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+func (enc *binaryEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+func (enc *binaryEncoder) AddInt8(key string, value int8)   { enc.AddInt64(key, int64(value)) }
+
+func (enc *binaryEncoder) AddString(key, value string) {
+	enc.addKey(key)
+	enc.format.appendString(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddTime(key string, value time.Time) {
+	enc.addKey(key)
+	enc.format.appendTime(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddUint(key string, value uint) { enc.AddUint64(key, uint64(value)) }
+
+func (enc *binaryEncoder) AddUint64(key string, value uint64) {
+	enc.addKey(key)
+	enc.format.appendUint(enc.buf, value)
+}
+
+func (enc *binaryEncoder) AddUint32(key string, value uint32)   { enc.AddUint64(key, uint64(value)) }
+func (enc *binaryEncoder) AddUint16(key string, value uint16)   { enc.AddUint64(key, uint64(value)) }
+func (enc *binaryEncoder) AddUint8(key string, value uint8)     { enc.AddUint64(key, uint64(value)) }
+func (enc *binaryEncoder) AddUintptr(key string, value uintptr) { enc.AddUint64(key, uint64(value)) }
+
+func (enc *binaryEncoder) AddReflected(key string, value any) error {
+	s, err := reflectedString(value)
+	if err != nil {
+		return err
+	}
+	enc.AddString(key, s)
+	return nil
+}
+
+func (enc *binaryEncoder) OpenNamespace(key string) {
+	enc.addKey(key)
+	enc.openMap()
+}
+
+// binaryArrayEncoder writes array elements to the buffer of the encoder.
+type binaryArrayEncoder struct {
+	enc   *binaryEncoder
+	count int
+}
+
+// appendArray appends the array as an element, or a value of the encoder if the array encoder is new.
+func (arr *binaryArrayEncoder) appendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := &binaryArrayEncoder{enc: arr.enc}
+	start := arr.enc.buf.Len()
+	arr.enc.format.appendArrayStart(arr.enc.buf)
+	err := marshaler.MarshalLogArray(nested)
+	arr.enc.format.appendArrayEnd(arr.enc.buf, start, nested.count)
+	arr.count++
+	return err
+}
+
+func (arr *binaryArrayEncoder) AppendBool(v bool) {
+	arr.count++
+	arr.enc.format.appendBool(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendByteString(v []byte) {
+	arr.AppendString(string(v))
+}
+
+func (arr *binaryArrayEncoder) AppendComplex128(v complex128) {
+	arr.count++
+	// Complex numbers are written as arrays of their real and imaginary parts.
+	start := arr.enc.buf.Len()
+	arr.enc.format.appendArrayStart(arr.enc.buf)
+	arr.enc.format.appendFloat64(arr.enc.buf, real(v))
+	arr.enc.format.appendFloat64(arr.enc.buf, imag(v))
+	arr.enc.format.appendArrayEnd(arr.enc.buf, start, 2)
+}
+
+func (arr *binaryArrayEncoder) AppendComplex64(v complex64) { arr.AppendComplex128(complex128(v)) }
+
+func (arr *binaryArrayEncoder) AppendFloat64(v float64) {
+	arr.count++
+	arr.enc.format.appendFloat64(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendFloat32(v float32) {
+	arr.count++
+	arr.enc.format.appendFloat32(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendInt(v int) { arr.AppendInt64(int64(v)) }
+
+func (arr *binaryArrayEncoder) AppendInt64(v int64) {
+	arr.count++
+	arr.enc.format.appendInt(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendInt32(v int32) { arr.AppendInt64(int64(v)) }
+func (arr *binaryArrayEncoder) AppendInt16(v int16) { arr.AppendInt64(int64(v)) }
+func (arr *binaryArrayEncoder) AppendInt8(v int8)   { arr.AppendInt64(int64(v)) }
+
+func (arr *binaryArrayEncoder) AppendString(v string) {
+	arr.count++
+	arr.enc.format.appendString(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendUint(v uint) { arr.AppendUint64(uint64(v)) }
+
+func (arr *binaryArrayEncoder) AppendUint64(v uint64) {
+	arr.count++
+	arr.enc.format.appendUint(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendUint32(v uint32)   { arr.AppendUint64(uint64(v)) }
+func (arr *binaryArrayEncoder) AppendUint16(v uint16)   { arr.AppendUint64(uint64(v)) }
+func (arr *binaryArrayEncoder) AppendUint8(v uint8)     { arr.AppendUint64(uint64(v)) }
+func (arr *binaryArrayEncoder) AppendUintptr(v uintptr) { arr.AppendUint64(uint64(v)) }
+
+func (arr *binaryArrayEncoder) AppendDuration(v time.Duration) { arr.AppendInt64(int64(v)) }
+
+func (arr *binaryArrayEncoder) AppendTime(v time.Time) {
+	arr.count++
+	arr.enc.format.appendTime(arr.enc.buf, v)
+}
+
+func (arr *binaryArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	return arr.appendArray(marshaler)
+}
+
+func (arr *binaryArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	arr.count++
+	obj := &binaryEncoder{EncoderConfig: arr.enc.EncoderConfig, format: arr.enc.format, buf: arr.enc.buf}
+	obj.openMap()
+	err := marshaler.MarshalLogObject(obj)
+	obj.closeMaps(0)
+	return err
+}
+
+func (arr *binaryArrayEncoder) AppendReflected(value any) error {
+	s, err := reflectedString(value)
+	if err != nil {
+		return err
+	}
+	arr.AppendString(s)
+	return nil
+}
+
+// DecodeBinary converts entries written in a binary encoding, "cbor" or "msgpack", to JSON lines for inspection.
+//
+// Times are converted to RFC 3339 strings, byte strings to base64 strings,
+// and NaN and infinite numbers to strings.
+func DecodeBinary(encoding string, r io.Reader, w io.Writer) error {
+	var decode func(r *bufio.Reader, buf *buffer.Buffer) error
+	switch encoding {
+	case "cbor":
+		decode = decodeCBOR
+	case "msgpack":
+		decode = decodeMsgpack
+	default:
+		return fmt.Errorf("unknown binary encoding: %q", encoding)
+	}
+	br := bufio.NewReader(r)
+	buf := &buffer.Buffer{}
+	for {
+		if _, err := br.Peek(1); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		buf.Reset()
+		if err := decode(br, buf); err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		buf.AppendByte('\n')
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// readFull reads n bytes.
+func readFull(r *bufio.Reader, n uint64) ([]byte, error) {
+	if n > 1<<30 {
+		return nil, fmt.Errorf("length too large: %d", n)
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+// appendJSONKey appends the JSON of a map key, which is quoted unless it is a string already.
+func appendJSONKey(buf *buffer.Buffer, key []byte) {
+	if len(key) != 0 && key[0] == '"' {
+		_, _ = buf.Write(key)
+		return
+	}
+	appendJSONString(buf, string(key))
+}
+
+// appendJSONFloat appends the float as a JSON number, or a string if it is NaN or infinite.
+func appendJSONFloat(buf *buffer.Buffer, f float64) {
+	switch {
+	case math.IsNaN(f):
+		buf.AppendString(`"NaN"`)
+	case math.IsInf(f, 1):
+		buf.AppendString(`"+Inf"`)
+	case math.IsInf(f, -1):
+		buf.AppendString(`"-Inf"`)
+	default:
+		buf.AppendFloat(f, 64)
+	}
+}
+
+// appendJSONTime appends the time as an RFC 3339 string in UTC.
+func appendJSONTime(buf *buffer.Buffer, t time.Time) {
+	buf.AppendByte('"')
+	buf.AppendString(t.UTC().Format(time.RFC3339Nano))
+	buf.AppendByte('"')
+}
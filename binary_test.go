@@ -0,0 +1,163 @@
+package zap
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// decodeBinary decodes the entries to JSON lines.
+func decodeBinary(t *testing.T, encoding string, b []byte) string {
+	t.Helper()
+	w := &bytes.Buffer{}
+	assert.Nil(t, DecodeBinary(encoding, bytes.NewReader(b), w))
+	return w.String()
+}
+
+func TestBinaryEncoder_EncodeEntry(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2022, 7, 1, 8, 0, 0, 0, time.UTC),
+		LoggerName: "foo",
+		Message:    "hello world",
+		Caller:     zapcore.NewEntryCaller(0, "/a/b/c.go", 10, true),
+		Stack:      "line1\nline2",
+	}
+	for _, encoding := range []string{"cbor", "msgpack"} {
+		t.Run(encoding, func(t *testing.T) {
+			enc, err := newEncoder(encoding, testLogfmtEncoderConfig())
+			assert.Nil(t, err)
+			enc.AddString("service", "demo")
+			enc.OpenNamespace("ctx")
+			enc.AddInt("n", 1)
+			buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+				zap.String("k", "v"),
+				zap.Object("o", fieldsMarshaler{Group("g", logging.Bool("b", true))}),
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, `{"ts":"2022-07-01T08:00:00Z","level":"WARN","logger":"foo","caller":"b/c.go:10","func":"",`+
+				`"msg":"hello world","service":"demo","ctx":{"n":1,"k":"v","o":{"g":{"b":true}}},`+
+				`"stacktrace":"line1\nline2"}`+"\n", decodeBinary(t, encoding, buf.Bytes()))
+
+			// the encoder itself is not changed by encoding entries.
+			buf, err = enc.EncodeEntry(zapcore.Entry{Time: ent.Time}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, `{"ts":"2022-07-01T08:00:00Z","level":"INFO","msg":"","service":"demo","ctx":{"n":1}}`+"\n",
+				decodeBinary(t, encoding, buf.Bytes()))
+		})
+	}
+}
+
+func TestBinaryEncoder_Clone(t *testing.T) {
+	for _, encoding := range []string{"cbor", "msgpack"} {
+		t.Run(encoding, func(t *testing.T) {
+			enc, err := newEncoder(encoding, zapcore.EncoderConfig{})
+			assert.Nil(t, err)
+			enc.OpenNamespace("a")
+			enc.AddString("b", "c")
+			clone := enc.Clone()
+			clone.AddString("d", "e")
+			buf, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, `{"a":{"b":"c"}}`+"\n", decodeBinary(t, encoding, buf.Bytes()))
+			buf, err = clone.EncodeEntry(zapcore.Entry{}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, `{"a":{"b":"c","d":"e"}}`+"\n", decodeBinary(t, encoding, buf.Bytes()))
+		})
+	}
+}
+
+func TestBinaryEncoder_fields(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  string
+		field zapcore.Field
+	}{
+		{name: "binary", field: zap.Binary("k", []byte("abc")), want: `"YWJj"`},
+		{name: "byte_string", field: zap.ByteString("k", []byte("a b")), want: `"a b"`},
+		{name: "bool", field: zap.Bool("k", true), want: "true"},
+		{name: "c128", field: zap.Complex128("k", 1+2i), want: "[1,2]"},
+		{name: "c64", field: zap.Complex64("k", 1+2i), want: "[1,2]"},
+		{name: "duration", field: zap.Duration("k", time.Second), want: "1000000000"},
+		{name: "f64", field: zap.Float64("k", 1.5), want: "1.5"},
+		{name: "f32", field: zap.Float32("k", 1.5), want: "1.5"},
+		{name: "nan", field: zap.Float64("k", math.NaN()), want: `"NaN"`},
+		{name: "int", field: zap.Int("k", -1), want: "-1"},
+		{name: "i64", field: zap.Int64("k", -1<<40), want: "-1099511627776"},
+		{name: "i8", field: zap.Int8("k", -100), want: "-100"},
+		{name: "uint", field: zap.Uint("k", 1), want: "1"},
+		{name: "u64", field: zap.Uint64("k", 1<<63), want: "9223372036854775808"},
+		{name: "uintptr", field: zap.Uintptr("k", 300), want: "300"},
+		{name: "string", field: zap.String("k", strings.Repeat("x", 300)), want: `"` + strings.Repeat("x", 300) + `"`},
+		{name: "time", field: zap.Time("k", time.Date(2022, 7, 1, 8, 0, 0, 500, time.UTC)), want: `"2022-07-01T08:00:00.0000005Z"`},
+		{name: "reflected", field: zap.Reflect("k", map[string]int{"x": 1}), want: `"{\"x\":1}"`},
+		{name: "array", field: zap.Ints("k", []int{1, 2}), want: "[1,2]"},
+		{name: "empty_array", field: zap.Ints("k", nil), want: "[]"},
+		{name: "objects", field: zap.Array("k", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+			_ = enc.AppendObject(fieldsMarshaler{logging.String("a", "b")})
+			return enc.AppendArray(zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+				enc.AppendTime(time.Unix(1, 0))
+				return nil
+			}))
+		})), want: `[{"a":"b"},["1970-01-01T00:00:01Z"]]`},
+		{name: "namespace_in_object", field: zap.Object("k", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.OpenNamespace("ns")
+			enc.AddString("a", "b")
+			return nil
+		})), want: `{"ns":{"a":"b"}}`},
+	}
+	for _, encoding := range []string{"cbor", "msgpack"} {
+		for _, tt := range tests {
+			t.Run(encoding+"_"+tt.name, func(t *testing.T) {
+				enc, err := newEncoder(encoding, zapcore.EncoderConfig{})
+				assert.Nil(t, err)
+				buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{tt.field})
+				assert.Nil(t, err)
+				assert.Equal(t, `{"k":`+tt.want+"}\n", decodeBinary(t, encoding, buf.Bytes()))
+			})
+		}
+	}
+}
+
+func TestDecodeBinary(t *testing.T) {
+	enc := NewMsgpackEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	var b []byte
+	for _, msg := range []string{"a", "b"} {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: msg}, nil)
+		assert.Nil(t, err)
+		b = append(b, buf.Bytes()...)
+	}
+	assert.Equal(t, `{"msg":"a"}`+"\n"+`{"msg":"b"}`+"\n", decodeBinary(t, "msgpack", b))
+
+	assert.Equal(t, io.ErrUnexpectedEOF, DecodeBinary("msgpack", bytes.NewReader(b[:len(b)-1]), &bytes.Buffer{}))
+	assert.EqualError(t, DecodeBinary("json", bytes.NewReader(b), &bytes.Buffer{}), `unknown binary encoding: "json"`)
+}
+
+func TestBinary_encoding(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-binary", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-binary://"),
+		Encoding("cbor"),
+		GlobalFields(logging.String("service", "demo")),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	factory.Logger("foo").Infow("abc", logging.String("k", "v"))
+	assert.Len(t, sink.lines, 1)
+	line := decodeBinary(t, "cbor", []byte(sink.lines[0]))
+	assert.Contains(t, line, `"logger":"foo"`)
+	assert.Contains(t, line, `"msg":"abc"`)
+	assert.Contains(t, line, `"service":"demo"`)
+	assert.Contains(t, line, `"k":"v"`)
+}
@@ -0,0 +1,418 @@
+package zap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Major types of CBOR items, in the high 3 bits of the initial bytes.
+const (
+	cborUint   = 0 << 5
+	cborNegInt = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+
+	// cborIndefinite is the additional information of items of indefinite length.
+	cborIndefinite = 31
+	cborBreak      = cborSimple | cborIndefinite
+
+	// cborTagTime and cborTagEpoch are the tags of times as RFC 3339 strings and as seconds since the epoch.
+	cborTagTime  = 0
+	cborTagEpoch = 1
+)
+
+func init() {
+	registerEncoder("cbor", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewCBOREncoder(config), nil
+	})
+}
+
+// NewCBOREncoder creates an encoder writing entries as CBOR maps (RFC 8949).
+//
+// Times are written as epoch times tagged 1, or RFC 3339 strings tagged 0 if they have fractions of seconds,
+// durations as integers of nanoseconds, binaries as byte strings,
+// and complex numbers as arrays of real and imaginary parts.
+// Maps and arrays are of indefinite length, so that entries are written in a single pass.
+// DecodeBinary converts entries back to JSON.
+func NewCBOREncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return newBinaryEncoder(&config, cborFormat{})
+}
+
+type cborFormat struct{}
+
+// appendCBORHead appends the head of an item of the major type and the argument.
+func appendCBORHead(buf *buffer.Buffer, major byte, n uint64) {
+	var b [9]byte
+	switch {
+	case n < 24:
+		buf.AppendByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.AppendByte(major | 24)
+		buf.AppendByte(byte(n))
+	case n <= math.MaxUint16:
+		b[0] = major | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		_, _ = buf.Write(b[:3])
+	case n <= math.MaxUint32:
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		_, _ = buf.Write(b[:5])
+	default:
+		b[0] = major | 27
+		binary.BigEndian.PutUint64(b[1:], n)
+		_, _ = buf.Write(b[:9])
+	}
+}
+
+func (cborFormat) appendMapStart(buf *buffer.Buffer) {
+	buf.AppendByte(cborMap | cborIndefinite)
+}
+
+func (cborFormat) appendMapEnd(buf *buffer.Buffer, _, _ int) {
+	buf.AppendByte(cborBreak)
+}
+
+func (cborFormat) appendArrayStart(buf *buffer.Buffer) {
+	buf.AppendByte(cborArray | cborIndefinite)
+}
+
+func (cborFormat) appendArrayEnd(buf *buffer.Buffer, _, _ int) {
+	buf.AppendByte(cborBreak)
+}
+
+func (cborFormat) appendString(buf *buffer.Buffer, s string) {
+	// Text strings must be valid UTF-8.
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	}
+	appendCBORHead(buf, cborText, uint64(len(s)))
+	buf.AppendString(s)
+}
+
+func (cborFormat) appendBytes(buf *buffer.Buffer, b []byte) {
+	appendCBORHead(buf, cborBytes, uint64(len(b)))
+	_, _ = buf.Write(b)
+}
+
+func (cborFormat) appendInt(buf *buffer.Buffer, i int64) {
+	if i >= 0 {
+		appendCBORHead(buf, cborUint, uint64(i))
+		return
+	}
+	appendCBORHead(buf, cborNegInt, uint64(-1-i))
+}
+
+func (cborFormat) appendUint(buf *buffer.Buffer, u uint64) {
+	appendCBORHead(buf, cborUint, u)
+}
+
+func (cborFormat) appendFloat64(buf *buffer.Buffer, f float64) {
+	var b [9]byte
+	b[0] = cborSimple | 27
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	_, _ = buf.Write(b[:])
+}
+
+func (cborFormat) appendFloat32(buf *buffer.Buffer, f float32) {
+	var b [5]byte
+	b[0] = cborSimple | 26
+	binary.BigEndian.PutUint32(b[1:], math.Float32bits(f))
+	_, _ = buf.Write(b[:])
+}
+
+func (cborFormat) appendBool(buf *buffer.Buffer, b bool) {
+	if b {
+		buf.AppendByte(cborSimple | 21)
+	} else {
+		buf.AppendByte(cborSimple | 20)
+	}
+}
+
+// appendTime appends the time tagged as an epoch time of integer seconds if it has no fraction,
+// or as an RFC 3339 string in UTC otherwise, as floats of seconds lose nanoseconds.
+func (f cborFormat) appendTime(buf *buffer.Buffer, t time.Time) {
+	if t.Nanosecond() == 0 {
+		appendCBORHead(buf, cborTag, cborTagEpoch)
+		f.appendInt(buf, t.Unix())
+		return
+	}
+	appendCBORHead(buf, cborTag, cborTagTime)
+	f.appendString(buf, t.UTC().Format(time.RFC3339Nano))
+}
+
+// decodeCBOR decodes a CBOR item and appends it as JSON.
+func decodeCBOR(r *bufio.Reader, buf *buffer.Buffer) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	major, info := b&0xe0, b&0x1f
+	if major == cborSimple {
+		return decodeCBORSimple(r, buf, info)
+	}
+	if info == cborIndefinite {
+		return decodeCBORIndefinite(r, buf, major)
+	}
+	n, err := readCBORArgument(r, info)
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborUint:
+		buf.AppendUint(n)
+	case cborNegInt:
+		if n <= math.MaxInt64 {
+			buf.AppendInt(-1 - int64(n))
+		} else {
+			v := new(big.Int).SetUint64(n)
+			buf.AppendString(v.Neg(v.Add(v, big.NewInt(1))).String())
+		}
+	case cborBytes:
+		b, err := readFull(r, n)
+		if err != nil {
+			return err
+		}
+		appendJSONString(buf, base64.StdEncoding.EncodeToString(b))
+	case cborText:
+		b, err := readFull(r, n)
+		if err != nil {
+			return err
+		}
+		appendJSONString(buf, string(b))
+	case cborArray, cborMap:
+		return decodeCBORContainer(r, buf, major, func(i int) (bool, error) { return uint64(i) < n, nil })
+	case cborTag:
+		if n == cborTagEpoch {
+			return decodeCBOREpoch(r, buf)
+		}
+		// Other tags, including times as RFC 3339 strings tagged 0, are decoded as their contents.
+		return decodeCBOR(r, buf)
+	}
+	return nil
+}
+
+// decodeCBORIndefinite decodes an item of indefinite length, the head of which is read.
+func decodeCBORIndefinite(r *bufio.Reader, buf *buffer.Buffer, major byte) error {
+	more := func(int) (bool, error) {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false, err
+		}
+		if b[0] == cborBreak {
+			_, _ = r.ReadByte()
+			return false, nil
+		}
+		return true, nil
+	}
+	switch major {
+	case cborArray, cborMap:
+		return decodeCBORContainer(r, buf, major, more)
+	case cborBytes, cborText:
+	default:
+		return fmt.Errorf("invalid cbor item of indefinite length, major type %d", major>>5)
+	}
+	// Strings are chunks of definite strings of the same major type.
+	var s []byte
+	for i := 0; ; i++ {
+		ok, err := more(i)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		head, _ := r.ReadByte()
+		if head&0xe0 != major || head&0x1f == cborIndefinite {
+			return fmt.Errorf("invalid cbor string chunk %#x", head)
+		}
+		n, err := readCBORArgument(r, head&0x1f)
+		if err != nil {
+			return err
+		}
+		chunk, err := readFull(r, n)
+		if err != nil {
+			return err
+		}
+		s = append(s, chunk...)
+	}
+	if major == cborBytes {
+		appendJSONString(buf, base64.StdEncoding.EncodeToString(s))
+	} else {
+		appendJSONString(buf, string(s))
+	}
+	return nil
+}
+
+// decodeCBORContainer decodes the elements or pairs of an array or map while more reports true.
+func decodeCBORContainer(r *bufio.Reader, buf *buffer.Buffer, major byte, more func(i int) (bool, error)) error {
+	open, close := byte('['), byte(']')
+	if major == cborMap {
+		open, close = '{', '}'
+	}
+	buf.AppendByte(open)
+	for i := 0; ; i++ {
+		ok, err := more(i)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if i != 0 {
+			buf.AppendByte(',')
+		}
+		if major == cborMap {
+			err = decodeCBORPair(r, buf)
+		} else {
+			err = decodeCBOR(r, buf)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	buf.AppendByte(close)
+	return nil
+}
+
+// decodeCBORPair decodes a key and a value of a map, keys which are not strings are converted to strings.
+func decodeCBORPair(r *bufio.Reader, buf *buffer.Buffer) error {
+	key := &buffer.Buffer{}
+	if err := decodeCBOR(r, key); err != nil {
+		return err
+	}
+	appendJSONKey(buf, key.Bytes())
+	buf.AppendByte(':')
+	return decodeCBOR(r, buf)
+}
+
+// decodeCBORSimple decodes a simple value or a float, the initial byte of which is read.
+func decodeCBORSimple(r *bufio.Reader, buf *buffer.Buffer, info byte) error {
+	switch info {
+	case 20:
+		buf.AppendBool(false)
+	case 21:
+		buf.AppendBool(true)
+	case 22, 23:
+		// null and undefined.
+		buf.AppendString("null")
+	case 24:
+		v, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		buf.AppendUint(uint64(v))
+	case 25, 26, 27:
+		f, err := readCBORFloat(r, info)
+		if err != nil {
+			return err
+		}
+		appendJSONFloat(buf, f)
+	case cborIndefinite:
+		return fmt.Errorf("unexpected cbor break")
+	default:
+		buf.AppendUint(uint64(info))
+	}
+	return nil
+}
+
+// decodeCBOREpoch decodes an epoch time, the tag of which is read, and appends it as an RFC 3339 string.
+func decodeCBOREpoch(r *bufio.Reader, buf *buffer.Buffer) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	major, info := b&0xe0, b&0x1f
+	switch {
+	case major == cborUint || major == cborNegInt:
+		n, err := readCBORArgument(r, info)
+		if err != nil {
+			return err
+		}
+		sec := int64(n)
+		if major == cborNegInt {
+			sec = -1 - sec
+		}
+		appendJSONTime(buf, time.Unix(sec, 0))
+	case major == cborSimple && info >= 25 && info <= 27:
+		f, err := readCBORFloat(r, info)
+		if err != nil {
+			return err
+		}
+		sec, frac := math.Modf(f)
+		appendJSONTime(buf, time.Unix(int64(sec), int64(math.Round(frac*1e9))))
+	default:
+		return fmt.Errorf("invalid cbor epoch time %#x", b)
+	}
+	return nil
+}
+
+// readCBORArgument reads the argument of the head by its additional information.
+func readCBORArgument(r *bufio.Reader, info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, fmt.Errorf("invalid cbor additional information %d", info)
+	}
+	b, err := readFull(r, 1<<(info-24))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// readCBORFloat reads a half, single or double precision float by the additional information.
+func readCBORFloat(r *bufio.Reader, info byte) (float64, error) {
+	bits, err := readCBORArgument(r, info)
+	if err != nil {
+		return 0, err
+	}
+	switch info {
+	case 25:
+		return cborHalfFloat(uint16(bits)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(bits))), nil
+	default:
+		return math.Float64frombits(bits), nil
+	}
+}
+
+// cborHalfFloat converts the bits of a half precision float.
+func cborHalfFloat(bits uint16) float64 {
+	exp, mant := int(bits>>10&0x1f), float64(bits&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,105 @@
+package zap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/buffer"
+)
+
+func Test_cborFormat(t *testing.T) {
+	f := cborFormat{}
+	tests := []struct {
+		name   string
+		want   string
+		append func(buf *buffer.Buffer)
+	}{
+		{name: "uint_small", want: "17", append: func(buf *buffer.Buffer) { f.appendUint(buf, 23) }},
+		{name: "uint_8", want: "1818", append: func(buf *buffer.Buffer) { f.appendUint(buf, 24) }},
+		{name: "uint_16", want: "190100", append: func(buf *buffer.Buffer) { f.appendUint(buf, 256) }},
+		{name: "uint_32", want: "1a00010000", append: func(buf *buffer.Buffer) { f.appendUint(buf, 1<<16) }},
+		{name: "uint_64", want: "1b0000000100000000", append: func(buf *buffer.Buffer) { f.appendUint(buf, 1<<32) }},
+		{name: "int_negative", want: "20", append: func(buf *buffer.Buffer) { f.appendInt(buf, -1) }},
+		{name: "int_min", want: "3b7fffffffffffffff", append: func(buf *buffer.Buffer) { f.appendInt(buf, math.MinInt64) }},
+		{name: "string", want: "6161", append: func(buf *buffer.Buffer) { f.appendString(buf, "a") }},
+		{name: "string_invalid", want: "63efbfbd", append: func(buf *buffer.Buffer) { f.appendString(buf, "\xff") }},
+		{name: "bytes", want: "4161", append: func(buf *buffer.Buffer) { f.appendBytes(buf, []byte("a")) }},
+		{name: "float64", want: "fb3ff8000000000000", append: func(buf *buffer.Buffer) { f.appendFloat64(buf, 1.5) }},
+		{name: "float32", want: "fa3fc00000", append: func(buf *buffer.Buffer) { f.appendFloat32(buf, 1.5) }},
+		{name: "bool", want: "f5f4", append: func(buf *buffer.Buffer) { f.appendBool(buf, true); f.appendBool(buf, false) }},
+		{name: "time_epoch", want: "c11a514b67b0", append: func(buf *buffer.Buffer) {
+			f.appendTime(buf, time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC))
+		}},
+		{name: "time_string", want: "c0" + "76" + hex.EncodeToString([]byte("2013-03-21T20:04:00.5Z")), append: func(buf *buffer.Buffer) {
+			f.appendTime(buf, time.Date(2013, 3, 21, 20, 4, 0, 5e8, time.UTC))
+		}},
+		{name: "map", want: "bf6161f5ff", append: func(buf *buffer.Buffer) {
+			f.appendMapStart(buf)
+			f.appendString(buf, "a")
+			f.appendBool(buf, true)
+			f.appendMapEnd(buf, 0, 1)
+		}},
+		{name: "array", want: "9f01ff", append: func(buf *buffer.Buffer) {
+			f.appendArrayStart(buf)
+			f.appendInt(buf, 1)
+			f.appendArrayEnd(buf, 0, 1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &buffer.Buffer{}
+			tt.append(buf)
+			assert.Equal(t, tt.want, hex.EncodeToString(buf.Bytes()))
+		})
+	}
+}
+
+func Test_decodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{name: "negative_big", in: "3bffffffffffffffff", want: "-18446744073709551616"},
+		{name: "half_float", in: "f93e00", want: "1.5"},
+		{name: "half_float_subnormal", in: "f90001", want: "0.00000005960464477539063"},
+		{name: "half_float_inf", in: "f9fc00", want: `"-Inf"`},
+		{name: "null", in: "f6", want: "null"},
+		{name: "undefined", in: "f7", want: "null"},
+		{name: "simple", in: "f820", want: "32"},
+		{name: "definite_array", in: "820102", want: "[1,2]"},
+		{name: "definite_map", in: "a26161010102", want: `{"a":1,"1":2}`},
+		{name: "indefinite_string", in: "7f61616162ff", want: `"ab"`},
+		{name: "indefinite_bytes", in: "5f4161ff", want: `"YQ=="`},
+		{name: "empty_indefinite_map", in: "bfff", want: "{}"},
+		{name: "epoch_float", in: "c1fb41d452d9ec200000", want: `"2013-03-21T20:04:00.5Z"`},
+		{name: "epoch_negative", in: "c120", want: `"1969-12-31T23:59:59Z"`},
+		{name: "other_tag", in: "d8206161", want: `"a"`},
+		{name: "invalid_chunk", in: "7f01ff", err: "invalid cbor string chunk 0x1"},
+		{name: "invalid_epoch", in: "c16161", err: "invalid cbor epoch time 0x61"},
+		{name: "unexpected_break", in: "ff", err: "unexpected cbor break"},
+		{name: "invalid_argument", in: "1c", err: "invalid cbor additional information 28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := hex.DecodeString(tt.in)
+			if !assert.Nil(t, err) {
+				return
+			}
+			buf := &buffer.Buffer{}
+			err = decodeCBOR(bufio.NewReader(bytes.NewReader(in)), buf)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
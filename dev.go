@@ -0,0 +1,356 @@
+package zap
+
+import (
+	"encoding/base64"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	devReset   = "\x1b[0m"
+	devBold    = "\x1b[1m"
+	devDim     = "\x1b[2m"
+	devRed     = "\x1b[31m"
+	devYellow  = "\x1b[33m"
+	devBlue    = "\x1b[34m"
+	devMagenta = "\x1b[35m"
+	devCyan    = "\x1b[36m"
+
+	// devIndent is the indent of fields written below entries.
+	devIndent = 4
+	// devMaxLoggerWidth and devMaxCallerWidth limit the widths of the aligned columns.
+	devMaxLoggerWidth = 24
+	devMaxCallerWidth = 32
+)
+
+var devPool = buffer.NewPool()
+
+func init() {
+	registerEncoder("dev", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewDevEncoder(config, DevOptions{}), nil
+	})
+}
+
+// DevOptions configures the dev encoding.
+type DevOptions struct {
+	// Color is when to color entries, one of "auto", "always" and "never". "auto" as default,
+	// which colors entries if all outputs are terminals and the NO_COLOR environment variable is empty.
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+	// RelativeTime indicates whether times are written as durations since the outputs opened,
+	// rather than times of day. False as default.
+	RelativeTime bool `json:"relative_time,omitempty" yaml:"relative_time,omitempty"`
+}
+
+// Defaulted returns a new DevOptions filling blank items with default values.
+func (d DevOptions) Defaulted() DevOptions {
+	d.Color = strings.TrimSpace(d.Color)
+	if d.Color == "" {
+		d.Color = "auto"
+	}
+	return d
+}
+
+// colored reports whether entries written to the output paths are colored.
+func (d DevOptions) colored(paths []string) bool {
+	switch d.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || len(paths) == 0 {
+		return false
+	}
+	for _, path := range paths {
+		var f *os.File
+		switch path {
+		case "stdout":
+			f = os.Stdout
+		case "stderr":
+			f = os.Stderr
+		default:
+			return false
+		}
+		if fi, err := f.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NewDevEncoder creates a human-friendly encoder for development.
+//
+// Entries are written as aligned columns of time, level, logger name, caller and message,
+// followed by fields of simple values as key=value pairs.
+// Objects, arrays and multi-line strings are pretty-printed in indented lines below the entry,
+// as are the error fields and the stacktrace, highlighted.
+// Levels are colored as DevOptions.Color tells, which is "auto" for standard output.
+func NewDevEncoder(config zapcore.EncoderConfig, options DevOptions) zapcore.Encoder {
+	return newDevEncoder(&config, options.Defaulted(), []string{"stdout"})
+}
+
+func newDevEncoder(config *zapcore.EncoderConfig, options DevOptions, paths []string) *devEncoder {
+	return &devEncoder{
+		otlpObjectEncoder: newOTLPObjectEncoder(config),
+		options:           options,
+		color:             options.colored(paths),
+		columns:           &devColumns{start: time.Now()},
+	}
+}
+
+// devEncoder writes entries for humans.
+//
+// Fields are collected as OTLP attributes, which keep their order and types.
+type devEncoder struct {
+	*otlpObjectEncoder
+	options DevOptions
+	color   bool
+	columns *devColumns
+}
+
+// devColumns are the widths of aligned columns, shared by clones,
+// which grow to the widest values written so far.
+type devColumns struct {
+	start  time.Time
+	logger atomic.Int64
+	caller atomic.Int64
+}
+
+// width returns the width of the column for the value of n characters.
+func (c *devColumns) width(column *atomic.Int64, n, max int) int {
+	if n > max {
+		n = max
+	}
+	for {
+		cur := column.Load()
+		if int64(n) <= cur {
+			return int(cur)
+		}
+		if column.CAS(cur, int64(n)) {
+			return n
+		}
+	}
+}
+
+// withOptions configures the encoder with the dev options and the output paths of the Options.
+func (enc *devEncoder) withOptions(o *Options) zapcore.Encoder {
+	return newDevEncoder(enc.EncoderConfig, o.Dev.Defaulted(), o.OutputPaths)
+}
+
+func (enc *devEncoder) Clone() zapcore.Encoder {
+	return &devEncoder{otlpObjectEncoder: enc.clone(), options: enc.options, color: enc.color, columns: enc.columns}
+}
+
+type devError struct {
+	key string
+	err error
+}
+
+func (enc *devEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	var errs []devError
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType {
+			err, _ := f.Interface.(error)
+			errs = append(errs, devError{key: f.Key, err: err})
+			continue
+		}
+		f.AddTo(final)
+	}
+
+	buf := devPool.Get()
+	sep := func() {
+		if buf.Len() != 0 {
+			buf.AppendByte(' ')
+		}
+	}
+	if enc.TimeKey != "" {
+		sep()
+		if enc.options.RelativeTime {
+			enc.paint(buf, devDim, enc.pad("+"+strconv.FormatFloat(ent.Time.Sub(enc.columns.start).Seconds(), 'f', 3, 64)+"s", 10))
+		} else {
+			enc.paint(buf, devDim, ent.Time.Format("15:04:05.000"))
+		}
+	}
+	if enc.LevelKey != "" {
+		sep()
+		enc.paint(buf, devLevelColor(ent.Level), enc.pad(ent.Level.CapitalString(), 5))
+	}
+	if enc.NameKey != "" && ent.LoggerName != "" {
+		sep()
+		width := enc.columns.width(&enc.columns.logger, len(ent.LoggerName), devMaxLoggerWidth)
+		enc.paint(buf, devBold, enc.pad(ent.LoggerName, width))
+	}
+	if enc.CallerKey != "" && ent.Caller.Defined {
+		sep()
+		caller := ent.Caller.TrimmedPath()
+		if enc.FunctionKey != "" && ent.Caller.Function != "" {
+			caller += " " + ent.Caller.Function
+		}
+		width := enc.columns.width(&enc.columns.caller, len(caller), devMaxCallerWidth)
+		enc.paint(buf, devDim, enc.pad(caller, width))
+	}
+	if enc.MessageKey != "" {
+		sep()
+		buf.AppendString(ent.Message)
+	}
+
+	var blocks []otlpKeyValue
+	for _, kv := range final.root.values {
+		s, ok := devInline(kv.value)
+		if !ok {
+			blocks = append(blocks, kv)
+			continue
+		}
+		sep()
+		enc.paint(buf, devCyan, kv.key)
+		buf.AppendByte('=')
+		buf.AppendString(s)
+	}
+	for _, kv := range blocks {
+		enc.appendBlock(buf, devIndent, kv.key, kv.value)
+	}
+	for _, e := range errs {
+		buf.AppendByte('\n')
+		buf.AppendString(strings.Repeat(" ", devIndent))
+		enc.paint(buf, devRed+devBold, e.key+": "+errorMessage(e.err))
+		if stack := errorStacktrace(e.err); stack != "" {
+			enc.appendLines(buf, devIndent+2, devDim, stack)
+		}
+	}
+	if ent.Stack != "" && enc.StacktraceKey != "" {
+		buf.AppendByte('\n')
+		buf.AppendString(strings.Repeat(" ", devIndent))
+		enc.paint(buf, devRed, enc.StacktraceKey+":")
+		enc.appendLines(buf, devIndent+2, devDim, ent.Stack)
+	}
+	if !enc.SkipLineEnding {
+		if enc.LineEnding != "" {
+			buf.AppendString(enc.LineEnding)
+		} else {
+			buf.AppendString(zapcore.DefaultLineEnding)
+		}
+	}
+	return buf, nil
+}
+
+// appendBlock appends the pair in indented lines, pretty-printing the value.
+func (enc *devEncoder) appendBlock(buf *buffer.Buffer, indent int, key string, value any) {
+	buf.AppendByte('\n')
+	buf.AppendString(strings.Repeat(" ", indent))
+	enc.paint(buf, devCyan, key)
+	buf.AppendByte(':')
+	enc.appendBlockValue(buf, indent, value)
+}
+
+func (enc *devEncoder) appendBlockValue(buf *buffer.Buffer, indent int, value any) {
+	if s, ok := devInline(value); ok {
+		buf.AppendByte(' ')
+		buf.AppendString(s)
+		return
+	}
+	switch v := value.(type) {
+	case string:
+		buf.AppendString(" |")
+		enc.appendLines(buf, indent+2, "", v)
+	case []any:
+		for _, elem := range v {
+			buf.AppendByte('\n')
+			buf.AppendString(strings.Repeat(" ", indent+2))
+			buf.AppendByte('-')
+			enc.appendBlockValue(buf, indent+2, elem)
+		}
+	case *otlpKVList:
+		for _, kv := range v.values {
+			enc.appendBlock(buf, indent+2, kv.key, kv.value)
+		}
+	}
+}
+
+// appendLines appends the lines of the text, indented.
+func (enc *devEncoder) appendLines(buf *buffer.Buffer, indent int, color, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		buf.AppendByte('\n')
+		buf.AppendString(strings.Repeat(" ", indent))
+		enc.paint(buf, color, line)
+	}
+}
+
+// paint appends the text in the color, if colors are enabled.
+func (enc *devEncoder) paint(buf *buffer.Buffer, color, text string) {
+	if !enc.color || color == "" {
+		buf.AppendString(text)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(text)
+	buf.AppendString(devReset)
+}
+
+// pad pads the text with spaces to the width.
+func (enc *devEncoder) pad(text string, width int) string {
+	if len(text) >= width {
+		return text
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+func devLevelColor(level zapcore.Level) string {
+	switch level {
+	case zapcore.DebugLevel:
+		return devMagenta
+	case zapcore.InfoLevel:
+		return devBlue
+	case zapcore.WarnLevel:
+		return devYellow
+	case zapcore.ErrorLevel:
+		return devRed
+	default:
+		return devRed + devBold
+	}
+}
+
+// devInline returns the value formatted in a line, reporting false if it should be pretty-printed in lines.
+func devInline(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, "\n") {
+			return "", false
+		}
+		if needsLogfmtQuote(v) {
+			return strconv.Quote(v), true
+		}
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), true
+	case []any:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			s, ok := devInline(elem)
+			if !ok {
+				return "", false
+			}
+			elems[i] = s
+		}
+		return "[" + strings.Join(elems, ", ") + "]", true
+	case *otlpKVList:
+		if len(v.values) == 0 {
+			return "{}", true
+		}
+		return "", false
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,163 @@
+package zap
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDevOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, DevOptions{Color: "auto"}, DevOptions{}.Defaulted())
+	assert.Equal(t, DevOptions{Color: "never", RelativeTime: true}, DevOptions{Color: " never ", RelativeTime: true}.Defaulted())
+}
+
+func TestDevOptions_colored(t *testing.T) {
+	assert.True(t, DevOptions{Color: "always"}.colored([]string{"/tmp/a.log"}))
+	assert.False(t, DevOptions{Color: "never"}.colored([]string{"stdout"}))
+	// Outputs of tests are not terminals.
+	assert.False(t, DevOptions{Color: "auto"}.colored([]string{"stdout"}))
+	assert.False(t, DevOptions{Color: "auto"}.colored([]string{"/tmp/a.log"}))
+	assert.False(t, DevOptions{Color: "auto"}.colored(nil))
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, DevOptions{Color: "auto"}.colored([]string{"stdout"}))
+}
+
+func newTestDevEncoder(options DevOptions) *devEncoder {
+	config := testLogfmtEncoderConfig()
+	return newDevEncoder(&config, options.Defaulted(), nil)
+}
+
+func TestDevEncoder_EncodeEntry(t *testing.T) {
+	enc := newTestDevEncoder(DevOptions{Color: "never"})
+	enc.AddString("service", "demo")
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Date(2022, 1, 2, 15, 4, 5, 6000000, time.Local),
+		LoggerName: "foo",
+		Message:    "hello",
+		Caller:     zapcore.NewEntryCaller(0, "/a/b/c.go", 12, true),
+		Stack:      "main.main\n\t/a/b/c.go:12",
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.Int("count", 3),
+		zap.String("name", "a b"),
+		zap.Strings("tags", []string{"x", "y"}),
+		zap.Object("http", fieldsMarshaler{
+			logging.String("method", "GET"),
+			Group("headers", logging.String("accept", "*/*")),
+		}),
+		zap.String("body", "line1\nline2"),
+		zap.Error(tStackError{cause: errors.New("boom"), stack: "main.f\n\tmain.go:1"}),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Join([]string{
+		`15:04:05.006 ERROR foo b/c.go:12 hello service=demo count=3 name="a b" tags=[x, y]`,
+		`    http:`,
+		`      method: GET`,
+		`      headers:`,
+		`        accept: */*`,
+		`    body: |`,
+		`      line1`,
+		`      line2`,
+		`    error: stack`,
+		`      main.f`,
+		`      	main.go:1`,
+		`    stacktrace:`,
+		`      main.main`,
+		`      	/a/b/c.go:12`,
+	}, "\n")+"\n", buf.String())
+}
+
+func TestDevEncoder_columns(t *testing.T) {
+	enc := newTestDevEncoder(DevOptions{Color: "never"})
+	enc.TimeKey = ""
+	write := func(name string, level zapcore.Level) string {
+		buf, err := enc.Clone().EncodeEntry(zapcore.Entry{Level: level, LoggerName: name, Message: "m"}, nil)
+		assert.Nil(t, err)
+		return buf.String()
+	}
+	assert.Equal(t, "INFO  a m\n", write("a", zapcore.InfoLevel))
+	assert.Equal(t, "WARN  abc m\n", write("abc", zapcore.WarnLevel))
+	// Columns are as wide as the widest values written so far.
+	assert.Equal(t, "INFO  a   m\n", write("a", zapcore.InfoLevel))
+	assert.Equal(t, "INFO  "+strings.Repeat("x", 30)+" m\n", write(strings.Repeat("x", 30), zapcore.InfoLevel))
+	assert.Equal(t, "INFO  a"+strings.Repeat(" ", devMaxLoggerWidth-1)+" m\n", write("a", zapcore.InfoLevel))
+}
+
+func TestDevEncoder_function(t *testing.T) {
+	enc := newTestDevEncoder(DevOptions{Color: "never"})
+	enc.TimeKey = ""
+	enc.FunctionKey = "func"
+	caller := zapcore.NewEntryCaller(0, "/a/b/c.go", 12, true)
+	caller.Function = "main.main"
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "m", Caller: caller}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "INFO  b/c.go:12 main.main m\n", buf.String())
+}
+
+func TestDevEncoder_color(t *testing.T) {
+	enc := newTestDevEncoder(DevOptions{Color: "always"})
+	enc.TimeKey = ""
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "m"},
+		[]zapcore.Field{zap.Int("a", 1), zap.Error(errors.New("boom"))})
+	assert.Nil(t, err)
+	assert.Equal(t, devYellow+"WARN "+devReset+" m "+devCyan+"a"+devReset+"=1\n    "+
+		devRed+devBold+"error: boom"+devReset+"\n", buf.String())
+}
+
+func TestDevEncoder_relativeTime(t *testing.T) {
+	enc := newTestDevEncoder(DevOptions{Color: "never", RelativeTime: true})
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    enc.columns.start.Add(1234 * time.Millisecond),
+		Message: "m",
+	}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "+1.234s    INFO  m\n", buf.String())
+}
+
+func Test_devInline(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		want   string
+		inline bool
+	}{
+		{name: "string", value: "abc", want: "abc", inline: true},
+		{name: "quoted", value: `a"b`, want: `"a\"b"`, inline: true},
+		{name: "empty", value: "", want: `""`, inline: true},
+		{name: "multi_line", value: "a\nb"},
+		{name: "bool", value: true, want: "true", inline: true},
+		{name: "int", value: int64(-1), want: "-1", inline: true},
+		{name: "float", value: 1.5, want: "1.5", inline: true},
+		{name: "bytes", value: []byte{1, 2}, want: "AQI=", inline: true},
+		{name: "array", value: []any{"a", int64(1)}, want: "[a, 1]", inline: true},
+		{name: "nested_array", value: []any{&otlpKVList{values: []otlpKeyValue{{key: "a", value: "b"}}}}},
+		{name: "empty_object", value: &otlpKVList{}, want: "{}", inline: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, inline := devInline(tt.value)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.inline, inline)
+		})
+	}
+}
+
+func TestDev_encoding(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-dev", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	options := NewOptions(OutputPaths("test-dev://"), Encoding("dev"), Dev(DevOptions{Color: "always"}), DisableCaller(true))
+	newZapLogger(t, options, "foo").Info("abc", zap.Strings("a", []string{"b"}))
+	assert.Len(t, sink.lines, 1)
+	assert.Contains(t, sink.lines[0], devBlue+"INFO "+devReset+" "+devBold+"foo"+devReset+" abc "+devCyan+"a"+devReset+"=[b]\n")
+}
@@ -0,0 +1,133 @@
+package zap
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ecsVersion is the version of Elastic Common Schema entries conform to.
+const ecsVersion = "1.6.0"
+
+// ecsFieldKeys maps common keys of fields to their ECS locations.
+var ecsFieldKeys = map[string]string{
+	"trace_id":        "trace.id",
+	"traceId":         "trace.id",
+	"traceID":         "trace.id",
+	"span_id":         "span.id",
+	"spanId":          "span.id",
+	"spanID":          "span.id",
+	"transaction_id":  "transaction.id",
+	"transactionId":   "transaction.id",
+	"transactionID":   "transaction.id",
+	"service":         "service.name",
+	"service_name":    "service.name",
+	"serviceName":     "service.name",
+	"service_version": "service.version",
+	"serviceVersion":  "service.version",
+}
+
+func init() {
+	schemas["ecs"] = schema{
+		encoderConfig: ecsEncoderConfig,
+		wrapCore: func(core zapcore.Core, _ *Options) zapcore.Core {
+			return newECSCore(core)
+		},
+	}
+}
+
+func ecsEncoderConfig(config *zapcore.EncoderConfig) {
+	config.TimeKey = "@timestamp"
+	config.LevelKey = "log.level"
+	config.NameKey = "log.logger"
+	config.MessageKey = "message"
+	config.StacktraceKey = "error.stack_trace"
+	// The caller is written as log.origin fields by ecsCore.
+	config.CallerKey = zapcore.OmitKey
+	config.FunctionKey = zapcore.OmitKey
+	config.EncodeLevel = zapcore.LowercaseLevelEncoder
+	config.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	}
+}
+
+// ecsCore rewrites entries to Elastic Common Schema.
+//
+// It adds ecs.version and the log.origin fields of the caller, renders the error field as
+// error.message, error.type and error.stack_trace, and maps known keys of fields to their ECS locations,
+// e.g. trace_id to trace.id.
+type ecsCore struct {
+	zapcore.Core
+	// nested are the accumulated fields since the first namespace,
+	// which are written after the fields of ECS so that those are not nested.
+	nested []zapcore.Field
+}
+
+func newECSCore(core zapcore.Core) zapcore.Core {
+	return &ecsCore{Core: core}
+}
+
+func (c *ecsCore) With(fields []zapcore.Field) zapcore.Core {
+	flat, nested := splitNamespace(fields, len(c.nested) != 0)
+	return &ecsCore{
+		Core:   c.Core.With(ecsFields(nil, flat, nil)),
+		nested: ecsFields(c.nested[:len(c.nested):len(c.nested)], nested, nil),
+	}
+}
+
+func (c *ecsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ecsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	res := make([]zapcore.Field, 0, len(c.nested)+len(fields)+6)
+	res = append(res, zap.String("ecs.version", ecsVersion))
+	if ent.Caller.Defined {
+		file := ent.Caller.TrimmedPath()
+		if i := strings.LastIndexByte(file, ':'); i != -1 {
+			file = file[:i]
+		}
+		res = append(res,
+			zap.String("log.origin.file.name", file),
+			zap.Int("log.origin.file.line", ent.Caller.Line),
+		)
+		if ent.Caller.Function != "" {
+			res = append(res, zap.String("log.origin.function", ent.Caller.Function))
+		}
+	}
+	res = append(res, c.nested...)
+	res = ecsFields(res, fields, &ent)
+	return c.Core.Write(ent, res)
+}
+
+// ecsFields appends the fields mapped to ECS to res.
+//
+// The stack trace carried by the error field replaces the stack trace of the entry, if the entry is not nil.
+func ecsFields(res []zapcore.Field, fields []zapcore.Field, ent *zapcore.Entry) []zapcore.Field {
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType && f.Key == "error" {
+			err, _ := f.Interface.(error)
+			res = append(res,
+				zap.String("error.message", errorMessage(err)),
+				zap.String("error.type", fmt.Sprintf("%T", err)),
+			)
+			if ent != nil {
+				if stack := errorStacktrace(err); stack != "" {
+					ent.Stack = stack
+				}
+			}
+			continue
+		}
+		if key, ok := ecsFieldKeys[f.Key]; ok {
+			f.Key = key
+		}
+		res = append(res, f)
+	}
+	return res
+}
@@ -0,0 +1,113 @@
+package zap
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestECS(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-ecs", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	// The zap logger is called directly, rather than through a zapLogger.
+	logger := newZapLogger(t, NewOptions(OutputPaths("test-ecs://"), Schema("ecs"), Development(true),
+		GlobalAddCallerSkipAdjust(-1)), "foo")
+	logger.With(zap.String("service", "demo")).Info("abc",
+		zap.String("trace_id", "t1"),
+		zap.Object("http", fieldsMarshaler{logging.String("method", "GET")}),
+	)
+	logger.Error("def", zap.Error(tStackError{cause: errors.New("boom"), stack: "main.main\n\tmain.go:1"}))
+	logger.Warn("ghi", zap.Error(errors.New("boom")))
+	assert.Len(t, sink.lines, 3)
+	decode := func(line string) map[string]any {
+		m := map[string]any{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &m), line)
+		return m
+	}
+
+	info := decode(sink.lines[0])
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z$`, info["@timestamp"])
+	delete(info, "@timestamp")
+	assert.True(t, strings.HasSuffix(info["log.origin.file.name"].(string), "ecs_test.go"))
+	delete(info, "log.origin.file.name")
+	assert.NotZero(t, info["log.origin.file.line"])
+	delete(info, "log.origin.file.line")
+	assert.Equal(t, map[string]any{
+		"log.level":           "info",
+		"log.logger":          "foo",
+		"log.origin.function": "github.com/yimi-go/zap-logging.TestECS",
+		"message":             "abc",
+		"ecs.version":         ecsVersion,
+		"service.name":        "demo",
+		"trace.id":            "t1",
+		"http":                map[string]any{"method": "GET"},
+	}, info)
+
+	// The stack trace carried by the error replaces that of the entry.
+	err := decode(sink.lines[1])
+	assert.Equal(t, "error", err["log.level"])
+	assert.Equal(t, "stack", err["error.message"])
+	assert.Equal(t, "zap.tStackError", err["error.type"])
+	assert.Equal(t, "main.main\n\tmain.go:1", err["error.stack_trace"])
+
+	warn := decode(sink.lines[2])
+	assert.Equal(t, "boom", warn["error.message"])
+	assert.Equal(t, "*errors.errorString", warn["error.type"])
+	assert.NotContains(t, warn, "error.stack_trace")
+	assert.NotContains(t, warn, "error")
+}
+
+func Test_ecsFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []zapcore.Field
+		want   []zapcore.Field
+	}{
+		{
+			name:   "known_keys",
+			fields: []zapcore.Field{zap.String("traceID", "t"), zap.String("spanId", "s"), zap.Int("foo", 1)},
+			want:   []zapcore.Field{zap.String("trace.id", "t"), zap.String("span.id", "s"), zap.Int("foo", 1)},
+		},
+		{
+			name:   "error",
+			fields: []zapcore.Field{zap.Error(errors.New("boom"))},
+			want:   []zapcore.Field{zap.String("error.message", "boom"), zap.String("error.type", "*errors.errorString")},
+		},
+		{
+			name:   "named_error",
+			fields: []zapcore.Field{zap.NamedError("cause", errors.New("boom"))},
+			want:   []zapcore.Field{zap.NamedError("cause", errors.New("boom"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ecsFields(nil, tt.fields, nil))
+		})
+	}
+}
+
+func TestECS_namespace(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-ecs-namespace", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	logger := newZapLogger(t, NewOptions(OutputPaths("test-ecs-namespace://"), Schema("ecs"), DisableCaller(true)), "foo")
+	logger.With(zap.String("a", "1"), zap.Namespace("ns"), zap.String("b", "2")).
+		With(zap.String("c", "3")).Info("abc", zap.String("d", "4"))
+	assert.Len(t, sink.lines, 1)
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal([]byte(sink.lines[0]), &m))
+	// Fields of ECS are not nested in namespaces.
+	assert.Equal(t, ecsVersion, m["ecs.version"])
+	assert.Equal(t, "1", m["a"])
+	assert.Equal(t, map[string]any{"b": "2", "c": "3", "d": "4"}, m["ns"])
+}
@@ -0,0 +1,98 @@
+package zap
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]func(config zapcore.EncoderConfig) (zapcore.Encoder, error){
+		"json": func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return zapcore.NewJSONEncoder(config), nil
+		},
+		"console": func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return zapcore.NewConsoleEncoder(config), nil
+		},
+	}
+)
+
+// registerEncoder registers the encoder constructor of the encoding to this package and zap.
+func registerEncoder(name string, constructor func(config zapcore.EncoderConfig) (zapcore.Encoder, error)) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[name] = constructor
+	_ = zap.RegisterEncoder(name, constructor)
+}
+
+// hasEncoder reports whether the encoding is registered to this package.
+func hasEncoder(name string) bool {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	_, ok := encoders[name]
+	return ok
+}
+
+func newEncoder(name string, config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	encodersMu.RLock()
+	constructor, ok := encoders[name]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no encoder registered for name %q", name)
+	}
+	return constructor(config)
+}
+
+// zapEncodingScheme is the zap sink scheme, through which cores of encodings registered to zap only write.
+const zapEncodingScheme = "zap-logging-encoding"
+
+var (
+	zapEncodingOnce    sync.Once
+	zapEncodingID      atomic.Uint64
+	zapEncodingWriters sync.Map
+)
+
+// zapEncodingSink is the sink of zapEncodingScheme, leaving closing to the writer owner.
+type zapEncodingSink struct {
+	zapcore.WriteSyncer
+}
+
+func (zapEncodingSink) Close() error {
+	return nil
+}
+
+// newZapEncodingCore creates a core writing entries to the writer,
+// encoded by the encoding registered by zap.RegisterEncoder rather than to this package.
+//
+// zap does not expose its encoders, so the core is built by zap.Config,
+// with the writer passed as a sink of zapEncodingScheme.
+func newZapEncodingCore(name string, config zapcore.EncoderConfig, ws zapcore.WriteSyncer) (zapcore.Core, error) {
+	zapEncodingOnce.Do(func() {
+		_ = zap.RegisterSink(zapEncodingScheme, func(u *url.URL) (zap.Sink, error) {
+			ws, ok := zapEncodingWriters.Load(u.Host)
+			if !ok {
+				return nil, fmt.Errorf("no writer of %s", u.Redacted())
+			}
+			return zapEncodingSink{WriteSyncer: ws.(zapcore.WriteSyncer)}, nil
+		})
+	})
+	id := strconv.FormatUint(zapEncodingID.Inc(), 10)
+	zapEncodingWriters.Store(id, ws)
+	defer zapEncodingWriters.Delete(id)
+	l, err := zap.Config{
+		Level:         zap.NewAtomicLevelAt(zapcore.DebugLevel),
+		Encoding:      name,
+		EncoderConfig: config,
+		OutputPaths:   []string{zapEncodingScheme + "://" + id},
+	}.Build()
+	if err != nil {
+		return nil, err
+	}
+	return l.Core(), nil
+}
@@ -0,0 +1,33 @@
+package zap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_newEncoder(t *testing.T) {
+	for _, name := range []string{"json", "console", "logfmt"} {
+		enc, err := newEncoder(name, zapcore.EncoderConfig{})
+		assert.Nil(t, err)
+		assert.NotNil(t, enc)
+	}
+	_, err := newEncoder("unknown", zapcore.EncoderConfig{})
+	assert.NotNil(t, err)
+}
+
+func Test_registerEncoder(t *testing.T) {
+	registerEncoder("test-encoding", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewJSONEncoder(config), nil
+	})
+	// Registered to zap as well.
+	_, err := zap.Config{
+		Level:    zap.NewAtomicLevel(),
+		Encoding: "test-encoding",
+	}.Build()
+	assert.Nil(t, err)
+	_, err = newEncoder("test-encoding", zapcore.EncoderConfig{})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,256 @@
+package zap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorOptions configures how error fields are rendered.
+type ErrorOptions struct {
+	// Structured indicates whether error fields are rendered as objects,
+	// with message, type, causes, stacktrace and attributes of the errors.
+	// False as default, errors are rendered as messages.
+	//
+	// Errors in Groups, ObjectMarshaler values and Lazy values are rendered as objects as well,
+	// while errors added to encoders by zapcore.ObjectMarshaler and zapcore.ArrayMarshaler values are not.
+	Structured bool `json:"structured,omitempty" yaml:"structured,omitempty"`
+	// DisableType indicates whether omit the Go type of errors. False as default.
+	DisableType bool `json:"disable_type,omitempty" yaml:"disable_type,omitempty"`
+	// DisableCauses indicates whether omit the errors wrapped by errors. False as default.
+	DisableCauses bool `json:"disable_causes,omitempty" yaml:"disable_causes,omitempty"`
+	// DisableStacktrace indicates whether omit the stack traces carried by errors. False as default.
+	DisableStacktrace bool `json:"disable_stacktrace,omitempty" yaml:"disable_stacktrace,omitempty"`
+	// DisableAttributes indicates whether omit the attributes of errors implementing ErrorAttributer.
+	// False as default.
+	DisableAttributes bool `json:"disable_attributes,omitempty" yaml:"disable_attributes,omitempty"`
+	// MaxCauseDepth is the maximum depth of rendered wrapped errors. 10 as default.
+	MaxCauseDepth int `json:"max_cause_depth,omitempty" yaml:"max_cause_depth,omitempty"`
+}
+
+// Defaulted returns a new ErrorOptions filling blank items with default values.
+func (e ErrorOptions) Defaulted() ErrorOptions {
+	if e.MaxCauseDepth <= 0 {
+		e.MaxCauseDepth = 10
+	}
+	return e
+}
+
+// ErrorAttributer is implemented by errors carrying attributes to be logged along with them.
+type ErrorAttributer interface {
+	ErrorAttributes() []logging.Field
+}
+
+// ErrorStacktracer is implemented by errors carrying stack traces.
+//
+// Errors formatting stack traces after their messages with %+v, like those created by github.com/pkg/errors,
+// are recognized as well.
+type ErrorStacktracer interface {
+	ErrorStacktrace() string
+}
+
+// errorCore renders error fields as structured objects.
+type errorCore struct {
+	zapcore.Core
+	options ErrorOptions
+}
+
+func newErrorCore(core zapcore.Core, options ErrorOptions) zapcore.Core {
+	return &errorCore{Core: core, options: options}
+}
+
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &errorCore{Core: c.Core.With(c.rewrite(fields)), options: c.options}
+}
+
+func (c *errorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.rewrite(fields))
+}
+
+func (c *errorCore) rewrite(fields []zapcore.Field) []zapcore.Field {
+	var res []zapcore.Field
+	for i, f := range fields {
+		rf, ok := c.rewriteField(f)
+		if !ok {
+			continue
+		}
+		if res == nil {
+			res = make([]zapcore.Field, len(fields))
+			copy(res, fields)
+		}
+		res[i] = rf
+	}
+	if res == nil {
+		return fields
+	}
+	return res
+}
+
+// rewriteField returns the field rendering errors as objects, and whether the field is rewritten.
+// Errors nested in fields created by this package are rewritten as well.
+func (c *errorCore) rewriteField(f zapcore.Field) (zapcore.Field, bool) {
+	switch m := f.Interface.(type) {
+	case error:
+		if f.Type != zapcore.ErrorType {
+			return f, false
+		}
+		f.Type, f.Interface = zapcore.ObjectMarshalerType, &errorMarshaler{err: m, options: &c.options, root: true}
+	case fieldsMarshaler:
+		if f.Type != zapcore.ObjectMarshalerType {
+			return f, false
+		}
+		f.Interface = &errorFieldsMarshaler{fields: m, core: c}
+	case *lazyMarshaler:
+		f.Interface = &errorLazyMarshaler{lazy: m, core: c}
+	default:
+		return f, false
+	}
+	return f, true
+}
+
+// errorFieldsMarshaler marshals fields of a Group or ObjectMarshaler, rendering errors as objects.
+type errorFieldsMarshaler struct {
+	fields fieldsMarshaler
+	core   *errorCore
+}
+
+func (e *errorFieldsMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range e.fields {
+		zf, _ := e.core.rewriteField(mapZapField(f))
+		zf.AddTo(enc)
+	}
+	return nil
+}
+
+// errorLazyMarshaler adds a Lazy field inline, rendering errors as objects.
+type errorLazyMarshaler struct {
+	lazy *lazyMarshaler
+	core *errorCore
+}
+
+func (e *errorLazyMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	zf, _ := e.core.rewriteField(e.lazy.resolve())
+	zf.AddTo(enc)
+	return nil
+}
+
+// errorMarshaler marshals an error and its causes as an object.
+type errorMarshaler struct {
+	err     error
+	options *ErrorOptions
+	depth   int
+	root    bool
+}
+
+func (e *errorMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", errorMessage(e.err))
+	if !e.options.DisableType {
+		enc.AddString("type", fmt.Sprintf("%T", e.err))
+	}
+	if !e.options.DisableAttributes {
+		if attributer, ok := e.err.(ErrorAttributer); ok {
+			if attributes := attributer.ErrorAttributes(); len(attributes) != 0 {
+				_ = enc.AddObject("attributes", fieldsMarshaler(attributes))
+			}
+		}
+	}
+	if !e.options.DisableCauses && e.depth < e.options.MaxCauseDepth {
+		if causes := unwrapErrors(e.err); len(causes) != 0 {
+			_ = enc.AddArray("causes", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+				for _, cause := range causes {
+					_ = arr.AppendObject(&errorMarshaler{err: cause, options: e.options, depth: e.depth + 1})
+				}
+				return nil
+			}))
+		}
+	}
+	if e.root && !e.options.DisableStacktrace {
+		if stack := errorStacktrace(e.err); stack != "" {
+			enc.AddString("stacktrace", stack)
+		}
+	}
+	return nil
+}
+
+// errorMessage returns the message of the error.
+// The error may be a typed nil pointer, or just panics on calling Error.
+func errorMessage(err error) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprintf("PANIC=%v", r)
+		}
+	}()
+	return err.Error()
+}
+
+// unwrapErrors returns the errors directly wrapped by the error.
+func unwrapErrors(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	case interface{ Unwrap() []error }:
+		var causes []error
+		for _, cause := range e.Unwrap() {
+			if cause != nil {
+				causes = append(causes, cause)
+			}
+		}
+		return causes
+	}
+	return nil
+}
+
+// errorStacktrace returns the deepest stack trace carried by the error chain.
+func errorStacktrace(err error) string {
+	var stack string
+	for depth := 0; err != nil && depth < 100; depth++ {
+		if s := ownStacktrace(err); s != "" {
+			stack = s
+		}
+		causes := unwrapErrors(err)
+		if len(causes) != 1 {
+			break
+		}
+		err = causes[0]
+	}
+	return stack
+}
+
+// ownStacktrace returns the stack trace carried by the error itself, recovering from panics of typed nil errors.
+func ownStacktrace(err error) (stack string) {
+	defer func() {
+		if recover() != nil {
+			stack = ""
+		}
+	}()
+	if st, ok := err.(ErrorStacktracer); ok {
+		return st.ErrorStacktrace()
+	}
+	f, ok := err.(fmt.Formatter)
+	if !ok {
+		return ""
+	}
+	// github.com/pkg/errors formats the message followed by the frames,
+	// each of which is a line of the function, and a tab-indented line of the file and line number.
+	s := fmt.Sprintf("%+v", f)
+	frame := strings.Index(s, "\n\t")
+	if frame < 0 {
+		return ""
+	}
+	function := strings.LastIndexByte(s[:frame], '\n')
+	if function < 0 {
+		return ""
+	}
+	return s[function+1:]
+}
@@ -0,0 +1,246 @@
+package zap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type tMultiError []error
+
+func (m tMultiError) Error() string   { return "multi" }
+func (m tMultiError) Unwrap() []error { return m }
+
+type tAttrError struct {
+	code int
+}
+
+func (e tAttrError) Error() string { return "attr" }
+
+func (e tAttrError) ErrorAttributes() []logging.Field {
+	return []logging.Field{logging.Int("code", e.code)}
+}
+
+type tStackError struct {
+	cause error
+	stack string
+}
+
+func (e tStackError) Error() string           { return "stack" }
+func (e tStackError) Unwrap() error           { return e.cause }
+func (e tStackError) ErrorStacktrace() string { return e.stack }
+
+// tPkgError formats its stack trace like errors created by github.com/pkg/errors.
+type tPkgError struct {
+	pcs []uintptr
+}
+
+func (e tPkgError) Error() string { return "pkg" }
+
+func (e tPkgError) Format(s fmt.State, verb rune) {
+	_, _ = io.WriteString(s, e.Error())
+	if verb != 'v' || !s.Flag('+') {
+		return
+	}
+	frames := runtime.CallersFrames(e.pcs)
+	for len(e.pcs) != 0 {
+		frame, more := frames.Next()
+		_, _ = fmt.Fprintf(s, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+}
+
+func newPkgError() error {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(1, pcs)
+	return tPkgError{pcs: pcs[:n]}
+}
+
+type tPanicError struct{}
+
+func (e *tPanicError) Error() string { panic("oops") }
+
+func TestErrorOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, ErrorOptions{MaxCauseDepth: 10}, ErrorOptions{}.Defaulted())
+	assert.Equal(t, ErrorOptions{MaxCauseDepth: 2, Structured: true}, ErrorOptions{MaxCauseDepth: 2, Structured: true}.Defaulted())
+}
+
+func marshalError(err error, options ErrorOptions) map[string]any {
+	options = options.Defaulted()
+	enc := zapcore.NewMapObjectEncoder()
+	_ = (&errorMarshaler{err: err, options: &options, root: true}).MarshalLogObject(enc)
+	return enc.Fields
+}
+
+func Test_errorMarshaler(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", tMultiError{io.EOF, tAttrError{code: 3}})
+	assert.Equal(t, map[string]any{
+		"message": "wrap: multi",
+		"type":    "*fmt.wrapError",
+		"causes": []any{
+			map[string]any{
+				"message": "multi",
+				"type":    "zap.tMultiError",
+				"causes": []any{
+					map[string]any{"message": "EOF", "type": "*errors.errorString"},
+					map[string]any{
+						"message":    "attr",
+						"type":       "zap.tAttrError",
+						"attributes": map[string]any{"code": int64(3)},
+					},
+				},
+			},
+		},
+	}, marshalError(err, ErrorOptions{}))
+
+	assert.Equal(t, map[string]any{
+		"message": "wrap: multi",
+	}, marshalError(err, ErrorOptions{DisableType: true, DisableCauses: true}))
+
+	assert.Equal(t, map[string]any{
+		"message": "wrap: multi",
+		"causes":  []any{map[string]any{"message": "multi"}},
+	}, marshalError(err, ErrorOptions{DisableType: true, MaxCauseDepth: 1}))
+
+	assert.Equal(t, map[string]any{
+		"message": "attr",
+	}, marshalError(tAttrError{}, ErrorOptions{DisableType: true, DisableAttributes: true}))
+
+	assert.Equal(t, map[string]any{
+		"message": "PANIC=oops",
+		"type":    "*zap.tPanicError",
+	}, marshalError(&tPanicError{}, ErrorOptions{}))
+}
+
+func Test_errorMarshaler_stacktrace(t *testing.T) {
+	err := tStackError{stack: "outer", cause: tStackError{stack: "inner", cause: io.EOF}}
+	m := marshalError(err, ErrorOptions{DisableCauses: true, DisableType: true})
+	assert.Equal(t, map[string]any{"message": "stack", "stacktrace": "inner"}, m)
+
+	m = marshalError(err, ErrorOptions{DisableCauses: true, DisableType: true, DisableStacktrace: true})
+	assert.Equal(t, map[string]any{"message": "stack"}, m)
+
+	m = marshalError(fmt.Errorf("wrap: %w", newPkgError()), ErrorOptions{})
+	assert.Contains(t, m["stacktrace"], "newPkgError")
+	assert.Contains(t, m["stacktrace"], "errors_test.go")
+}
+
+type tBadStackError struct{}
+
+func (e tBadStackError) Error() string { return "bad\n\tdetails" }
+
+func (e tBadStackError) Format(s fmt.State, _ rune) { _, _ = io.WriteString(s, e.Error()) }
+
+func Test_ownStacktrace(t *testing.T) {
+	assert.Empty(t, ownStacktrace(io.EOF))
+	assert.Empty(t, ownStacktrace(tBadStackError{}))
+	assert.Empty(t, ownStacktrace(tPkgError{}))
+	assert.Empty(t, ownStacktrace((*tPkgError)(nil)))
+	assert.Empty(t, ownStacktrace((*tStackError)(nil)))
+	stack := ownStacktrace(newPkgError())
+	assert.True(t, strings.HasPrefix(stack, "github.com/yimi-go/zap-logging.newPkgError\n\t"), stack)
+	assert.Contains(t, stack, "errors_test.go:")
+}
+
+func Test_errorCore(t *testing.T) {
+	var entries []map[string]any
+	core := newErrorCore(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		zapcore.AddSync(writerFunc(func(p []byte) (int, error) {
+			m := map[string]any{}
+			assert.Nil(t, json.Unmarshal(p, &m))
+			entries = append(entries, m)
+			return len(p), nil
+		})),
+		zapcore.InfoLevel,
+	), ErrorOptions{DisableType: true}.Defaulted())
+	l := zap.New(core).With(zap.Error(io.EOF), zap.String("a", "b"))
+	l.Debug("ignored", zap.NamedError("cause", io.ErrUnexpectedEOF))
+	l.Info("hello", zap.NamedError("cause", io.ErrUnexpectedEOF))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, map[string]any{"message": "EOF"}, entries[0]["error"])
+	assert.Equal(t, map[string]any{"message": "unexpected EOF"}, entries[0]["cause"])
+	assert.Equal(t, "b", entries[0]["a"])
+}
+
+type tErrorObject struct{}
+
+func (tErrorObject) MarshalLogFields() []logging.Field {
+	return []logging.Field{logging.Error(io.EOF)}
+}
+
+func Test_errorCore_nested(t *testing.T) {
+	var entries []map[string]any
+	core := newErrorCore(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		zapcore.AddSync(writerFunc(func(p []byte) (int, error) {
+			m := map[string]any{}
+			assert.Nil(t, json.Unmarshal(p, &m))
+			entries = append(entries, m)
+			return len(p), nil
+		})),
+		zapcore.InfoLevel,
+	), ErrorOptions{DisableType: true}.Defaulted())
+	eof := map[string]any{"message": "EOF"}
+	l := zap.New(core).With(mapZapField(Group("static", logging.Error(io.EOF))))
+	l.Info("hello",
+		mapZapField(Group("g", logging.Error(io.EOF), Group("inner", logging.NamedError("cause", io.EOF)))),
+		mapZapField(logging.Any("obj", tErrorObject{})),
+		mapZapField(Lazy("lazy", func() any { return io.EOF })),
+		mapZapField(Namespace("ns")),
+		mapZapField(logging.Error(io.EOF)),
+	)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, map[string]any{"error": eof}, entries[0]["static"])
+	assert.Equal(t, map[string]any{"error": eof, "inner": map[string]any{"cause": eof}}, entries[0]["g"])
+	assert.Equal(t, map[string]any{"error": eof}, entries[0]["obj"])
+	assert.Equal(t, eof, entries[0]["lazy"])
+	assert.Equal(t, map[string]any{"error": eof}, entries[0]["ns"])
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestStructuredErrors(t *testing.T) {
+	o := &Options{}
+	StructuredErrors(true)(o)
+	assert.True(t, o.Errors.Structured)
+}
+
+func TestErrors(t *testing.T) {
+	o := &Options{}
+	Errors(ErrorOptions{Structured: true, DisableType: true})(o)
+	assert.Equal(t, ErrorOptions{Structured: true, DisableType: true}, o.Errors)
+}
+
+func TestStructuredErrors_log(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel, StructuredErrors(true))
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	factory.Logger("foo").Infow("hello", logging.Error(fmt.Errorf("wrap: %w", errors.New("root"))))
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	t.Log(scanner.Text())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.Equal(t, map[string]any{
+		"message": "wrap: root",
+		"type":    "*fmt.wrapError",
+		"causes":  []any{map[string]any{"message": "root", "type": "*errors.errorString"}},
+	}, m["error"])
+}
@@ -1,6 +1,10 @@
 package zap
 
 import (
+	"fmt"
+	"os"
+	"sync"
+
 	"github.com/yimi-go/keeper"
 	"github.com/yimi-go/logging"
 	"go.uber.org/atomic"
@@ -10,6 +14,15 @@ import (
 type zapFactory struct {
 	zlCache keeper.Keeper[string, *zap.Logger]
 	options atomic.Value
+	// outputs are opened with the options, shared by zap loggers of all names.
+	outputs atomic.Value
+	// generation is increased every time options switched,
+	// so that loggers can tell whether their cached zap loggers are stale.
+	generation atomic.Uint64
+	// metrics counts entries of loggers of all options.
+	metrics *Metrics
+	// hooks are the hooks added to the factory, called with entries of loggers of all options.
+	hooks *hookList
 }
 
 func NewFactory(options *Options) logging.Factory {
@@ -17,14 +30,50 @@ func NewFactory(options *Options) logging.Factory {
 		options = NewOptions()
 	}
 	options = options.Defaulted()
-	zf := &zapFactory{}
+	zf := &zapFactory{metrics: NewMetrics(), hooks: &hookList{}}
+	options.metrics = zf.metrics
+	options.factoryHooks = zf.hooks
+	zf.outputs.Store(&factoryOutputs{options: options})
 	zf.options.Store(options)
 	zf.zlCache = keeper.NewKeeper(func(key string) *zap.Logger {
-		return zf.options.Load().(*Options).newZapLogger(key)
+		return zf.outputs.Load().(*factoryOutputs).get().zapLogger(key)
 	})
 	return zf
 }
 
+// factoryOutputs opens outputs of the options on first use.
+type factoryOutputs struct {
+	options *Options
+	once    sync.Once
+	out     *outputs
+}
+
+// get opens the outputs if not yet, reporting the error and discarding entries if failed.
+func (f *factoryOutputs) get() *outputs {
+	f.once.Do(func() {
+		out, err := f.options.openOutputs()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "zap-logging: failed to open outputs: %v\n", err)
+			out = nopOutputs(f.options)
+		}
+		f.out = out
+	})
+	return f.out
+}
+
+// close closes the outputs if opened. Outputs are never opened after that.
+func (f *factoryOutputs) close() error {
+	opened := true
+	f.once.Do(func() {
+		opened = false
+		f.out = nopOutputs(f.options)
+	})
+	if !opened {
+		return nil
+	}
+	return f.out.close()
+}
+
 func (z *zapFactory) Logger(name string) logging.Logger {
 	return &zapLogger{
 		name:    name,
@@ -42,11 +91,24 @@ func (z *zapFactory) zap(name string) *zap.Logger {
 	return z.zlCache.Get(name)
 }
 
+// SwitchOptions switches the options of loggers created by the factory.
+//
+// Outputs of the old options are closed after writes in flight finish,
+// loggers open outputs of the new options on their next writes.
+// Entries checked by the old outputs but written after they are closed are dropped,
+// and counted as WriteFailed by the factory Metrics.
 func (z *zapFactory) SwitchOptions(options *Options) {
 	if options == nil {
 		return
 	}
 	options = options.Defaulted()
+	options.metrics = z.metrics
+	options.factoryHooks = z.hooks
+	old := z.outputs.Swap(&factoryOutputs{options: options}).(*factoryOutputs)
 	z.options.Store(options)
 	z.zlCache.Clear()
+	z.generation.Inc()
+	if err := old.close(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "zap-logging: failed to close outputs: %v\n", err)
+	}
 }
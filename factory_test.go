@@ -1,10 +1,14 @@
 package zap
 
 import (
+	"net/url"
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestNewFactory(t *testing.T) {
@@ -21,6 +25,8 @@ func TestNewFactory(t *testing.T) {
 			args: args{nil},
 			validate: func(f *zapFactory, t *testing.T) {
 				newOptions := NewOptions()
+				newOptions.metrics = f.metrics
+				newOptions.factoryHooks = f.hooks
 				if !reflect.DeepEqual(f.options.Load().(*Options), newOptions) {
 					t.Errorf("NewZapFactory() options = %v, want %v", f.options.Load().(*Options), newOptions)
 				}
@@ -103,3 +109,38 @@ func Test_zapFactory_SwitchOptions(t *testing.T) {
 		t.Errorf("zapFactory.SwitchOptions() options is not updated")
 	}
 }
+
+func Test_zapFactory_SwitchOptions_outputs(t *testing.T) {
+	var sinks []*tEntrySink
+	registerSink("test-switch", func(u *url.URL, o *Options) (zap.Sink, error) {
+		sink := &tEntrySink{}
+		sinks = append(sinks, sink)
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(OutputPaths("test-switch://"))).(*zapFactory)
+	// Outputs are opened on first use, and shared by loggers of all names.
+	assert.Len(t, sinks, 0)
+	factory.Logger("foo").Info("abc")
+	factory.Logger("bar").Info("def")
+	assert.Len(t, sinks, 1)
+	assert.Len(t, sinks[0].entries, 2)
+
+	factory.SwitchOptions(NewOptions(OutputPaths("test-switch://")))
+	assert.True(t, sinks[0].closed)
+	factory.Logger("foo").Info("ghi")
+	assert.Len(t, sinks, 2)
+	assert.Len(t, sinks[1].entries, 1)
+
+	// Entries checked by the old outputs are counted as failed rather than written to closed sinks.
+	ce := factory.zap("foo").Check(zapcore.InfoLevel, "jkl")
+	factory.SwitchOptions(NewOptions(OutputPaths("test-switch://")))
+	assert.True(t, sinks[1].closed)
+	ce.Write()
+	assert.Len(t, sinks[1].entries, 1)
+	assert.Contains(t, FactoryMetrics(factory).Snapshot(), EntryCounts{Logger: "foo", Level: "info", Emitted: 2, WriteFailed: 1})
+
+	// Outputs never opened are not opened by closing.
+	factory.SwitchOptions(NewOptions(OutputPaths("test-switch://")))
+	factory.SwitchOptions(NewOptions())
+	assert.Len(t, sinks, 2)
+}
@@ -2,6 +2,7 @@ package zap
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/yimi-go/logging"
@@ -9,6 +10,31 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// Field types extended by this package.
+// They are numbered down from the top of logging.FieldType to avoid clashing with types defined by logging.
+const (
+	// NamespaceType is the type of fields created by Namespace.
+	NamespaceType logging.FieldType = math.MaxUint8 - iota
+	// GroupType is the type of fields created by Group.
+	GroupType
+	// LazyType is the type of fields created by Lazy.
+	LazyType
+	// RingBufferType is the type of fields added by RingBuffered and ContextWithRingBuffer.
+	RingBufferType
+	// LevelOverrideType is the type of fields created by LevelOverride.
+	LevelOverrideType
+)
+
+type field struct {
+	val any
+	key string
+	typ logging.FieldType
+}
+
+func (f field) Key() string             { return f.key }
+func (f field) Type() logging.FieldType { return f.typ }
+func (f field) Value() any              { return f.val }
+
 func mapZapField(field logging.Field) zapcore.Field {
 	switch field.Type() {
 	case logging.BinaryType:
@@ -53,7 +79,17 @@ func mapZapField(field logging.Field) zapcore.Field {
 		return zap.NamedError(field.Key(), field.Value().(error))
 	case logging.StackType:
 		return zap.StackSkip(field.Key(), field.Value().(int)+3)
+	case NamespaceType:
+		return zap.Namespace(field.Key())
+	case GroupType:
+		return zap.Object(field.Key(), fieldsMarshaler(field.Value().([]logging.Field)))
+	case LazyType:
+		return zap.Inline(&lazyMarshaler{key: field.Key(), value: field.Value().(func() any)})
+	case RingBufferType:
+		return zapcore.Field{Type: zapcore.SkipType, Interface: field.Value()}
+	case LevelOverrideType:
+		return zapcore.Field{Type: zapcore.SkipType, Interface: levelOverride(field.Value().(logging.Level))}
 	default:
-		return zap.Any(field.Key(), field.Value())
+		return mapZapValue(field.Key(), field.Value())
 	}
 }
@@ -0,0 +1,265 @@
+package zap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var flatPool = buffer.NewPool()
+
+// flatEncoder flattens fields to key value pairs of strings.
+// Keys of fields nested in objects and namespaces are prefixed by the keys of the objects and namespaces.
+//
+// How pairs are written to the buffer depends on the format of encoders built on it.
+type flatEncoder struct {
+	*zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// appendPair appends the pair to the buffer.
+	appendPair func(buf *buffer.Buffer, namespaces []string, key, value string)
+	// appendNumber appends the pair of a number to the buffer, if formats differ numbers from strings.
+	appendNumber func(buf *buffer.Buffer, namespaces []string, key, value string)
+	// namespaces are the prefixes of keys, opened by namespaces and nested objects.
+	namespaces []string
+}
+
+func (enc *flatEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &flatArrayEncoder{config: enc.EncoderConfig}
+	err := marshaler.MarshalLogArray(arr)
+	enc.addString(key, arr.array())
+	return err
+}
+
+func (enc *flatEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	// namespaces opened by the marshaler are closed with the object as well.
+	n := len(enc.namespaces)
+	enc.namespaces = append(enc.namespaces, key)
+	err := marshaler.MarshalLogObject(enc)
+	enc.namespaces = enc.namespaces[:n]
+	return err
+}
+
+func (enc *flatEncoder) AddBinary(key string, value []byte) {
+	enc.addString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (enc *flatEncoder) AddByteString(key string, value []byte) {
+	enc.addString(key, string(value))
+}
+
+func (enc *flatEncoder) AddBool(key string, value bool) {
+	enc.addString(key, strconv.FormatBool(value))
+}
+
+func (enc *flatEncoder) AddComplex128(key string, value complex128) {
+	enc.addString(key, strconv.FormatComplex(value, 'f', -1, 128))
+}
+
+func (enc *flatEncoder) AddComplex64(key string, value complex64) {
+	enc.addString(key, strconv.FormatComplex(complex128(value), 'f', -1, 64))
+}
+
+func (enc *flatEncoder) AddDuration(key string, value time.Duration) {
+	arr := &flatArrayEncoder{config: enc.EncoderConfig}
+	arr.AppendDuration(value)
+	enc.addString(key, arr.value())
+}
+
+func (enc *flatEncoder) AddFloat64(key string, value float64) {
+	enc.addNumber(key, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (enc *flatEncoder) AddFloat32(key string, value float32) {
+	enc.addNumber(key, strconv.FormatFloat(float64(value), 'f', -1, 32))
+}
+
+func (enc *flatEncoder) AddInt(key string, value int) { enc.AddInt64(key, int64(value)) }
+
+func (enc *flatEncoder) AddInt64(key string, value int64) {
+	enc.addNumber(key, strconv.FormatInt(value, 10))
+}
+
+func (enc *flatEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+func (enc *flatEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+func (enc *flatEncoder) AddInt8(key string, value int8)   { enc.AddInt64(key, int64(value)) }
+
+func (enc *flatEncoder) AddString(key, value string) {
+	enc.addString(key, value)
+}
+
+func (enc *flatEncoder) AddTime(key string, value time.Time) {
+	arr := &flatArrayEncoder{config: enc.EncoderConfig}
+	arr.AppendTime(value)
+	enc.addString(key, arr.value())
+}
+
+func (enc *flatEncoder) AddUint(key string, value uint) { enc.AddUint64(key, uint64(value)) }
+
+func (enc *flatEncoder) AddUint64(key string, value uint64) {
+	enc.addNumber(key, strconv.FormatUint(value, 10))
+}
+
+func (enc *flatEncoder) AddUint32(key string, value uint32)   { enc.AddUint64(key, uint64(value)) }
+func (enc *flatEncoder) AddUint16(key string, value uint16)   { enc.AddUint64(key, uint64(value)) }
+func (enc *flatEncoder) AddUint8(key string, value uint8)     { enc.AddUint64(key, uint64(value)) }
+func (enc *flatEncoder) AddUintptr(key string, value uintptr) { enc.AddUint64(key, uint64(value)) }
+
+func (enc *flatEncoder) AddReflected(key string, value any) error {
+	s, err := reflectedString(value)
+	if err != nil {
+		return err
+	}
+	enc.addString(key, s)
+	return nil
+}
+
+func (enc *flatEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces, key)
+}
+
+// clone copies the encoder, along with the pairs added.
+func (enc *flatEncoder) clone() *flatEncoder {
+	namespaces := make([]string, len(enc.namespaces))
+	copy(namespaces, enc.namespaces)
+	clone := &flatEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           flatPool.Get(),
+		appendPair:    enc.appendPair,
+		appendNumber:  enc.appendNumber,
+		namespaces:    namespaces,
+	}
+	_, _ = clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *flatEncoder) addString(key, value string) {
+	enc.appendPair(enc.buf, enc.namespaces, key, value)
+}
+
+func (enc *flatEncoder) addNumber(key, value string) {
+	if enc.appendNumber == nil {
+		enc.addString(key, value)
+		return
+	}
+	enc.appendNumber(enc.buf, enc.namespaces, key, value)
+}
+
+func reflectedString(value any) (string, error) {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// flatArrayEncoder collects array elements or a single primitive value as strings.
+//
+// Arrays are formatted like [a,b], objects in arrays are formatted like {k=v k2=v2}.
+type flatArrayEncoder struct {
+	config   *zapcore.EncoderConfig
+	elements []string
+}
+
+func (arr *flatArrayEncoder) value() string {
+	return strings.Join(arr.elements, ",")
+}
+
+func (arr *flatArrayEncoder) array() string {
+	return "[" + strings.Join(arr.elements, ",") + "]"
+}
+
+func (arr *flatArrayEncoder) AppendBool(v bool) {
+	arr.elements = append(arr.elements, strconv.FormatBool(v))
+}
+
+func (arr *flatArrayEncoder) AppendByteString(v []byte) {
+	arr.elements = append(arr.elements, string(v))
+}
+
+func (arr *flatArrayEncoder) AppendComplex128(v complex128) {
+	arr.elements = append(arr.elements, strconv.FormatComplex(v, 'f', -1, 128))
+}
+
+func (arr *flatArrayEncoder) AppendComplex64(v complex64) {
+	arr.elements = append(arr.elements, strconv.FormatComplex(complex128(v), 'f', -1, 64))
+}
+
+func (arr *flatArrayEncoder) AppendFloat64(v float64) {
+	arr.elements = append(arr.elements, strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+func (arr *flatArrayEncoder) AppendFloat32(v float32) {
+	arr.elements = append(arr.elements, strconv.FormatFloat(float64(v), 'f', -1, 32))
+}
+
+func (arr *flatArrayEncoder) AppendInt(v int)     { arr.AppendInt64(int64(v)) }
+func (arr *flatArrayEncoder) AppendInt32(v int32) { arr.AppendInt64(int64(v)) }
+func (arr *flatArrayEncoder) AppendInt16(v int16) { arr.AppendInt64(int64(v)) }
+func (arr *flatArrayEncoder) AppendInt8(v int8)   { arr.AppendInt64(int64(v)) }
+
+func (arr *flatArrayEncoder) AppendInt64(v int64) {
+	arr.elements = append(arr.elements, strconv.FormatInt(v, 10))
+}
+
+func (arr *flatArrayEncoder) AppendString(v string) {
+	arr.elements = append(arr.elements, v)
+}
+
+func (arr *flatArrayEncoder) AppendUint(v uint)       { arr.AppendUint64(uint64(v)) }
+func (arr *flatArrayEncoder) AppendUint32(v uint32)   { arr.AppendUint64(uint64(v)) }
+func (arr *flatArrayEncoder) AppendUint16(v uint16)   { arr.AppendUint64(uint64(v)) }
+func (arr *flatArrayEncoder) AppendUint8(v uint8)     { arr.AppendUint64(uint64(v)) }
+func (arr *flatArrayEncoder) AppendUintptr(v uintptr) { arr.AppendUint64(uint64(v)) }
+
+func (arr *flatArrayEncoder) AppendUint64(v uint64) {
+	arr.elements = append(arr.elements, strconv.FormatUint(v, 10))
+}
+
+func (arr *flatArrayEncoder) AppendDuration(v time.Duration) {
+	cur := len(arr.elements)
+	if arr.config.EncodeDuration != nil {
+		arr.config.EncodeDuration(v, arr)
+	}
+	if cur == len(arr.elements) {
+		arr.elements = append(arr.elements, v.String())
+	}
+}
+
+func (arr *flatArrayEncoder) AppendTime(v time.Time) {
+	cur := len(arr.elements)
+	if arr.config.EncodeTime != nil {
+		arr.config.EncodeTime(v, arr)
+	}
+	if cur == len(arr.elements) {
+		arr.elements = append(arr.elements, v.Format(time.RFC3339Nano))
+	}
+}
+
+func (arr *flatArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := &flatArrayEncoder{config: arr.config}
+	err := marshaler.MarshalLogArray(nested)
+	arr.elements = append(arr.elements, nested.array())
+	return err
+}
+
+func (arr *flatArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	enc := newFlatLogfmtEncoder(arr.config)
+	defer enc.buf.Free()
+	err := marshaler.MarshalLogObject(enc)
+	arr.elements = append(arr.elements, "{"+enc.buf.String()+"}")
+	return err
+}
+
+func (arr *flatArrayEncoder) AppendReflected(value any) error {
+	s, err := reflectedString(value)
+	if err != nil {
+		return err
+	}
+	arr.elements = append(arr.elements, s)
+	return nil
+}
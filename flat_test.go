@@ -0,0 +1,55 @@
+package zap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func Test_flatEncoder_AddReflected(t *testing.T) {
+	enc := NewLogfmtEncoder(testLogfmtEncoderConfig())
+	assert.NotNil(t, enc.AddReflected("k", make(chan int)))
+}
+
+func Test_flatEncoder_AddObject_namespace(t *testing.T) {
+	enc := NewLogfmtEncoder(testLogfmtEncoderConfig()).(*logfmtEncoder)
+	assert.Nil(t, enc.AddObject("k", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.OpenNamespace("ns")
+		enc.AddString("a", "b")
+		return nil
+	})))
+	// namespaces opened in the object are closed with it.
+	enc.AddString("c", "d")
+	assert.Equal(t, "k.ns.a=b c=d", enc.buf.String())
+}
+
+func Test_flatArrayEncoder(t *testing.T) {
+	arr := &flatArrayEncoder{config: &zapcore.EncoderConfig{}}
+	assert.NotNil(t, arr.AppendReflected(make(chan int)))
+	assert.Nil(t, arr.AppendReflected(1))
+	arr.AppendTime(time.Unix(0, 0).UTC())
+	arr.AppendDuration(time.Second)
+	assert.Nil(t, arr.AppendArray(zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		enc.AppendBool(true)
+		enc.AppendByteString([]byte("b"))
+		enc.AppendComplex128(1)
+		enc.AppendComplex64(1)
+		enc.AppendFloat64(1)
+		enc.AppendFloat32(1)
+		enc.AppendInt(1)
+		enc.AppendInt64(1)
+		enc.AppendInt32(1)
+		enc.AppendInt16(1)
+		enc.AppendInt8(1)
+		enc.AppendUint(1)
+		enc.AppendUint64(1)
+		enc.AppendUint32(1)
+		enc.AppendUint16(1)
+		enc.AppendUint8(1)
+		enc.AppendUintptr(1)
+		return nil
+	})))
+	assert.Equal(t, "[1,1970-01-01T00:00:00Z,1s,[true,b,(1+0i),(1+0i),1,1,1,1,1,1,1,1,1,1,1,1,1]]", arr.array())
+}
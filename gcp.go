@@ -0,0 +1,200 @@
+package zap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	schemas["gcp"] = schema{
+		encoderConfig: gcpEncoderConfig,
+		wrapCore: func(core zapcore.Core, o *Options) zapcore.Core {
+			project := o.GCPProject
+			if project == "" {
+				project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+			}
+			return &gcpCore{Core: core, project: project}
+		},
+	}
+}
+
+func gcpEncoderConfig(config *zapcore.EncoderConfig) {
+	config.TimeKey = "timestamp"
+	config.LevelKey = "severity"
+	config.NameKey = "logger"
+	config.MessageKey = "message"
+	config.StacktraceKey = "stack_trace"
+	// The caller is written as logging.googleapis.com/sourceLocation by gcpCore.
+	config.CallerKey = zapcore.OmitKey
+	config.FunctionKey = zapcore.OmitKey
+	config.EncodeLevel = gcpLevelEncoder
+	config.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// gcpLevelEncoder encodes the level as the severity of Cloud Logging.
+func gcpLevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch level {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.PanicLevel:
+		enc.AppendString("ALERT")
+	case zapcore.FatalLevel:
+		enc.AppendString("EMERGENCY")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+// gcpCore rewrites entries to the special fields of Google Cloud Logging structured logging.
+//
+// It writes the caller as logging.googleapis.com/sourceLocation, fields keyed as trace and span ids,
+// e.g. trace_id and span_id, as logging.googleapis.com/trace and logging.googleapis.com/spanId,
+// and the object field keyed labels, e.g. a Group, as logging.googleapis.com/labels with values as strings.
+// Fields nested in namespaces are not rewritten.
+type gcpCore struct {
+	zapcore.Core
+	project string
+	// labels, trace and spanID are taken from accumulated fields, to be written along with those of entries.
+	labels []zapcore.ObjectMarshaler
+	trace  string
+	spanID string
+	// nested are the accumulated fields since the first namespace,
+	// which are written after the special fields so that those are not nested.
+	nested []zapcore.Field
+}
+
+func (c *gcpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.labels = c.labels[:len(c.labels):len(c.labels)]
+	flat, nested := splitNamespace(fields, len(c.nested) != 0)
+	clone.nested = append(c.nested[:len(c.nested):len(c.nested)], nested...)
+	clone.Core = c.Core.With(clone.take(flat))
+	return &clone
+}
+
+func (c *gcpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *gcpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	state := *c
+	state.labels = c.labels[:len(c.labels):len(c.labels)]
+	flat, nested := splitNamespace(fields, len(c.nested) != 0)
+	flat = state.take(flat)
+	res := make([]zapcore.Field, 0, len(flat)+len(c.nested)+len(nested)+4)
+	if ent.Caller.Defined {
+		res = append(res, zap.Object("logging.googleapis.com/sourceLocation", gcpSourceLocation(ent.Caller)))
+	}
+	if state.trace != "" {
+		trace := state.trace
+		if state.project != "" {
+			trace = "projects/" + state.project + "/traces/" + trace
+		}
+		res = append(res, zap.String("logging.googleapis.com/trace", trace))
+	}
+	if state.spanID != "" {
+		res = append(res, zap.String("logging.googleapis.com/spanId", state.spanID))
+	}
+	if len(state.labels) != 0 {
+		res = append(res, zap.Object("logging.googleapis.com/labels", gcpLabels(state.labels)))
+	}
+	res = append(res, flat...)
+	res = append(res, c.nested...)
+	res = append(res, nested...)
+	return c.Core.Write(ent, res)
+}
+
+// take takes the fields written as special fields from the fields not nested, and returns the others.
+func (c *gcpCore) take(fields []zapcore.Field) []zapcore.Field {
+	res := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		key := f.Key
+		if k, ok := ecsFieldKeys[key]; ok {
+			key = k
+		}
+		switch {
+		case f.Type == zapcore.StringType && key == "trace.id":
+			c.trace = f.String
+		case f.Type == zapcore.StringType && key == "span.id":
+			c.spanID = f.String
+		case f.Type == zapcore.ObjectMarshalerType && key == "labels":
+			c.labels = append(c.labels, f.Interface.(zapcore.ObjectMarshaler))
+		default:
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// gcpSourceLocation is the caller written as logging.googleapis.com/sourceLocation.
+type gcpSourceLocation zapcore.EntryCaller
+
+func (l gcpSourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", l.File)
+	// The line is an int64, which is a string in JSON of the Cloud Logging API.
+	enc.AddString("line", strconv.Itoa(l.Line))
+	if l.Function != "" {
+		enc.AddString("function", l.Function)
+	}
+	return nil
+}
+
+// gcpLabels are objects merged as logging.googleapis.com/labels, with values as strings.
+type gcpLabels []zapcore.ObjectMarshaler
+
+func (ls gcpLabels) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	labels := zapcore.NewMapObjectEncoder()
+	for _, l := range ls {
+		if err := l.MarshalLogObject(labels); err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(labels.Fields))
+	for key := range labels.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := labels.Fields[key]
+		switch v := value.(type) {
+		case string:
+			enc.AddString(key, v)
+			continue
+		case time.Time:
+			enc.AddString(key, v.Format(time.RFC3339Nano))
+			continue
+		}
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			enc.AddString(key, string(b))
+		default:
+			enc.AddString(key, fmt.Sprint(value))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package zap
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGCP(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-gcp", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-gcp://"),
+		Schema("gcp"),
+		GCPProject("my-project"),
+		Levels(map[string]logging.Level{"": logging.DebugLevel}),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	logger := factory.Logger("foo").WithField(
+		logging.String("trace_id", "0af7651916cd43dd8448eb211c80319c"),
+		Group("labels", logging.String("team", "a")),
+	)
+	logger.Debugw("abc", logging.String("span_id", "b7ad6b7169203331"), Group("labels", logging.Int("shard", 3)))
+	logger.Warn("def")
+	logger.WithField(Namespace("ns"), logging.String("trace_id", "x")).Error("ghi")
+	assert.Len(t, sink.lines, 3)
+	decode := func(line string) map[string]any {
+		m := map[string]any{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &m), line)
+		return m
+	}
+
+	debug := decode(sink.lines[0])
+	ts, err := time.Parse(time.RFC3339Nano, debug["timestamp"].(string))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+	assert.True(t, strings.HasSuffix(debug["timestamp"].(string), "Z"))
+	delete(debug, "timestamp")
+	location := debug["logging.googleapis.com/sourceLocation"].(map[string]any)
+	assert.True(t, strings.HasSuffix(location["file"].(string), "gcp_test.go"))
+	assert.NotEmpty(t, location["line"])
+	assert.Equal(t, "github.com/yimi-go/zap-logging.TestGCP", location["function"])
+	delete(debug, "logging.googleapis.com/sourceLocation")
+	assert.Equal(t, map[string]any{
+		"severity":                      "DEBUG",
+		"logger":                        "foo",
+		"message":                       "abc",
+		"logging.googleapis.com/trace":  "projects/my-project/traces/0af7651916cd43dd8448eb211c80319c",
+		"logging.googleapis.com/spanId": "b7ad6b7169203331",
+		"logging.googleapis.com/labels": map[string]any{"team": "a", "shard": "3"},
+	}, debug)
+
+	warn := decode(sink.lines[1])
+	assert.Equal(t, "WARNING", warn["severity"])
+	assert.NotContains(t, warn, "logging.googleapis.com/spanId")
+	assert.Equal(t, map[string]any{"team": "a"}, warn["logging.googleapis.com/labels"])
+
+	// Fields nested in namespaces are not rewritten.
+	errEntry := decode(sink.lines[2])
+	assert.Equal(t, "ERROR", errEntry["severity"])
+	assert.Equal(t, map[string]any{"trace_id": "x"}, errEntry["ns"])
+	assert.Equal(t, "projects/my-project/traces/0af7651916cd43dd8448eb211c80319c", errEntry["logging.googleapis.com/trace"])
+	assert.NotEmpty(t, errEntry["stack_trace"])
+}
+
+func Test_gcpLevelEncoder(t *testing.T) {
+	tests := []struct {
+		level zapcore.Level
+		want  string
+	}{
+		{level: zapcore.DebugLevel, want: "DEBUG"},
+		{level: zapcore.InfoLevel, want: "INFO"},
+		{level: zapcore.WarnLevel, want: "WARNING"},
+		{level: zapcore.ErrorLevel, want: "ERROR"},
+		{level: zapcore.DPanicLevel, want: "CRITICAL"},
+		{level: zapcore.PanicLevel, want: "ALERT"},
+		{level: zapcore.FatalLevel, want: "EMERGENCY"},
+		{level: zapcore.Level(42), want: "DEFAULT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			arr := &flatArrayEncoder{}
+			gcpLevelEncoder(tt.level, arr)
+			assert.Equal(t, tt.want, arr.value())
+		})
+	}
+}
+
+func Test_gcpCore_project(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "env-project")
+	core := schemas["gcp"].wrapCore(zapcore.NewNopCore(), NewOptions())
+	assert.Equal(t, "env-project", core.(*gcpCore).project)
+	core = schemas["gcp"].wrapCore(zapcore.NewNopCore(), NewOptions(GCPProject("my-project")))
+	assert.Equal(t, "my-project", core.(*gcpCore).project)
+}
+
+func Test_gcpLabels(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	assert.Nil(t, gcpLabels{
+		fieldsMarshaler{logging.Bool("b", true), logging.Any("m", map[string]int{"x": 1})},
+		fieldsMarshaler{logging.String("s", "v"), Group("g", logging.Int("i", 1))},
+	}.MarshalLogObject(enc))
+	assert.Equal(t, map[string]any{
+		"b": "true",
+		"m": `{"x":1}`,
+		"s": "v",
+		"g": `{"i":1}`,
+	}, enc.Fields)
+}
@@ -0,0 +1,337 @@
+package zap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+)
+
+func init() {
+	registerEncoder("gelf", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewGELFEncoder(config), nil
+	})
+	registerSink("gelf+udp", newGELFUDPSink)
+	registerSink("gelf+tcp", newGELFTCPSink)
+}
+
+// NewGELFEncoder creates an encoder writing entries as GELF 1.1 payloads.
+//
+// The message is written as short_message, the level as the syslog severity in level,
+// the time as float seconds in timestamp, and the host name reported by the kernel as host.
+// The stacktrace is written as full_message along with the message. The logger name and the caller are
+// written as additional fields with their keys in EncoderConfig, e.g. _logger and _caller.
+// Fields are written as additional fields with keys prefixed by '_', keys of nested fields are joined by '_',
+// e.g. _http_request_method.
+func NewGELFEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	hostname, _ := os.Hostname()
+	return newGELFEncoder(config, hostname)
+}
+
+func newGELFEncoder(config zapcore.EncoderConfig, host string) *gelfEncoder {
+	return &gelfEncoder{flatEncoder: newFlatGELFEncoder(&config), host: host}
+}
+
+func newFlatGELFEncoder(config *zapcore.EncoderConfig) *flatEncoder {
+	return &flatEncoder{
+		EncoderConfig: config,
+		buf:           flatPool.Get(),
+		appendPair:    appendGELFPair,
+		appendNumber:  appendGELFNumber,
+	}
+}
+
+type gelfEncoder struct {
+	*flatEncoder
+	host string
+}
+
+func (enc *gelfEncoder) Clone() zapcore.Encoder {
+	return &gelfEncoder{flatEncoder: enc.clone(), host: enc.host}
+}
+
+func (enc *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := newFlatGELFEncoder(enc.EncoderConfig)
+	final.buf.AppendString(`{"version":"1.1","host":`)
+	appendJSONString(final.buf, enc.host)
+	final.buf.AppendString(`,"short_message":`)
+	appendJSONString(final.buf, ent.Message)
+	if ent.Stack != "" {
+		final.buf.AppendString(`,"full_message":`)
+		appendJSONString(final.buf, ent.Message+"\n"+ent.Stack)
+	}
+	final.buf.AppendString(`,"timestamp":`)
+	final.buf.AppendString(strconv.FormatFloat(float64(ent.Time.UnixMicro())/1e6, 'f', -1, 64))
+	final.buf.AppendString(`,"level":`)
+	final.buf.AppendInt(int64(syslogSeverity(ent.Level)))
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addString(final.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			arr := &flatArrayEncoder{config: final.EncoderConfig}
+			final.EncodeCaller(ent.Caller, arr)
+			caller := arr.value()
+			if caller == "" {
+				caller = ent.Caller.String()
+			}
+			final.addString(final.CallerKey, caller)
+		}
+		if final.FunctionKey != "" {
+			final.addString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	_, _ = final.buf.Write(enc.buf.Bytes())
+	final.namespaces = append(final.namespaces, enc.namespaces...)
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.buf.AppendByte('}')
+	if !final.SkipLineEnding {
+		if final.LineEnding != "" {
+			final.buf.AppendString(final.LineEnding)
+		} else {
+			final.buf.AppendString(zapcore.DefaultLineEnding)
+		}
+	}
+	return final.buf, nil
+}
+
+func appendGELFPair(buf *buffer.Buffer, namespaces []string, key, value string) {
+	appendGELFName(buf, namespaces, key)
+	appendJSONString(buf, value)
+}
+
+func appendGELFNumber(buf *buffer.Buffer, namespaces []string, key, value string) {
+	appendGELFName(buf, namespaces, key)
+	switch value {
+	case "NaN", "+Inf", "-Inf":
+		// Not valid JSON numbers.
+		appendJSONString(buf, value)
+	default:
+		buf.AppendString(value)
+	}
+}
+
+// appendGELFName appends the name of the additional field,
+// which consists of letters, digits, underscores, dashes and dots, prefixed by an underscore.
+func appendGELFName(buf *buffer.Buffer, namespaces []string, key string) {
+	name := key
+	if len(namespaces) != 0 {
+		name = strings.Join(namespaces, "_") + "_" + key
+	}
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+	switch name {
+	case "":
+		name = "field"
+	case "id":
+		// _id is reserved.
+		name = "id_"
+	}
+	buf.AppendString(`,"_`)
+	buf.AppendString(name)
+	buf.AppendString(`":`)
+}
+
+// appendJSONString appends the string as a JSON string.
+func appendJSONString(buf *buffer.Buffer, s string) {
+	buf.AppendByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.AppendByte('\\')
+			buf.AppendByte(byte(r))
+		case r == '\n':
+			buf.AppendString(`\n`)
+		case r == '\r':
+			buf.AppendString(`\r`)
+		case r == '\t':
+			buf.AppendString(`\t`)
+		case r < 0x20:
+			buf.AppendString(`\u00`)
+			buf.AppendByte("0123456789abcdef"[r>>4])
+			buf.AppendByte("0123456789abcdef"[r&0xf])
+		case r < utf8.RuneSelf:
+			buf.AppendByte(byte(r))
+		default:
+			var b [utf8.UTFMax]byte
+			n := utf8.EncodeRune(b[:], r)
+			_, _ = buf.Write(b[:n])
+		}
+	}
+	buf.AppendByte('"')
+}
+
+// gelfHostname returns the host of GELF payloads set by the query parameter "hostname",
+// the host name reported by the kernel as default.
+func gelfHostname(query url.Values) string {
+	hostname := query.Get("hostname")
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	return hostname
+}
+
+// gelfUDPSink writes entries to Graylog as GELF over UDP, chunked if too large.
+//
+// The output path is like:
+//
+//	gelf+udp://127.0.0.1:12201?chunk_size=8154&compress=gzip
+//
+// Query parameters:
+//   - chunk_size: maximum size of datagrams, messages larger than it are chunked. 1420 as default.
+//   - compress: "gzip" to compress messages, or "none". "none" as default.
+//   - hostname: host of the messages. The host name reported by the kernel as default.
+//
+// Entries are written in GELF, rather than in the encoding configured in Options.
+// Messages exceeding 128 chunks are dropped, as Graylog does not accept them.
+type gelfUDPSink struct {
+	conn      *redialConn
+	host      string
+	chunkSize int
+	gzip      bool
+}
+
+func newGELFUDPSink(u *url.URL, _ *Options) (zap.Sink, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing address of %s output", u.Scheme)
+	}
+	query := u.Query()
+	s := &gelfUDPSink{host: gelfHostname(query)}
+	var err error
+	if s.chunkSize, err = queryValue(query, "chunk_size", 1420, strconv.Atoi); err != nil {
+		return nil, err
+	}
+	if s.chunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("invalid chunk_size: %d", s.chunkSize)
+	}
+	switch compress := query.Get("compress"); compress {
+	case "", "none":
+	case "gzip":
+		s.gzip = true
+	default:
+		return nil, fmt.Errorf("unknown compress: %q", compress)
+	}
+	conn, err := newRedialConn(func() (net.Conn, error) {
+		return net.DialTimeout("udp", u.Host, 5*time.Second)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return s, nil
+}
+
+func (s *gelfUDPSink) newEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return newGELFEncoder(config, s.host)
+}
+
+func (s *gelfUDPSink) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\r\n")
+	if s.gzip {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		_, _ = zw.Write(msg)
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		msg = buf.Bytes()
+	}
+	if len(msg) <= s.chunkSize {
+		if _, err := s.conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	chunks, err := gelfChunks(msg, s.chunkSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, chunk := range chunks {
+		if _, err := s.conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// gelfChunks splits the message into chunks no larger than the size, including the chunk headers.
+func gelfChunks(msg []byte, size int) ([][]byte, error) {
+	payload := size - gelfChunkHeaderSize
+	count := (len(msg) + payload - 1) / payload
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("GELF message too large: %d bytes in %d chunks", len(msg), count)
+	}
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payload
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk := make([]byte, 0, gelfChunkHeaderSize+end-i*payload)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*payload:end]...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func (s *gelfUDPSink) Sync() error {
+	return nil
+}
+
+func (s *gelfUDPSink) Close() error {
+	return s.conn.Close()
+}
+
+// gelfTCPSink writes entries to Graylog as GELF over TCP, terminated by null bytes.
+//
+// The output path is like:
+//
+//	gelf+tcp://127.0.0.1:12201?tls=true
+//
+// Query parameters are those of tcp outputs except framing, and hostname as host of the messages,
+// the host name reported by the kernel as default.
+// Entries are written in GELF, rather than in the encoding configured in Options.
+type gelfTCPSink struct {
+	*networkSink
+	host string
+}
+
+func newGELFTCPSink(u *url.URL, _ *Options) (zap.Sink, error) {
+	sink, err := openNetworkSink(u, "tcp", "null")
+	if err != nil {
+		return nil, err
+	}
+	return &gelfTCPSink{networkSink: sink, host: gelfHostname(u.Query())}, nil
+}
+
+func (s *gelfTCPSink) newEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return newGELFEncoder(config, s.host)
+}
@@ -0,0 +1,281 @@
+package zap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func decodeGELF(t *testing.T, p []byte) map[string]any {
+	t.Helper()
+	msg := map[string]any{}
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if err := decoder.Decode(&msg); err != nil {
+		t.Fatalf("invalid GELF %q: %v", p, err)
+	}
+	return msg
+}
+
+func TestGELFEncoder_EncodeEntry(t *testing.T) {
+	enc := newGELFEncoder(testLogfmtEncoderConfig(), "host-a")
+	enc.AddString("service", "demo")
+	enc.OpenNamespace("ctx")
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Unix(1700000000, 123456000),
+		LoggerName: "foo",
+		Message:    "hello \"world\"",
+		Caller:     zapcore.NewEntryCaller(0, "/a/b/c.go", 12, true),
+		Stack:      "goroutine 1",
+	}
+	ent.Caller.Function = "main.main"
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.Int("count", 3),
+		zap.Float64("ratio", 0.5),
+		zap.Float64("nan", math.NaN()),
+		zap.Bool("ok", true),
+		zap.String("id", "x"),
+		zap.Object("http", fieldsMarshaler{Group("request", logging.String("method", "GET"))}),
+	})
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("}\n")))
+	assert.Equal(t, map[string]any{
+		"version":                  "1.1",
+		"host":                     "host-a",
+		"short_message":            `hello "world"`,
+		"full_message":             "hello \"world\"\ngoroutine 1",
+		"timestamp":                json.Number("1700000000.123456"),
+		"level":                    json.Number("4"),
+		"_logger":                  "foo",
+		"_caller":                  "b/c.go:12",
+		"_func":                    "main.main",
+		"_service":                 "demo",
+		"_ctx_count":               json.Number("3"),
+		"_ctx_ratio":               json.Number("0.5"),
+		"_ctx_nan":                 "NaN",
+		"_ctx_ok":                  "true",
+		"_ctx_id":                  "x",
+		"_ctx_http_request_method": "GET",
+	}, decodeGELF(t, buf.Bytes()))
+
+	// Context fields are kept by clones.
+	clone := enc.Clone().(*gelfEncoder)
+	clone.AddInt("id", 1)
+	buf, err = clone.EncodeEntry(zapcore.Entry{Message: "a"}, nil)
+	assert.Nil(t, err)
+	msg := decodeGELF(t, buf.Bytes())
+	assert.Equal(t, "demo", msg["_service"])
+	assert.Equal(t, json.Number("1"), msg["_ctx_id"])
+	assert.NotContains(t, msg, "_logger")
+	assert.NotContains(t, msg, "full_message")
+}
+
+func Test_appendGELFName(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		key        string
+		want       string
+	}{
+		{name: "plain", key: "user", want: `,"_user":`},
+		{name: "nested", namespaces: []string{"http", "req"}, key: "a.b-c", want: `,"_http_req_a.b-c":`},
+		{name: "invalid", key: "a b/c", want: `,"_a_b_c":`},
+		{name: "empty", key: "", want: `,"_field":`},
+		{name: "id", key: "id", want: `,"_id_":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := flatPool.Get()
+			defer buf.Free()
+			appendGELFName(buf, tt.namespaces, tt.key)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func Test_appendJSONString(t *testing.T) {
+	for _, s := range []string{"", "abc", "a\"b\\c", "a\nb\rc\td", "\x00\x1f", "中文", "\xff"} {
+		buf := &buffer.Buffer{}
+		appendJSONString(buf, s)
+		var got string
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &got), buf.String())
+		if s == "\xff" {
+			s = "�"
+		}
+		assert.Equal(t, s, got)
+	}
+}
+
+func Test_newGELFUDPSink_error(t *testing.T) {
+	for _, path := range []string{
+		"gelf+udp://",
+		"gelf+udp://127.0.0.1:12201?chunk_size=foo",
+		"gelf+udp://127.0.0.1:12201?chunk_size=12",
+		"gelf+udp://127.0.0.1:12201?compress=zlib",
+	} {
+		u, err := url.Parse(path)
+		assert.Nil(t, err)
+		_, err = newGELFUDPSink(u, NewOptions())
+		assert.NotNil(t, err, path)
+	}
+}
+
+func TestGELFUDPSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	read := func() []byte {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 2048)
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		return buf[:n]
+	}
+	factory := NewFactory(NewOptions(
+		OutputPaths("gelf+udp://"+conn.LocalAddr().String()+"?hostname=host-a&chunk_size=256"),
+		DisableCaller(true),
+	)).(*zapFactory)
+	defer factory.SwitchOptions(NewOptions())
+
+	factory.Logger("foo").Info("abc")
+	msg := decodeGELF(t, read())
+	assert.Equal(t, "host-a", msg["host"])
+	assert.Equal(t, "abc", msg["short_message"])
+	assert.Equal(t, json.Number("6"), msg["level"])
+	assert.Equal(t, "foo", msg["_logger"])
+
+	// Chunked.
+	long := string(bytes.Repeat([]byte("x"), 600))
+	factory.Logger("foo").Info(long)
+	var payload []byte
+	var id []byte
+	for i := 0; ; i++ {
+		chunk := read()
+		assert.LessOrEqual(t, len(chunk), 256)
+		assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+		if id == nil {
+			id = chunk[2:10]
+		}
+		assert.Equal(t, id, chunk[2:10])
+		assert.Equal(t, byte(i), chunk[10])
+		payload = append(payload, chunk[12:]...)
+		if int(chunk[11]) == i+1 {
+			break
+		}
+	}
+	assert.Equal(t, long, decodeGELF(t, payload)["short_message"])
+}
+
+func TestGELFUDPSink_gzip(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	u, _ := url.Parse("gelf+udp://" + conn.LocalAddr().String() + "?compress=gzip")
+	sink, err := newGELFUDPSink(u, NewOptions())
+	assert.Nil(t, err)
+	defer func() {
+		_ = sink.Close()
+	}()
+	_, err = sink.Write([]byte(`{"short_message":"a"}` + "\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Sync())
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(buf[:n]))
+	assert.Nil(t, err)
+	msg, err := io.ReadAll(zr)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"short_message":"a"}`, string(msg))
+}
+
+func Test_gelfChunks(t *testing.T) {
+	chunks, err := gelfChunks(bytes.Repeat([]byte("x"), 25), 22)
+	assert.Nil(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Len(t, chunks[0], 22)
+	assert.Len(t, chunks[2], 17)
+	assert.Equal(t, byte(3), chunks[2][11])
+
+	_, err = gelfChunks(make([]byte, 129), 13)
+	assert.NotNil(t, err)
+}
+
+func TestGELFTCPSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	u, _ := url.Parse("gelf+tcp://" + ln.Addr().String() + "?hostname=host-a")
+	sink, err := newGELFTCPSink(u, NewOptions())
+	assert.Nil(t, err)
+	defer func() {
+		_ = sink.Close()
+	}()
+	logger := zap.New(newOutputCore(zapcore.NewJSONEncoder(testLogfmtEncoderConfig()), testLogfmtEncoderConfig(),
+		[]zap.Sink{sink}, zapcore.DebugLevel))
+	logger.Info("a")
+	logger.Error("b")
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"a", "b"} {
+		p, err := reader.ReadBytes(0)
+		assert.Nil(t, err)
+		msg := decodeGELF(t, p[:len(p)-1])
+		assert.Equal(t, want, msg["short_message"])
+		assert.Equal(t, "host-a", msg["host"])
+	}
+
+	u, _ = url.Parse("gelf+tcp://")
+	_, err = newGELFTCPSink(u, NewOptions())
+	assert.NotNil(t, err)
+}
+
+func TestGELF_encoding(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-gelf", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	newZapLogger(t, NewOptions(OutputPaths("test-gelf://"), Encoding("gelf")), "foo").
+		Error("abc", zap.Error(errors.New("boom")))
+	assert.Len(t, sink.lines, 1)
+	msg := decodeGELF(t, []byte(sink.lines[0]))
+	assert.Equal(t, "abc", msg["short_message"])
+	assert.Equal(t, json.Number("3"), msg["level"])
+	assert.Equal(t, "boom", msg["_error"])
+	assert.Contains(t, msg["full_message"], "abc\n")
+}
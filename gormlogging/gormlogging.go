@@ -0,0 +1,153 @@
+// Package gormlogging adapts a logging.Factory to the logger interface of GORM.
+package gormlogging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// maxDepth is the maximum number of GORM frames skipped by callers of entries.
+const maxDepth = 32
+
+// Options configures Loggers.
+type Options struct {
+	// SlowThreshold is the duration beyond which queries are written at WarnLevel. 200ms as default, negative to disable.
+	SlowThreshold time.Duration `json:"slow_threshold,omitempty" yaml:"slow_threshold,omitempty"`
+	// IgnoreRecordNotFoundError indicates whether queries failed with gorm.ErrRecordNotFound are not written as errors.
+	IgnoreRecordNotFoundError bool `json:"ignore_record_not_found_error,omitempty" yaml:"ignore_record_not_found_error,omitempty"`
+}
+
+// Defaulted returns a new Options filling blank items with default values.
+func (o Options) Defaulted() Options {
+	if o.SlowThreshold == 0 {
+		o.SlowThreshold = 200 * time.Millisecond
+	}
+	return o
+}
+
+// Logger is a GORM logger writing to a logger of a factory.
+//
+// Queries are written at DebugLevel, slow queries at WarnLevel and failed queries at ErrorLevel,
+// when enabled by both the level of the logger and LogMode, whose default is gormlogger.Info.
+// Fields of contexts added by logging.NewContext are written along with them.
+// Callers of entries are the callers of GORM.
+type Logger struct {
+	options Options
+	mode    gormlogger.LogLevel
+	// loggers are the loggers skipping callers of GORM, indexed by the number of GORM frames.
+	loggers *[maxDepth + 1]logging.Logger
+}
+
+var _ gormlogger.Interface = (*Logger)(nil)
+
+// New creates a Logger writing to the logger of the name of the factory, "gorm" if empty.
+func New(factory logging.Factory, name string, options Options) *Logger {
+	if name == "" {
+		name = "gorm"
+	}
+	l := &Logger{options: options.Defaulted(), mode: gormlogger.Info, loggers: &[maxDepth + 1]logging.Logger{}}
+	logger := factory.Logger(name)
+	for i := range l.loggers {
+		l.loggers[i] = zaplogging.AddCallerSkip(logger, 2+i)
+	}
+	return l
+}
+
+// LogMode returns a Logger of the mode.
+func (l *Logger) LogMode(mode gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.mode = mode
+	return &clone
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, data ...any) {
+	if l.mode >= gormlogger.Info {
+		l.log(ctx, logging.InfoLevel, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.mode >= gormlogger.Warn {
+		l.log(ctx, logging.WarnLevel, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, data ...any) {
+	if l.mode >= gormlogger.Error {
+		l.log(ctx, logging.ErrorLevel, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace writes the query.
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.mode <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	var (
+		level logging.Level
+		msg   string
+	)
+	switch {
+	case err != nil && l.mode >= gormlogger.Error &&
+		!(l.options.IgnoreRecordNotFoundError && errors.Is(err, gormlogger.ErrRecordNotFound)):
+		level, msg = logging.ErrorLevel, "sql failed"
+	case l.options.SlowThreshold > 0 && elapsed > l.options.SlowThreshold && l.mode >= gormlogger.Warn:
+		level, msg = logging.WarnLevel, "slow sql"
+	case l.mode >= gormlogger.Info:
+		level, msg = logging.DebugLevel, "sql"
+	default:
+		return
+	}
+	sql, rows := fc()
+	fields := []logging.Field{
+		logging.String("sql", sql),
+		logging.Duration("duration", elapsed),
+	}
+	if rows >= 0 {
+		fields = append(fields, logging.Int64("rows", rows))
+	}
+	if level == logging.WarnLevel {
+		fields = append(fields, logging.Duration("slow_threshold", l.options.SlowThreshold))
+	}
+	if level == logging.ErrorLevel {
+		fields = append(fields, logging.Error(err))
+	}
+	l.log(ctx, level, msg, fields...)
+}
+
+func (l *Logger) log(ctx context.Context, level logging.Level, msg string, fields ...logging.Field) {
+	logger := l.loggers[gormFrames()]
+	if !logger.Enabled(level) {
+		return
+	}
+	logger = logging.WithContextField(ctx, logger)
+	zaplogging.Logw(logger, level, msg, fields...)
+}
+
+// gormFrames returns the number of GORM frames calling the method of Logger.
+func gormFrames() int {
+	var pcs [maxDepth]uintptr
+	// skips runtime.Callers, gormFrames, log and the method of Logger.
+	n := runtime.Callers(4, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	count := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") || strings.HasSuffix(frame.File, "_test.go") {
+			return count
+		}
+		count++
+		if !more {
+			return count
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gormlogging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, Options{SlowThreshold: 200 * time.Millisecond}, Options{}.Defaulted())
+	assert.Equal(t, Options{SlowThreshold: -1}, Options{SlowThreshold: -1}.Defaulted())
+}
+
+func TestLogger_Trace(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"gorm": logging.DebugLevel}))
+	l := New(factory, "", Options{SlowThreshold: time.Second, IgnoreRecordNotFoundError: true})
+	ctx := logging.NewContext(context.Background(), logging.String("request_id", "1"))
+	query := func() (string, int64) { return "SELECT 1", 1 }
+	now := time.Now()
+
+	l.Trace(ctx, now, query, nil)
+	l.Trace(ctx, now.Add(-2*time.Second), func() (string, int64) { return "SELECT 2", -1 }, nil)
+	l.Trace(ctx, now, query, errors.New("failed"))
+	l.Trace(ctx, now, query, gorm.ErrRecordNotFound)
+	l.LogMode(gormlogger.Warn).Trace(ctx, now, query, nil)
+	l.LogMode(gormlogger.Silent).Trace(ctx, now, query, errors.New("failed"))
+	l.LogMode(gormlogger.Error).Trace(ctx, now.Add(-2*time.Second), query, nil)
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 4)
+	assert.Equal(t, "gorm", entries[0]["logger"])
+	assert.Equal(t, "DEBUG", entries[0]["level"])
+	assert.Equal(t, "sql", entries[0]["msg"])
+	assert.Equal(t, "SELECT 1", entries[0]["sql"])
+	assert.Equal(t, float64(1), entries[0]["rows"])
+	assert.Equal(t, "1", entries[0]["request_id"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "slow sql", entries[1]["msg"])
+	assert.NotContains(t, entries[1], "rows")
+	assert.Contains(t, entries[1], "slow_threshold")
+	assert.Equal(t, "ERROR", entries[2]["level"])
+	assert.Equal(t, "sql failed", entries[2]["msg"])
+	assert.Equal(t, "failed", entries[2]["error"])
+	// record not found is ignored as an error.
+	assert.Equal(t, "DEBUG", entries[3]["level"])
+}
+
+func TestLogger_messages(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	l := New(factory, "db", Options{})
+	ctx := context.Background()
+	l.Info(ctx, "a %d", 1)
+	l.Warn(ctx, "b")
+	l.Error(ctx, "c")
+	l.LogMode(gormlogger.Warn).Info(ctx, "d")
+	l.LogMode(gormlogger.Error).Warn(ctx, "e")
+	l.LogMode(gormlogger.Silent).Error(ctx, "f")
+	var got []string
+	for _, e := range rec.Entries() {
+		assert.Equal(t, "db", e["logger"])
+		got = append(got, e["level"].(string)+" "+e["msg"].(string))
+	}
+	assert.Equal(t, []string{"INFO a 1", "WARN b", "ERROR c"}, got)
+}
+
+func TestLogger_caller(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"gorm": logging.DebugLevel}))
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{Logger: New(factory, "", Options{}), DryRun: true})
+	assert.Nil(t, err)
+	var user tests.User
+	_, file, line, _ := runtime.Caller(0)
+	db.First(&user)
+	entries := rec.Entries()
+	assert.Len(t, entries, 1)
+	assert.Contains(t, entries[0]["sql"], "SELECT * FROM")
+	assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+1),
+		entries[0]["caller"])
+}
@@ -0,0 +1,41 @@
+package zap
+
+import (
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+// Namespace creates a field that nests all following fields of the log entry under the key.
+//
+// In json and console encodings the following fields are rendered as an object, in logfmt encoding their
+// keys are prefixed by the key and a dot.
+func Namespace(key string) logging.Field {
+	return field{key: key, typ: NamespaceType}
+}
+
+// Group creates a field that nests the fields under the key.
+func Group(key string, fields ...logging.Field) logging.Field {
+	return field{key: key, typ: GroupType, val: fields}
+}
+
+// WithGroup returns a child logger whose following fields are nested under the name.
+//
+// If the logger does not support groups natively, a Namespace field is used.
+func WithGroup(logger logging.Logger, name string) logging.Logger {
+	if g, ok := logger.(interface {
+		WithGroup(name string) logging.Logger
+	}); ok {
+		return g.WithGroup(name)
+	}
+	return logger.WithField(Namespace(name))
+}
+
+// fieldsMarshaler marshals logging fields as an object.
+type fieldsMarshaler []logging.Field
+
+func (fs fieldsMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range fs {
+		mapZapField(f).AddTo(enc)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package zap
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNamespace(t *testing.T) {
+	f := Namespace("http")
+	assert.Equal(t, "http", f.Key())
+	assert.Equal(t, NamespaceType, f.Type())
+	assert.Nil(t, f.Value())
+	assert.Equal(t, zap.Namespace("http"), mapZapField(f))
+}
+
+func TestGroup(t *testing.T) {
+	f := Group("request", logging.String("method", "GET"))
+	assert.Equal(t, "request", f.Key())
+	assert.Equal(t, GroupType, f.Type())
+	assert.Equal(t, []logging.Field{logging.String("method", "GET")}, f.Value())
+	assert.Equal(t, map[string]any{
+		"request": map[string]any{"method": "GET"},
+	}, encodeFields(mapZapField(f)))
+}
+
+type tNoGroupLogger struct {
+	logging.Logger
+	fields []logging.Field
+}
+
+func (l *tNoGroupLogger) WithField(field ...logging.Field) logging.Logger {
+	return &tNoGroupLogger{fields: append(l.fields, field...)}
+}
+
+func TestWithGroup(t *testing.T) {
+	factory := NewFactory(nil)
+	l := WithGroup(factory.Logger("foo"), "http").(*zapLogger)
+	assert.Equal(t, []logging.Field{Namespace("http")}, l.fields)
+
+	nl := WithGroup(&tNoGroupLogger{}, "http").(*tNoGroupLogger)
+	assert.Equal(t, []logging.Field{Namespace("http")}, nl.fields)
+}
+
+func groupLogLine(t *testing.T, encoding string) string {
+	origin := os.Stdout
+	defer func() {
+		os.Stdout = origin
+	}()
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	os.Stdout = w
+	factory := NewFactory(NewOptions(Encoding(encoding)))
+	l := WithGroup(factory.Logger("foo"), "http")
+	l.Infow("hello",
+		Group("request", logging.String("method", "GET")),
+		Group("response", logging.Int("status", 200)),
+	)
+	_ = w.Close()
+	scanner := bufio.NewScanner(r)
+	assert.True(t, scanner.Scan())
+	t.Log(scanner.Text())
+	return scanner.Text()
+}
+
+func TestGroup_render(t *testing.T) {
+	want := map[string]any{
+		"request":  map[string]any{"method": "GET"},
+		"response": map[string]any{"status": float64(200)},
+	}
+	t.Run("json", func(t *testing.T) {
+		m := map[string]any{}
+		assert.Nil(t, json.Unmarshal([]byte(groupLogLine(t, "json")), &m))
+		assert.Equal(t, want, m["http"])
+	})
+	t.Run("console", func(t *testing.T) {
+		line := groupLogLine(t, "console")
+		m := map[string]any{}
+		assert.Nil(t, json.Unmarshal([]byte(line[strings.Index(line, "{"):]), &m))
+		assert.Equal(t, want, m["http"])
+	})
+	t.Run("logfmt", func(t *testing.T) {
+		line := groupLogLine(t, "logfmt")
+		assert.Contains(t, line, " http.request.method=GET")
+		assert.Contains(t, line, " http.response.status=200")
+	})
+}
+
+func Test_zapLogger_WithGroup(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	l := &zapLogger{fields: nil, factory: factory, name: "foo"}
+	l.WithField(logging.String("a", "b")).(*zapLogger).WithGroup("http").Infow("hello", logging.String("method", "GET"))
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.Equal(t, "b", m["a"])
+	assert.Equal(t, map[string]any{"method": "GET"}, m["http"])
+}
+
+func Test_fieldsMarshaler(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	assert.Nil(t, fieldsMarshaler{logging.String("a", "b")}.MarshalLogObject(enc))
+	assert.Equal(t, map[string]any{"a": "b"}, enc.Fields)
+}
@@ -0,0 +1,194 @@
+// Package grpclogging logs gRPC calls and grpc-go's internal logs by loggers of a logging.Factory.
+package grpclogging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// InterceptorOptions configures interceptors.
+type InterceptorOptions struct {
+	// Logger is the name of the logger of call entries. "grpc.server" for server interceptors,
+	// and "grpc.client" for client interceptors as default.
+	Logger string `json:"logger,omitempty" yaml:"logger,omitempty"`
+	// Levels are the levels of call entries mapped by code names, e.g. "NotFound".
+	// InfoLevel for "OK", WarnLevel for codes of client errors, e.g. "InvalidArgument",
+	// and ErrorLevel for other codes as default. OffLevel to skip a code.
+	Levels map[string]logging.Level `json:"levels,omitempty" yaml:"levels,omitempty"`
+}
+
+// Defaulted returns a new InterceptorOptions filling blank items with default values,
+// with the logger name as default.
+func (o InterceptorOptions) Defaulted(logger string) InterceptorOptions {
+	o.Logger = strings.TrimSpace(o.Logger)
+	if o.Logger == "" {
+		o.Logger = logger
+	}
+	levels := make(map[string]logging.Level, len(o.Levels))
+	for code, level := range o.Levels {
+		levels[strings.TrimSpace(code)] = level
+	}
+	o.Levels = levels
+	return o
+}
+
+// level returns the level of entries of calls ended with the code.
+func (o InterceptorOptions) level(code codes.Code) logging.Level {
+	if level, ok := o.Levels[code.String()]; ok {
+		return level
+	}
+	switch code {
+	case codes.OK:
+		return logging.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange, codes.Unauthenticated:
+		return logging.WarnLevel
+	default:
+		return logging.ErrorLevel
+	}
+}
+
+// callLogger logs calls.
+type callLogger struct {
+	logger  logging.Logger
+	options InterceptorOptions
+}
+
+func newCallLogger(factory logging.Factory, options InterceptorOptions, logger string) *callLogger {
+	options = options.Defaulted(logger)
+	return &callLogger{logger: factory.Logger(options.Logger), options: options}
+}
+
+// log writes the entry of the call, whose peer is the address of the other side.
+func (l *callLogger) log(ctx context.Context, method, peerKey, peerAddr string, start time.Time, err error) {
+	code := status.Code(err)
+	level := l.options.level(code)
+	logger := logging.WithContextField(ctx, l.logger)
+	if !logger.Enabled(level) {
+		return
+	}
+	fields := []logging.Field{
+		logging.String("grpc.method", method),
+		logging.String("grpc.code", code.String()),
+		logging.Duration("duration", time.Since(start)),
+	}
+	if peerAddr != "" {
+		fields = append(fields, logging.String(peerKey, peerAddr))
+	}
+	if err != nil {
+		fields = append(fields, logging.Error(err))
+	}
+	zaplogging.Logw(logger, level, "grpc call", fields...)
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// UnaryServerInterceptor returns a server interceptor logging unary calls.
+func UnaryServerInterceptor(factory logging.Factory, options InterceptorOptions) grpc.UnaryServerInterceptor {
+	l := newCallLogger(factory, options, "grpc.server")
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		l.log(ctx, info.FullMethod, "peer.address", peerAddr(ctx), start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor logging streaming calls when they end.
+func StreamServerInterceptor(factory logging.Factory, options InterceptorOptions) grpc.StreamServerInterceptor {
+	l := newCallLogger(factory, options, "grpc.server")
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		ctx := ss.Context()
+		l.log(ctx, info.FullMethod, "peer.address", peerAddr(ctx), start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a client interceptor logging unary calls.
+func UnaryClientInterceptor(factory logging.Factory, options InterceptorOptions) grpc.UnaryClientInterceptor {
+	l := newCallLogger(factory, options, "grpc.client")
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		l.log(ctx, method, "grpc.target", cc.Target(), start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor logging streaming calls,
+// when they fail to start, when receiving from them ends, or when their context is done before that.
+// Calls not streaming from the server end once their only response is received.
+func StreamClientInterceptor(factory logging.Factory, options InterceptorOptions) grpc.StreamClientInterceptor {
+	l := newCallLogger(factory, options, "grpc.client")
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			l.log(ctx, method, "grpc.target", cc.Target(), start, err)
+			return nil, err
+		}
+		s := &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams, done: func(err error) {
+			l.log(ctx, method, "grpc.target", cc.Target(), start, err)
+		}}
+		go func() {
+			// The stream context is done when the stream ends, or when ctx is done, e.g. streams abandoned by
+			// canceling ctx instead of receiving till the end.
+			<-cs.Context().Done()
+			if err := ctx.Err(); err != nil {
+				s.end(status.FromContextError(err).Err())
+			}
+		}()
+		return s, nil
+	}
+}
+
+// clientStream calls done once the stream ends, with nil if the stream ends normally.
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	done          func(err error)
+	once          sync.Once
+}
+
+func (s *clientStream) end(err error) {
+	s.once.Do(func() { s.done(err) })
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		if !s.serverStreams {
+			s.end(nil)
+		}
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	default:
+		s.end(err)
+	}
+	return err
+}
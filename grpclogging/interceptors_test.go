@@ -0,0 +1,237 @@
+package grpclogging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestInterceptorOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, InterceptorOptions{
+		Logger: "grpc.server",
+		Levels: map[string]logging.Level{"NotFound": logging.InfoLevel},
+	}, InterceptorOptions{Levels: map[string]logging.Level{" NotFound ": logging.InfoLevel}}.Defaulted("grpc.server"))
+	assert.Equal(t, "foo", InterceptorOptions{Logger: " foo "}.Defaulted("grpc.server").Logger)
+}
+
+func TestInterceptorOptions_level(t *testing.T) {
+	o := InterceptorOptions{Levels: map[string]logging.Level{"NotFound": logging.DebugLevel}}
+	tests := []struct {
+		code codes.Code
+		want logging.Level
+	}{
+		{code: codes.OK, want: logging.InfoLevel},
+		{code: codes.InvalidArgument, want: logging.WarnLevel},
+		{code: codes.Unauthenticated, want: logging.WarnLevel},
+		{code: codes.NotFound, want: logging.DebugLevel},
+		{code: codes.Internal, want: logging.ErrorLevel},
+		{code: codes.Unknown, want: logging.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, o.level(tt.code))
+		})
+	}
+}
+
+// serve serves the services registered by register with the interceptors by an in-process listener,
+// returning a client connection to it.
+func serve(t *testing.T, factory logging.Factory, register func(srv *grpc.Server)) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(factory, InterceptorOptions{})),
+		grpc.StreamInterceptor(StreamServerInterceptor(factory, InterceptorOptions{})),
+	)
+	register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(factory, InterceptorOptions{})),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(factory, InterceptorOptions{})),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// dial serves the health service with the interceptors by an in-process listener, returning a client of it.
+func dial(t *testing.T, factory logging.Factory, hs *health.Server) healthpb.HealthClient {
+	return healthpb.NewHealthClient(serve(t, factory, func(srv *grpc.Server) { healthpb.RegisterHealthServer(srv, hs) }))
+}
+
+// calls returns the call entries of the logger.
+func calls(rec *logtest.Recorder, logger string) []map[string]any {
+	var res []map[string]any
+	for _, e := range rec.Entries() {
+		if e["logger"] == logger && e["msg"] == "grpc call" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	client := dial(t, factory, health.NewServer())
+	ctx := context.Background()
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "none"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	server := calls(rec, "grpc.server")
+	assert.Len(t, server, 2)
+	assert.Equal(t, "INFO", server[0]["level"])
+	assert.Equal(t, "/grpc.health.v1.Health/Check", server[0]["grpc.method"])
+	assert.Equal(t, "OK", server[0]["grpc.code"])
+	assert.Equal(t, "bufconn", server[0]["peer.address"])
+	assert.Contains(t, server[0], "duration")
+	assert.Equal(t, "WARN", server[1]["level"])
+	assert.Equal(t, "NotFound", server[1]["grpc.code"])
+	assert.Contains(t, server[1]["error"], "unknown service")
+
+	client2 := calls(rec, "grpc.client")
+	assert.Len(t, client2, 2)
+	assert.Equal(t, "bufnet", client2[0]["grpc.target"])
+	assert.Equal(t, "NotFound", client2[1]["grpc.code"])
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	hs := health.NewServer()
+	client := dial(t, factory, hs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	resp, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	hs.Shutdown()
+	resp, err = stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	_, err = stream.Recv()
+	assert.NotNil(t, err)
+
+	assert.Eventually(t, func() bool { return len(calls(rec, "grpc.server")) == 1 }, time.Second, time.Millisecond)
+	client2 := calls(rec, "grpc.client")
+	assert.Len(t, client2, 1)
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", client2[0]["grpc.method"])
+	assert.Equal(t, "Canceled", client2[0]["grpc.code"])
+	assert.Equal(t, "WARN", client2[0]["level"])
+}
+
+// sumDesc describes a client-streaming method, receiving requests till the client closes sending.
+var sumDesc = grpc.ServiceDesc{
+	ServiceName: "test.Sum",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Sum",
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			for {
+				err := stream.RecvMsg(&healthpb.HealthCheckRequest{})
+				if errors.Is(err, io.EOF) {
+					return stream.SendMsg(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+				}
+				if err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+func TestStreamInterceptors_clientStreaming(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	conn := serve(t, factory, func(srv *grpc.Server) { srv.RegisterService(&sumDesc, struct{}{}) })
+	desc := &grpc.StreamDesc{ClientStreams: true}
+
+	stream, err := conn.NewStream(context.Background(), desc, "/test.Sum/Sum")
+	assert.Nil(t, err)
+	assert.Nil(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	assert.Nil(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	assert.Nil(t, stream.CloseSend())
+	resp := &healthpb.HealthCheckResponse{}
+	assert.Nil(t, stream.RecvMsg(resp))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	client := calls(rec, "grpc.client")
+	assert.Len(t, client, 1)
+	assert.Equal(t, "/test.Sum/Sum", client[0]["grpc.method"])
+	assert.Equal(t, "OK", client[0]["grpc.code"])
+
+	// Abandoned by canceling the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = conn.NewStream(ctx, desc, "/test.Sum/Sum")
+	assert.Nil(t, err)
+	cancel()
+	assert.Eventually(t, func() bool { return len(calls(rec, "grpc.client")) == 2 }, time.Second, time.Millisecond)
+	client = calls(rec, "grpc.client")
+	assert.Equal(t, "Canceled", client[1]["grpc.code"])
+}
+
+func TestClientStream(t *testing.T) {
+	var ended []error
+	s := &clientStream{
+		ClientStream: tClientStream{err: io.EOF}, serverStreams: true, done: func(err error) { ended = append(ended, err) },
+	}
+	assert.Equal(t, io.EOF, s.RecvMsg(nil))
+	assert.Equal(t, io.EOF, s.RecvMsg(nil))
+	assert.Equal(t, []error{nil}, ended)
+
+	failed := errors.New("failed")
+	s = &clientStream{
+		ClientStream: tClientStream{err: failed}, serverStreams: true, done: func(err error) { ended = append(ended, err) },
+	}
+	assert.Equal(t, failed, s.RecvMsg(nil))
+	assert.Equal(t, []error{nil, failed}, ended)
+
+	s = &clientStream{ClientStream: tClientStream{}, serverStreams: true, done: func(err error) { ended = append(ended, err) }}
+	assert.Nil(t, s.RecvMsg(nil))
+	assert.Equal(t, []error{nil, failed}, ended)
+
+	s = &clientStream{ClientStream: tClientStream{}, done: func(err error) { ended = append(ended, err) }}
+	assert.Nil(t, s.RecvMsg(nil))
+	assert.Nil(t, s.RecvMsg(nil))
+	assert.Equal(t, []error{nil, failed, nil}, ended)
+}
+
+type tClientStream struct {
+	grpc.ClientStream
+	err error
+}
+
+func (s tClientStream) RecvMsg(any) error { return s.err }
+
+func TestInterceptors_levels(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"grpc": logging.WarnLevel}))
+	client := dial(t, factory, health.NewServer())
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Empty(t, calls(rec, "grpc.server"))
+	assert.Empty(t, calls(rec, "grpc.client"))
+}
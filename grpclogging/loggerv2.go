@@ -0,0 +1,77 @@
+package grpclogging
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"google.golang.org/grpc/grpclog"
+)
+
+// exit is called by Fatal methods, replaced in tests.
+var exit = os.Exit
+
+// loggerV2 is a grpclog.LoggerV2 writing to a logger of a factory.
+type loggerV2 struct {
+	logger logging.Logger
+}
+
+// NewLoggerV2 creates a grpclog.LoggerV2 writing to the logger of the name, "grpc" if empty,
+// so that grpc-go's internal logs respect the levels of the factory, e.g.
+//
+//	grpclog.SetLoggerV2(grpclogging.NewLoggerV2(factory, ""))
+//
+// Verbose logs, which grpc-go guards by V, are enabled when DebugLevel is enabled.
+// Callers of entries are in grpc-go, which can be adjusted by Options.AddCallerSkipAdjusts of the name.
+// Fatal methods sync the outputs before exiting.
+func NewLoggerV2(factory logging.Factory, name string) grpclog.LoggerV2 {
+	if name == "" {
+		name = "grpc"
+	}
+	return &loggerV2{logger: zaplogging.AddCallerSkip(factory.Logger(name), 1)}
+}
+
+func sprintln(v ...any) string {
+	s := fmt.Sprintln(v...)
+	return s[:len(s)-1]
+}
+
+func (l *loggerV2) Info(args ...any)                 { l.logger.Info(args...) }
+func (l *loggerV2) Infoln(args ...any)               { l.logger.Infoln(args...) }
+func (l *loggerV2) Infof(format string, args ...any) { l.logger.Infof(format, args...) }
+
+func (l *loggerV2) Warning(args ...any)                 { l.logger.Warn(args...) }
+func (l *loggerV2) Warningln(args ...any)               { l.logger.Warnln(args...) }
+func (l *loggerV2) Warningf(format string, args ...any) { l.logger.Warnf(format, args...) }
+
+func (l *loggerV2) Error(args ...any)                 { l.logger.Error(args...) }
+func (l *loggerV2) Errorln(args ...any)               { l.logger.Errorln(args...) }
+func (l *loggerV2) Errorf(format string, args ...any) { l.logger.Errorf(format, args...) }
+
+// Fatal logs at ErrorLevel and exits, as grpclog does.
+func (l *loggerV2) Fatal(args ...any) {
+	l.logger.Errorw(fmt.Sprint(args...), logging.Bool("fatal", true))
+	l.exit()
+}
+
+func (l *loggerV2) Fatalln(args ...any) {
+	l.logger.Errorw(sprintln(args...), logging.Bool("fatal", true))
+	l.exit()
+}
+
+func (l *loggerV2) Fatalf(format string, args ...any) {
+	l.logger.Errorw(fmt.Sprintf(format, args...), logging.Bool("fatal", true))
+	l.exit()
+}
+
+// exit syncs the outputs before exiting, so that the fatal entry is not lost.
+func (l *loggerV2) exit() {
+	_ = zaplogging.Sync(l.logger)
+	exit(1)
+}
+
+// V reports whether verbosity level l is enabled, all of which are enabled when DebugLevel is enabled.
+func (l *loggerV2) V(level int) bool {
+	return level <= 0 || l.logger.Enabled(logging.DebugLevel)
+}
@@ -0,0 +1,51 @@
+package grpclogging
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+)
+
+func TestLoggerV2(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"grpc": logging.WarnLevel}))
+	var exited []int
+	exit = func(code int) { exited = append(exited, code) }
+	defer func() { exit = os.Exit }()
+
+	l := NewLoggerV2(factory, "")
+	l.Info("a")
+	l.Infoln("a")
+	l.Infof("%s", "a")
+	l.Warning("b", 1)
+	l.Warningln("b", 1)
+	l.Warningf("b %d", 1)
+	l.Error("c")
+	l.Errorln("c")
+	l.Errorf("c")
+	l.Fatal("d")
+	l.Fatalln("d", 1)
+	l.Fatalf("d %d", 1)
+	assert.Equal(t, []int{1, 1, 1}, exited)
+	assert.False(t, l.V(2))
+	assert.True(t, l.V(0))
+
+	var got []string
+	for _, e := range rec.Entries() {
+		assert.Equal(t, "grpc", e["logger"])
+		assert.Contains(t, e["caller"], "grpclogging/loggerv2_test.go:")
+		got = append(got, e["level"].(string)+" "+e["msg"].(string))
+	}
+	assert.Equal(t, []string{
+		"WARN b1", "WARN b 1", "WARN b 1",
+		"ERROR c", "ERROR c", "ERROR c",
+		"ERROR d", "ERROR d 1", "ERROR d 1",
+	}, got)
+	assert.Equal(t, true, rec.Entries()[6]["fatal"])
+
+	debug, _ := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"": logging.DebugLevel}))
+	assert.True(t, NewLoggerV2(debug, "grpc").V(2))
+}
@@ -0,0 +1,177 @@
+// Package hcloglogging adapts a logging.Factory to hashicorp/go-hclog.
+package hcloglogging
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/keyvals"
+	"go.uber.org/atomic"
+)
+
+// logger is an hclog.Logger writing to loggers of a factory.
+type logger struct {
+	factory logging.Factory
+	name    string
+	args    []any
+	// level is the hclog.Level set by SetLevel, shared by loggers derived by With and Named.
+	level *atomic.Int32
+	// logger is the logger for methods of hclog.Logger, writer the one for StandardWriter.
+	logger logging.Logger
+	writer logging.Logger
+}
+
+// New creates an hclog.Logger writing to the logger of the name of the factory.
+//
+// Names added by Named are joined to the name by dots, so that prefixes of Options.Levels apply to them.
+// Entries are written if enabled by both the factory and SetLevel, Trace entries are written at DebugLevel.
+func New(factory logging.Factory, name string) hclog.Logger {
+	return newLogger(factory, name, nil, atomic.NewInt32(int32(hclog.NoLevel)))
+}
+
+func newLogger(factory logging.Factory, name string, args []any, level *atomic.Int32) *logger {
+	l := factory.Logger(name)
+	if len(args) != 0 {
+		l = l.WithField(keyvals.Fields(args)...)
+	}
+	return &logger{
+		factory: factory,
+		name:    name,
+		args:    args,
+		level:   level,
+		// skips the method of hclog.Logger and log.
+		logger: zaplogging.AddCallerSkip(l, 2),
+		// skips log.Logger.Print methods, log.Logger.output, stdWriter.Write and log.
+		writer: zaplogging.AddCallerSkip(l, 4),
+	}
+}
+
+// mapLevel maps the hclog.Level to a logging.Level.
+func mapLevel(level hclog.Level) logging.Level {
+	switch level {
+	case hclog.Trace, hclog.Debug:
+		return logging.DebugLevel
+	case hclog.NoLevel, hclog.Info:
+		return logging.InfoLevel
+	case hclog.Warn:
+		return logging.WarnLevel
+	case hclog.Error:
+		return logging.ErrorLevel
+	default:
+		return logging.OffLevel
+	}
+}
+
+func (l *logger) enabled(level hclog.Level) bool {
+	if min := hclog.Level(l.level.Load()); min != hclog.NoLevel && level < min {
+		return false
+	}
+	return l.logger.Enabled(mapLevel(level))
+}
+
+func (l *logger) log(logger logging.Logger, level hclog.Level, msg string, args []any) {
+	if !l.enabled(level) {
+		return
+	}
+	fields := keyvals.Fields(args)
+	zaplogging.Logw(logger, mapLevel(level), msg, fields...)
+}
+
+func (l *logger) Log(level hclog.Level, msg string, args ...any) { l.log(l.logger, level, msg, args) }
+func (l *logger) Trace(msg string, args ...any)                  { l.log(l.logger, hclog.Trace, msg, args) }
+func (l *logger) Debug(msg string, args ...any)                  { l.log(l.logger, hclog.Debug, msg, args) }
+func (l *logger) Info(msg string, args ...any)                   { l.log(l.logger, hclog.Info, msg, args) }
+func (l *logger) Warn(msg string, args ...any)                   { l.log(l.logger, hclog.Warn, msg, args) }
+func (l *logger) Error(msg string, args ...any)                  { l.log(l.logger, hclog.Error, msg, args) }
+
+func (l *logger) IsTrace() bool { return l.enabled(hclog.Trace) }
+func (l *logger) IsDebug() bool { return l.enabled(hclog.Debug) }
+func (l *logger) IsInfo() bool  { return l.enabled(hclog.Info) }
+func (l *logger) IsWarn() bool  { return l.enabled(hclog.Warn) }
+func (l *logger) IsError() bool { return l.enabled(hclog.Error) }
+
+func (l *logger) ImpliedArgs() []any { return l.args }
+
+func (l *logger) With(args ...any) hclog.Logger {
+	return newLogger(l.factory, l.name, append(l.args[:len(l.args):len(l.args)], args...), l.level)
+}
+
+func (l *logger) Name() string { return l.name }
+
+func (l *logger) Named(name string) hclog.Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return newLogger(l.factory, name, l.args, l.level)
+}
+
+func (l *logger) ResetNamed(name string) hclog.Logger {
+	return newLogger(l.factory, name, l.args, l.level)
+}
+
+// SetLevel sets the minimum level of the logger and loggers derived from it, besides the levels of the factory.
+// hclog.NoLevel to use the levels of the factory only.
+func (l *logger) SetLevel(level hclog.Level) {
+	l.level.Store(int32(level))
+}
+
+func (l *logger) StandardLogger(opts *hclog.StandardLoggerOptions) *log.Logger {
+	return log.New(l.StandardWriter(opts), "", 0)
+}
+
+func (l *logger) StandardWriter(opts *hclog.StandardLoggerOptions) io.Writer {
+	if opts == nil {
+		opts = &hclog.StandardLoggerOptions{}
+	}
+	return &stdWriter{logger: l, opts: *opts}
+}
+
+// stdWriter writes lines of standard loggers as entries.
+type stdWriter struct {
+	logger *logger
+	opts   hclog.StandardLoggerOptions
+}
+
+var levelPrefixes = []struct {
+	prefix string
+	level  hclog.Level
+}{
+	{prefix: "[TRACE]", level: hclog.Trace},
+	{prefix: "[DEBUG]", level: hclog.Debug},
+	{prefix: "[INFO]", level: hclog.Info},
+	{prefix: "[WARN]", level: hclog.Warn},
+	{prefix: "[ERROR]", level: hclog.Error},
+	{prefix: "[ERR]", level: hclog.Error},
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimRight(p, " \t\n"))
+	level := hclog.Info
+	if w.opts.ForceLevel != hclog.NoLevel || w.opts.InferLevels || w.opts.InferLevelsWithTimestamp {
+		if inferred, rest, ok := inferLevel(msg, w.opts.InferLevelsWithTimestamp); ok {
+			level, msg = inferred, rest
+		}
+		if w.opts.ForceLevel != hclog.NoLevel {
+			level = w.opts.ForceLevel
+		}
+	}
+	w.logger.log(w.logger.writer, level, msg, nil)
+	return len(p), nil
+}
+
+// inferLevel returns the level of the level prefix of the message, and the message without it.
+// The prefix may follow a timestamp if withTimestamp is true.
+func inferLevel(msg string, withTimestamp bool) (hclog.Level, string, bool) {
+	for _, p := range levelPrefixes {
+		i := strings.Index(msg, p.prefix)
+		if i == 0 || withTimestamp && i > 0 {
+			return p.level, strings.TrimSpace(msg[:i] + msg[i+len(p.prefix):]), true
+		}
+	}
+	return hclog.NoLevel, msg, false
+}
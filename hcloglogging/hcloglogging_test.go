@@ -0,0 +1,128 @@
+package hcloglogging
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+)
+
+func TestMapLevel(t *testing.T) {
+	tests := []struct {
+		level hclog.Level
+		want  logging.Level
+	}{
+		{level: hclog.NoLevel, want: logging.InfoLevel},
+		{level: hclog.Trace, want: logging.DebugLevel},
+		{level: hclog.Debug, want: logging.DebugLevel},
+		{level: hclog.Info, want: logging.InfoLevel},
+		{level: hclog.Warn, want: logging.WarnLevel},
+		{level: hclog.Error, want: logging.ErrorLevel},
+		{level: hclog.Off, want: logging.OffLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, mapLevel(tt.level))
+		})
+	}
+}
+
+func TestInferLevel(t *testing.T) {
+	tests := []struct {
+		msg           string
+		withTimestamp bool
+		level         hclog.Level
+		rest          string
+		ok            bool
+	}{
+		{msg: "[WARN] a", level: hclog.Warn, rest: "a", ok: true},
+		{msg: "[ERR] a", level: hclog.Error, rest: "a", ok: true},
+		{msg: "a", level: hclog.NoLevel, rest: "a", ok: false},
+		{msg: "2022/07/01 [DEBUG] a", level: hclog.NoLevel, rest: "2022/07/01 [DEBUG] a", ok: false},
+		{msg: "2022/07/01 [DEBUG] a", withTimestamp: true, level: hclog.Debug, rest: "2022/07/01  a", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			level, rest, ok := inferLevel(tt.msg, tt.withTimestamp)
+			assert.Equal(t, tt.level, level)
+			assert.Equal(t, tt.rest, rest)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{
+		"vault.sub": logging.DebugLevel,
+	}))
+	l := New(factory, "vault")
+	assert.Equal(t, "vault", l.Name())
+	assert.False(t, l.IsDebug())
+	assert.True(t, l.IsInfo())
+
+	_, file, line, _ := runtime.Caller(0)
+	l.Info("a", "n", 1)
+	l.Debug("b")
+	sub := l.With("k", "v").Named("sub")
+	assert.Equal(t, "vault.sub", sub.Name())
+	assert.Equal(t, []any{"k", "v"}, sub.ImpliedArgs())
+	assert.True(t, sub.IsTrace())
+	sub.Trace("c")
+	sub.Log(hclog.Warn, "d")
+	sub.Error("e", "err", errors.New("failed"))
+	sub.ResetNamed("other").Info("f")
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "vault", entries[0]["logger"])
+	assert.Equal(t, float64(1), entries[0]["n"])
+	assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+1),
+		entries[0]["caller"])
+	assert.Equal(t, "vault.sub", entries[1]["logger"])
+	assert.Equal(t, "DEBUG", entries[1]["level"])
+	assert.Equal(t, "v", entries[1]["k"])
+	assert.Equal(t, "WARN", entries[2]["level"])
+	assert.Equal(t, "failed", entries[3]["err"])
+	assert.Equal(t, "other", entries[4]["logger"])
+	assert.Equal(t, "v", entries[4]["k"])
+
+	// SetLevel is shared by derived loggers.
+	sub.SetLevel(hclog.Warn)
+	assert.False(t, l.IsInfo())
+	assert.True(t, l.IsWarn())
+	assert.True(t, sub.IsError())
+	l.Info("g")
+	assert.Len(t, rec.Entries(), 5)
+	l.SetLevel(hclog.NoLevel)
+	assert.True(t, l.IsInfo())
+	l.SetLevel(hclog.Off)
+	l.Error("h")
+	assert.Len(t, rec.Entries(), 5)
+}
+
+func TestLogger_StandardLogger(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	l := New(factory, "std")
+	_, file, line, _ := runtime.Caller(0)
+	l.StandardLogger(nil).Println("[WARN] a")
+	l.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true}).Printf("[WARN] b")
+	l.StandardLogger(&hclog.StandardLoggerOptions{ForceLevel: hclog.Error}).Print("[WARN] c")
+	_, _ = l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Error}).Write([]byte("d\n"))
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 4)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e["level"].(string)+" "+e["msg"].(string))
+	}
+	assert.Equal(t, []string{"INFO [WARN] a", "WARN b", "ERROR c", "ERROR d"}, got)
+	assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+1),
+		entries[0]["caller"])
+}
@@ -0,0 +1,92 @@
+package zap
+
+import (
+	"runtime"
+	"sync"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+var (
+	// helpers are the names of the functions marked by Helper.
+	helpers sync.Map
+	// hasHelpers reports whether any function is marked, so that stacks are not walked otherwise.
+	hasHelpers atomic.Bool
+)
+
+// Helper marks the calling function as a logging helper, like testing.T.Helper.
+// When resolving callers of entries, the frames of helpers are skipped,
+// so that the caller is the one calling the helper, whichever logger the helper is called with.
+//
+// Helpers are looked for from the frame the caller would be resolved to, that is,
+// right after the frames skipped by AddCallerSkip and the Options. Wrappers of loggers must declare
+// their frames by AddCallerSkip, otherwise neither callers nor helpers are resolved correctly.
+//
+// Helper may be called multiple times, and by nested helpers.
+// Once any function is marked, each logging call with caller enabled looks up the frame of its caller.
+func Helper() {
+	var pcs [1]uintptr
+	if runtime.Callers(2, pcs[:]) == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	if _, ok := helpers.Load(frame.Function); !ok {
+		helpers.Store(frame.Function, struct{}{})
+		hasHelpers.Store(true)
+	}
+}
+
+// helperFrames returns the number of consecutive frames of helpers,
+// starting from the caller of helperFrames after skipping skip frames.
+// Stacks are walked a few frames at a time, as callers are rarely helpers.
+func helperFrames(skip int) int {
+	var pcs [8]uintptr
+	count := 0
+	for {
+		n := runtime.Callers(skip+2+count, pcs[:])
+		frames := runtime.CallersFrames(pcs[:n])
+		for {
+			frame, more := frames.Next()
+			if _, ok := helpers.Load(frame.Function); !ok {
+				return count
+			}
+			count++
+			if !more {
+				break
+			}
+		}
+		if n < len(pcs) {
+			return count
+		}
+	}
+}
+
+// zapCall returns the zap logger of a logging call, skipping the frames of helpers.
+// It must be called by the logging methods directly, which must be called by the callers
+// or the wrappers declared by AddCallerSkip directly.
+func (z *zapLogger) zapCall() *zap.Logger {
+	logger := z.zap()
+	if !hasHelpers.Load() {
+		return logger
+	}
+	options := z.factory.options.Load().(*Options)
+	if options.DisableCaller {
+		return logger
+	}
+	// Skip the logging method, then the frames skipped by the factory and the logger.
+	skip := 2 + options.GlobalAddCallerSkipAdjust + options.AddCallerSkipAdjusts[z.name] + z.callerSkip
+	n := helperFrames(skip)
+	if n == 0 {
+		return logger
+	}
+	cache, ok := z.cache.Load().(*fieldCache)
+	if !ok || cache.logger != logger {
+		return logger.WithOptions(zap.AddCallerSkip(n))
+	}
+	if l, ok := cache.helpers.Load(n); ok {
+		return l.(*zap.Logger)
+	}
+	l, _ := cache.helpers.LoadOrStore(n, logger.WithOptions(zap.AddCallerSkip(n)))
+	return l.(*zap.Logger)
+}
@@ -0,0 +1,153 @@
+package zap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+)
+
+func logErr(l logging.Logger, err error) {
+	Helper()
+	l.Errorw("failed", logging.Error(err))
+}
+
+func logErrNested(l logging.Logger, err error) {
+	Helper()
+	logErr(l, err)
+}
+
+func logErrSkipped(l logging.Logger, err error) {
+	AddCallerSkip(l, 1).Errorw("failed", logging.Error(err))
+}
+
+func logErrWrapped(l logging.Logger, err error) {
+	Helper()
+	logErrSkipped(l, err)
+}
+
+// logErrDeep logs through the helper calling itself depth times, deeper than the frames walked at a time.
+func logErrDeep(l logging.Logger, err error, depth int) {
+	Helper()
+	if depth == 0 {
+		l.Errorw("failed", logging.Error(err))
+		return
+	}
+	logErrDeep(l, err, depth-1)
+}
+
+func logErrUnmarked(l logging.Logger, err error) {
+	logErr(l, err)
+}
+
+func TestHelper(t *testing.T) {
+	err := errors.New("oops")
+	// each log function returns the line of its call.
+	tests := []struct {
+		name     string
+		log      func(l logging.Logger) int
+		function string
+	}{
+		{
+			name:     "helper",
+			log:      func(l logging.Logger) int { logErr(l, err); _, _, line, _ := runtime.Caller(0); return line },
+			function: "TestHelper.func1",
+		},
+		{
+			name:     "nested",
+			log:      func(l logging.Logger) int { logErrNested(l, err); _, _, line, _ := runtime.Caller(0); return line },
+			function: "TestHelper.func2",
+		},
+		{
+			name: "child logger",
+			log: func(l logging.Logger) int {
+				logErr(l.WithField(logging.String("a", "b")), err)
+				_, _, line, _ := runtime.Caller(0)
+				return line - 1
+			},
+			function: "TestHelper.func3",
+		},
+		{
+			name: "caller skip",
+			log: func(l logging.Logger) int {
+				logErrWrapped(l, err)
+				_, _, line, _ := runtime.Caller(0)
+				return line - 1
+			},
+			function: "TestHelper.func4",
+		},
+		{
+			name:     "deep",
+			log:      func(l logging.Logger) int { logErrDeep(l, err, 20); _, _, line, _ := runtime.Caller(0); return line },
+			function: "TestHelper.func5",
+		},
+		{
+			name:     "unmarked",
+			log:      func(l logging.Logger) int { logErrUnmarked(l, err); return 0 },
+			function: "logErrUnmarked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel, FunctionFieldKey("func"))
+			defer func() {
+				_ = readCloser.Close()
+			}()
+			line := tt.log(factory.Logger("foo"))
+			_ = writeCloser.Close()
+			scanner := bufio.NewScanner(readCloser)
+			assert.True(t, scanner.Scan())
+			m := map[string]any{}
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+			assert.Equal(t, "oops", m["error"])
+			if line != 0 {
+				assert.True(t, strings.HasSuffix(m["caller"].(string), fmt.Sprintf("/helper_test.go:%d", line)), m["caller"])
+			}
+			assert.Equal(t, "github.com/yimi-go/zap-logging."+tt.function, m["func"])
+		})
+	}
+}
+
+func TestHelper_disableCaller(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel, DisableCaller(true))
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	logErr(factory.Logger("foo"), errors.New("oops"))
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.NotContains(t, m, "caller")
+}
+
+func TestHelper_cache(t *testing.T) {
+	factory := NewFactory(NewOptions())
+	l := factory.Logger("foo").(*zapLogger)
+	// call stands for the logging methods calling zapCall.
+	call := func() *zap.Logger { return l.zapCall() }
+	get := func() *zap.Logger {
+		Helper()
+		return call()
+	}
+	first := get()
+	assert.NotSame(t, l.zap(), first)
+	assert.Same(t, first, get())
+}
+
+func TestHelperFrames(t *testing.T) {
+	assert.Equal(t, 0, helperFrames(0))
+	func() {
+		Helper()
+		assert.Equal(t, 1, helperFrames(0))
+		assert.Equal(t, 0, helperFrames(1))
+	}()
+}
@@ -0,0 +1,193 @@
+package zap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a log entry passed to hooks.
+type Entry struct {
+	zapcore.Entry
+	// Fields are the fields of the logger and of the entry.
+	Fields []zapcore.Field
+}
+
+// Hook is called with entries written by loggers, e.g. to forward error entries to an incident tool.
+type Hook struct {
+	// Func is called with the entries passing the filters, including the ones dropped by sampling and RateLimit.
+	// Entries buffered by ring buffers below the levels of loggers are passed when flushed.
+	// Errors are reported to the error outputs, as are panics, which are recovered.
+	Func func(Entry) error
+	// Level is the minimum level of the entries. InfoLevel as zero value.
+	Level logging.Level
+	// Names are the logger names of the entries, which include their descendants, e.g. "foo" includes "foo.bar".
+	// All loggers if empty.
+	Names []string
+}
+
+// matches reports whether the entry of the logger name passes the filters of the hook.
+// Names are passed along with entries, which are not named with DisableLogger.
+func (h Hook) matches(name string, ent zapcore.Entry) bool {
+	level := logging.Level(ent.Level)
+	if ent.Level > zapcore.ErrorLevel {
+		level = logging.ErrorLevel
+	}
+	if level < h.Level {
+		return false
+	}
+	if len(h.Names) == 0 {
+		return true
+	}
+	for _, n := range h.Names {
+		if n == "" || name == n || strings.HasPrefix(name, n) && strings.ContainsRune("./:", rune(name[len(n)])) {
+			return true
+		}
+	}
+	return false
+}
+
+// call calls the hook, recovering panics as errors.
+func (h Hook) call(e Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hook panicked: %v", r)
+		}
+	}()
+	return h.Func(e)
+}
+
+var (
+	hookRegistryMu sync.RWMutex
+	hookRegistry   = map[string]Hook{}
+)
+
+// RegisterHook registers the hook by the name, which is enabled by Options.Hooks.
+func RegisterHook(name string, hook Hook) {
+	hookRegistryMu.Lock()
+	defer hookRegistryMu.Unlock()
+	hookRegistry[name] = hook
+}
+
+// hooks returns the hooks registered by the names in Options.Hooks.
+func (o *Options) hooks() ([]Hook, error) {
+	if len(o.Hooks) == 0 {
+		return nil, nil
+	}
+	hookRegistryMu.RLock()
+	defer hookRegistryMu.RUnlock()
+	hooks := make([]Hook, 0, len(o.Hooks))
+	for _, name := range o.Hooks {
+		hook, ok := hookRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown hook %q", name)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// hookList is the hooks added to a factory, which are kept across options.
+type hookList struct {
+	mu    sync.Mutex
+	hooks atomic.Value
+}
+
+func (l *hookList) add(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hooks := l.load()
+	l.hooks.Store(append(hooks[:len(hooks):len(hooks)], hook))
+}
+
+// load returns the hooks, nil if the list is nil.
+func (l *hookList) load() []Hook {
+	if l == nil {
+		return nil
+	}
+	hooks, _ := l.hooks.Load().([]Hook)
+	return hooks
+}
+
+// AddHook adds the hook to the factory created by NewFactory, which is kept when options switch.
+func AddHook(factory logging.Factory, hook Hook) error {
+	zf, ok := factory.(*zapFactory)
+	if !ok {
+		return errors.New("not a factory created by NewFactory")
+	}
+	zf.hooks.add(hook)
+	return nil
+}
+
+// hookCore calls hooks with entries checked by it, including the ones dropped by the cores it wraps,
+// e.g. by sampling and RateLimit.
+type hookCore struct {
+	zapcore.Core
+	// hooks are the hooks of the options, factory the hooks of the factory.
+	hooks   []Hook
+	factory *hookList
+	fields  []zapcore.Field
+	// name is the logger name, which Names of hooks match.
+	name string
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+// Check checks the entry by the wrapped core, and adds the hookCore to call the hooks matching the entry.
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	if c.matches(ent) {
+		ce = ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) matches(ent zapcore.Entry) bool {
+	for _, hooks := range [][]Hook{c.hooks, c.factory.load()} {
+		for _, h := range hooks {
+			if h.matches(c.name, ent) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Write calls the hooks, returning their errors. Entries are written by the wrapped core on its own.
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var err error
+	var e *Entry
+	for _, hooks := range [][]Hook{c.hooks, c.factory.load()} {
+		for _, h := range hooks {
+			if !h.matches(c.name, ent) {
+				continue
+			}
+			if e == nil {
+				e = &Entry{Entry: ent, Fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+			}
+			err = multierr.Append(err, h.call(*e))
+		}
+	}
+	return err
+}
+
+// writeThrough writes the entry by the wrapped core, and calls the hooks matching it,
+// e.g. for entries buffered by ringCore, which are written without checked again.
+func (c *hookCore) writeThrough(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	if c.matches(ent) {
+		err = multierr.Append(err, c.Write(ent, fields))
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package zap
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestHook_matches(t *testing.T) {
+	tests := []struct {
+		name   string
+		hook   Hook
+		logger string
+		ent    zapcore.Entry
+		want   bool
+	}{
+		{name: "zero", hook: Hook{}, ent: zapcore.Entry{Level: zapcore.InfoLevel}, want: true},
+		{name: "below_level", hook: Hook{}, ent: zapcore.Entry{Level: zapcore.DebugLevel}, want: false},
+		{name: "debug", hook: Hook{Level: logging.DebugLevel}, ent: zapcore.Entry{Level: zapcore.DebugLevel}, want: true},
+		{name: "panic", hook: Hook{Level: logging.ErrorLevel}, ent: zapcore.Entry{Level: zapcore.PanicLevel}, want: true},
+		{name: "off", hook: Hook{Level: logging.OffLevel}, ent: zapcore.Entry{Level: zapcore.FatalLevel}, want: false},
+		{name: "name", hook: Hook{Names: []string{"foo"}}, logger: "foo", want: true},
+		{name: "descendant", hook: Hook{Names: []string{"foo"}}, logger: "foo.bar", want: true},
+		{name: "other", hook: Hook{Names: []string{"foo"}}, logger: "foobar", want: false},
+		{name: "root", hook: Hook{Names: []string{"bar", ""}}, logger: "foo", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.hook.matches(tt.logger, tt.ent))
+		})
+	}
+}
+
+func TestHook_call(t *testing.T) {
+	err := Hook{Func: func(Entry) error { panic("oops") }}.call(Entry{})
+	assert.EqualError(t, err, "hook panicked: oops")
+	err = Hook{Func: func(Entry) error { return errors.New("failed") }}.call(Entry{})
+	assert.EqualError(t, err, "failed")
+}
+
+func TestOptions_hooks(t *testing.T) {
+	RegisterHook("test-hooks", Hook{})
+	hooks, err := (&Options{Hooks: []string{"test-hooks"}}).hooks()
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	_, err = (&Options{Hooks: []string{"none"}}).hooks()
+	assert.EqualError(t, err, `unknown hook "none"`)
+	_, err = NewOptions(Hooks("none")).openOutputs()
+	assert.EqualError(t, err, `unknown hook "none"`)
+}
+
+func TestHookCore(t *testing.T) {
+	var entries []Entry
+	record := func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	}
+	factory := &hookList{}
+	factory.add(Hook{Func: func(Entry) error { panic("oops") }, Level: logging.ErrorLevel})
+	core := (&hookCore{
+		Core:    zapcore.NewNopCore(),
+		hooks:   []Hook{{Func: record}},
+		factory: factory,
+	}).With([]zapcore.Field{zap.String("a", "b")})
+
+	err := core.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "m"}, []zapcore.Field{zap.Int("n", 1)})
+	assert.Nil(t, err)
+	assert.Equal(t, []Entry{{
+		Entry:  zapcore.Entry{Level: zapcore.InfoLevel, Message: "m"},
+		Fields: []zapcore.Field{zap.String("a", "b"), zap.Int("n", 1)},
+	}}, entries)
+
+	err = core.Write(zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+	assert.EqualError(t, err, "hook panicked: oops")
+	assert.Len(t, entries, 2)
+}
+
+func TestAddHook(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-addhook", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	var messages []string
+	RegisterHook("test-addhook", Hook{Func: func(e Entry) error {
+		messages = append(messages, "named:"+e.Message)
+		return nil
+	}})
+	factory := NewFactory(NewOptions(OutputPaths("test-addhook://"), Hooks("test-addhook")))
+	assert.Nil(t, AddHook(factory, Hook{
+		Func: func(e Entry) error {
+			messages = append(messages, "error:"+e.Message)
+			return nil
+		},
+		Level: logging.ErrorLevel,
+		Names: []string{"foo"},
+	}))
+	factory.Logger("foo").Infow("a")
+	factory.Logger("foo.bar").Errorw("b")
+	factory.Logger("baz").Errorw("c")
+	assert.Equal(t, []string{"named:a", "named:b", "error:b", "named:c"}, messages)
+	assert.Len(t, sink.lines, 3)
+
+	// hooks of the factory are kept across options.
+	messages = nil
+	factory.(*zapFactory).SwitchOptions(NewOptions(OutputPaths("test-addhook://")))
+	factory.Logger("foo").Errorw("d")
+	assert.Equal(t, []string{"error:d"}, messages)
+
+	assert.NotNil(t, AddHook(logging.NewNopLoggerFactory(), Hook{}))
+}
+
+func TestAddHook_disableLogger(t *testing.T) {
+	registerSink("test-addhook-nameless", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return &tEntrySink{}, nil
+	})
+	var messages []string
+	factory := NewFactory(NewOptions(OutputPaths("test-addhook-nameless://"), DisableLogger(true)))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	assert.Nil(t, AddHook(factory, Hook{
+		Func: func(e Entry) error {
+			messages = append(messages, e.Message)
+			return nil
+		},
+		Names: []string{"foo"},
+	}))
+	factory.Logger("foo.bar").Infow("a")
+	factory.Logger("baz").Infow("b")
+	assert.Equal(t, []string{"a"}, messages)
+}
+
+func TestHookCore_Check(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.InfoLevel}
+	var messages []string
+	o := NewOptions()
+	o.factoryHooks = &hookList{}
+	o.factoryHooks.add(Hook{Func: func(e Entry) error {
+		messages = append(messages, e.Message)
+		return nil
+	}, Level: logging.ErrorLevel})
+	logger := zap.New(o.namedCore(rec, "foo", nil))
+	for i := 0; i < 200; i++ {
+		logger.Error("m")
+	}
+	logger.Info("i")
+	// every matching entry reaches hooks, including the ones dropped by sampling.
+	assert.Len(t, messages, 200)
+	assert.Len(t, rec.messages(), 102)
+}
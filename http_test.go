@@ -0,0 +1,383 @@
+package zap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+// tHTTPEndpoint records the NDJSON lines posted to it.
+type tHTTPEndpoint struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	batches  [][]string
+	// status returns the status of the nth request, 200 if nil.
+	status func(n int) int
+}
+
+func newTHTTPEndpoint(status func(n int) int) *tHTTPEndpoint {
+	e := &tHTTPEndpoint{status: status}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		n := len(e.requests)
+		e.requests = append(e.requests, r)
+		if e.status != nil {
+			if code := e.status(n); code != http.StatusOK {
+				w.WriteHeader(code)
+				return
+			}
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		var lines []string
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		e.batches = append(e.batches, lines)
+	}))
+	return e
+}
+
+func (e *tHTTPEndpoint) lines() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var lines []string
+	for _, batch := range e.batches {
+		lines = append(lines, batch...)
+	}
+	return lines
+}
+
+func startHTTPSink(t *testing.T, path string, opts ...Option) *httpSink {
+	t.Helper()
+	u, err := url.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := newHTTPSink(u, NewOptions(opts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = sink.Close()
+	})
+	return sink.(*httpSink)
+}
+
+func TestHTTPOptions_Defaulted(t *testing.T) {
+	headers := map[string]string{"a": "b"}
+	got := HTTPOptions{Headers: headers, MaxRetries: -1, Gzip: true}.Defaulted()
+	assert.Equal(t, HTTPOptions{
+		Headers:       map[string]string{"a": "b"},
+		BatchSize:     500,
+		BatchBytes:    1 << 20,
+		Buffer:        2000,
+		FlushInterval: time.Second,
+		Timeout:       10 * time.Second,
+		MaxInFlight:   2,
+		MaxRetries:    -1,
+		RetryBackoff:  100 * time.Millisecond,
+		SpillMaxBytes: 64 << 20,
+		Gzip:          true,
+	}, got)
+	got.Headers["c"] = "d"
+	assert.Len(t, headers, 1)
+	assert.Equal(t, 5, HTTPOptions{}.Defaulted().MaxRetries)
+}
+
+func TestHTTP(t *testing.T) {
+	o := &Options{}
+	HTTP(HTTPOptions{Gzip: true, BatchSize: 10})(o)
+	assert.Equal(t, HTTPOptions{Gzip: true, BatchSize: 10}, o.HTTP)
+	assert.Equal(t, 40, o.Defaulted().HTTP.Buffer)
+}
+
+func TestHTTPClient(t *testing.T) {
+	o := &Options{}
+	client := &http.Client{}
+	HTTPClient(client)(o)
+	assert.Same(t, client, o.httpClient)
+	assert.Same(t, client, o.Defaulted().httpClient)
+}
+
+func Test_newHTTPSink_error(t *testing.T) {
+	u, _ := url.Parse("http:///foo")
+	_, err := newHTTPSink(u, NewOptions())
+	assert.NotNil(t, err)
+}
+
+func TestHTTPSink_batch(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	path := strings.Replace(endpoint.URL, "http://", "http://user:secret@", 1) + "/ingest"
+	sink := startHTTPSink(t, path, HTTP(HTTPOptions{
+		BatchSize:     2,
+		MaxInFlight:   1,
+		FlushInterval: time.Hour,
+		Headers:       map[string]string{"X-Token": "abc"},
+	}))
+	for _, msg := range []string{"a", "b", "c"} {
+		_, _ = sink.Write([]byte(`{"msg":"` + msg + `"}` + "\n"))
+	}
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, [][]string{{`{"msg":"a"}`, `{"msg":"b"}`}, {`{"msg":"c"}`}}, endpoint.batches)
+	req := endpoint.requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/ingest", req.URL.Path)
+	assert.Equal(t, "application/x-ndjson", req.Header.Get("Content-Type"))
+	assert.Equal(t, "abc", req.Header.Get("X-Token"))
+	user, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", password)
+	stats, ok := NetworkOutputStats()[strings.Replace(path, "secret", "xxxxx", 1)]
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), stats.Written)
+}
+
+func TestHTTPSink_batchBytes(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{BatchBytes: 4, MaxInFlight: 1, FlushInterval: time.Hour}))
+	for _, msg := range []string{"ab", "cd", "e"} {
+		_, _ = sink.Write([]byte(msg))
+	}
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, [][]string{{"ab", "cd"}, {"e"}}, endpoint.batches)
+}
+
+func TestHTTPSink_flushInterval(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{FlushInterval: 10 * time.Millisecond}))
+	_, _ = sink.Write([]byte("a\n"))
+	assert.Eventually(t, func() bool {
+		return len(endpoint.lines()) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestHTTPSink_gzip(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{Gzip: true}))
+	_, _ = sink.Write([]byte("a\n"))
+	_, _ = sink.Write([]byte("b\r\n"))
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, []string{"a", "b"}, endpoint.lines())
+	assert.Equal(t, "gzip", endpoint.requests[0].Header.Get("Content-Encoding"))
+}
+
+func TestHTTPSink_retry(t *testing.T) {
+	endpoint := newTHTTPEndpoint(func(n int) int {
+		switch n {
+		case 0:
+			return http.StatusServiceUnavailable
+		case 1:
+			return http.StatusTooManyRequests
+		default:
+			return http.StatusOK
+		}
+	})
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{RetryBackoff: time.Millisecond}))
+	_, _ = sink.Write([]byte("a\n"))
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, []string{"a"}, endpoint.lines())
+	assert.Equal(t, uint64(2), NetworkOutputStats()[endpoint.URL].Retries)
+}
+
+func TestHTTPSink_drop(t *testing.T) {
+	endpoint := newTHTTPEndpoint(func(n int) int {
+		return http.StatusBadRequest
+	})
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{SpillDir: t.TempDir()}))
+	_, _ = sink.Write([]byte("a\n"))
+	assert.Nil(t, sink.Sync())
+	stats := NetworkOutputStats()[endpoint.URL]
+	// Not retried, nor spilled.
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, uint64(0), stats.Retries)
+	assert.Equal(t, uint64(0), stats.Spilled)
+	assert.Len(t, endpoint.requests, 1)
+}
+
+func TestHTTPSink_spill(t *testing.T) {
+	var available atomic.Bool
+	endpoint := newTHTTPEndpoint(func(n int) int {
+		if available.Load() {
+			return http.StatusOK
+		}
+		return http.StatusBadGateway
+	})
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{
+		BatchSize:    2,
+		MaxInFlight:  1,
+		MaxRetries:   -1,
+		RetryBackoff: time.Millisecond,
+		SpillDir:     t.TempDir(),
+	}))
+	for _, msg := range []string{"a", "b", "c"} {
+		_, _ = sink.Write([]byte(msg + "\n"))
+	}
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, uint64(3), NetworkOutputStats()[endpoint.URL].Spilled)
+	assert.Empty(t, endpoint.lines())
+
+	available.Store(true)
+	_, _ = sink.Write([]byte("d\n"))
+	assert.Nil(t, sink.Sync())
+	assert.Eventually(t, func() bool {
+		return len(endpoint.lines()) == 4
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"d", "a", "b", "c"}, endpoint.lines())
+	assert.Equal(t, uint64(4), NetworkOutputStats()[endpoint.URL].Written)
+}
+
+func TestHTTPSink_maxInFlight(t *testing.T) {
+	var inflight, maxInflight atomic.Int64
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Inc()
+		for {
+			m := maxInflight.Load()
+			if n <= m || maxInflight.CAS(m, n) {
+				break
+			}
+		}
+		<-release
+		inflight.Dec()
+	}))
+	defer server.Close()
+	sink := startHTTPSink(t, server.URL, HTTP(HTTPOptions{BatchSize: 1, MaxInFlight: 2}))
+	for i := 0; i < 5; i++ {
+		_, _ = sink.Write([]byte("a\n"))
+	}
+	assert.Eventually(t, func() bool {
+		return inflight.Load() == 2
+	}, 5*time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int64(2), maxInflight.Load())
+	close(release)
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, int64(2), maxInflight.Load())
+}
+
+func TestHTTPSink_close(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{FlushInterval: time.Hour}))
+	_, _ = sink.Write([]byte("a\n"))
+	// Buffered entries are posted on close.
+	assert.Nil(t, sink.Close())
+	assert.Equal(t, []string{"a"}, endpoint.lines())
+	_, err := sink.Write([]byte("b\n"))
+	assert.Equal(t, errNetworkSinkClosed, err)
+}
+
+func TestHTTPSink_closeConcurrently(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{FlushInterval: time.Hour, Buffer: 1000}))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = sink.Write([]byte("a\n"))
+			}
+		}()
+	}
+	assert.Nil(t, sink.Close())
+	wg.Wait()
+	// every entry is either dropped when writing, or posted when closing.
+	stats := NetworkOutputStats()[endpoint.URL]
+	assert.Equal(t, int64(0), sink.pending.Load())
+	assert.Equal(t, uint64(400), stats.Written+stats.Dropped)
+	assert.Len(t, endpoint.lines(), int(stats.Written))
+}
+
+func TestHTTPSink_closeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	sink := startHTTPSink(t, server.URL, HTTP(HTTPOptions{
+		Timeout:    50 * time.Millisecond,
+		MaxRetries: -1,
+		SpillDir:   t.TempDir(),
+	}))
+	_, _ = sink.Write([]byte("a\n"))
+	assert.Nil(t, sink.Close())
+	// Canceled and spilled.
+	assert.Equal(t, uint64(1), NetworkOutputStats()[server.URL].Spilled)
+}
+
+func TestHTTPSink_closeTimeoutRetrying(t *testing.T) {
+	endpoint := newTHTTPEndpoint(func(n int) int { return http.StatusBadGateway })
+	defer endpoint.Close()
+	sink := startHTTPSink(t, endpoint.URL, HTTP(HTTPOptions{
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Timeout:       50 * time.Millisecond,
+		MaxInFlight:   1,
+		MaxRetries:    10,
+		RetryBackoff:  time.Hour,
+		SpillDir:      t.TempDir(),
+	}))
+	for _, msg := range []string{"a", "b", "c"} {
+		_, _ = sink.Write([]byte(msg + "\n"))
+	}
+	// The final flush waits for the request in flight, which is retrying, till the deadline.
+	start := time.Now()
+	assert.Nil(t, sink.Close())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int64(0), sink.pending.Load())
+	assert.Equal(t, uint64(3), NetworkOutputStats()[endpoint.URL].Spilled)
+}
+
+func TestHTTPSink_outputPaths(t *testing.T) {
+	endpoint := newTHTTPEndpoint(nil)
+	defer endpoint.Close()
+	factory := NewFactory(NewOptions(
+		OutputPaths(endpoint.URL),
+		HTTPClient(endpoint.Client()),
+		HTTP(HTTPOptions{FlushInterval: time.Hour}),
+	)).(*zapFactory)
+	factory.Logger("foo").Info("abc")
+	factory.Logger("bar").Info("def")
+	// Posted on switch, in one batch.
+	factory.SwitchOptions(NewOptions())
+	assert.Len(t, endpoint.batches, 1)
+	lines := endpoint.lines()
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"msg":"abc"`)
+	assert.Contains(t, lines[1], `"msg":"def"`)
+}
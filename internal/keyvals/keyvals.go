@@ -0,0 +1,41 @@
+// Package keyvals maps alternating keys and values of other logger interfaces to fields.
+package keyvals
+
+import (
+	"fmt"
+
+	"github.com/yimi-go/logging"
+)
+
+// BadKey is the key of a value without a key.
+const BadKey = "!BADKEY"
+
+// Fields maps the alternating keys and values to fields.
+// Keys which are not strings are formatted, and errors are mapped to error fields.
+func Fields(keyvals []any) []logging.Field {
+	fields := make([]logging.Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			fields = append(fields, Field(BadKey, keyvals[i]))
+			break
+		}
+		fields = append(fields, Field(Key(keyvals[i]), keyvals[i+1]))
+	}
+	return fields
+}
+
+// Key returns the key as a string.
+func Key(key any) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
+
+// Field maps the key and the value to a field.
+func Field(key string, value any) logging.Field {
+	if err, ok := value.(error); ok {
+		return logging.NamedError(key, err)
+	}
+	return logging.Any(key, value)
+}
@@ -0,0 +1,29 @@
+package keyvals
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+)
+
+func TestFields(t *testing.T) {
+	err := errors.New("failed")
+	tests := []struct {
+		name    string
+		keyvals []any
+		want    []logging.Field
+	}{
+		{name: "empty", keyvals: nil, want: []logging.Field{}},
+		{name: "pairs", keyvals: []any{"a", 1, "b", "c"}, want: []logging.Field{logging.Any("a", 1), logging.Any("b", "c")}},
+		{name: "key", keyvals: []any{2, "b"}, want: []logging.Field{logging.Any("2", "b")}},
+		{name: "error", keyvals: []any{"err", err}, want: []logging.Field{logging.NamedError("err", err)}},
+		{name: "odd", keyvals: []any{"a", 1, "c"}, want: []logging.Field{logging.Any("a", 1), logging.Any(BadKey, "c")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Fields(tt.keyvals))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package logtest creates factories whose entries are inspected by tests.
+package logtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+)
+
+// Recorder reads entries written by a factory created by NewFactory.
+type Recorder struct {
+	t    testing.TB
+	path string
+}
+
+// NewFactory creates a factory writing JSON entries to a temporary file, which is read by the Recorder.
+// Options are applied after the output ones.
+func NewFactory(t testing.TB, options ...zaplogging.Option) (logging.Factory, *Recorder) {
+	path := filepath.Join(t.TempDir(), "entries.log")
+	options = append([]zaplogging.Option{
+		zaplogging.OutputPaths(path),
+		zaplogging.Encoding("json"),
+	}, options...)
+	factory := zaplogging.NewFactory(zaplogging.NewOptions(options...))
+	// closes the file before the temporary directory is removed.
+	t.Cleanup(func() {
+		factory.(interface{ SwitchOptions(*zaplogging.Options) }).SwitchOptions(zaplogging.NewOptions())
+	})
+	return factory, &Recorder{t: t, path: path}
+}
+
+// Entries returns the entries written so far.
+func (r *Recorder) Entries() []map[string]any {
+	f, err := os.Open(r.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		r.t.Fatalf("open entries: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	var entries []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			r.t.Fatalf("unmarshal entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		r.t.Fatalf("read entries: %v", err)
+	}
+	return entries
+}
@@ -0,0 +1,22 @@
+package logtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+)
+
+func TestNewFactory(t *testing.T) {
+	factory, rec := NewFactory(t, zaplogging.DisableCaller(true))
+	assert.Nil(t, rec.Entries())
+	factory.Logger("foo").Infow("a", logging.Int("n", 1))
+	factory.Logger("foo").Debugw("b")
+	entries := rec.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "a", entries[0]["msg"])
+	assert.Equal(t, "foo", entries[0]["logger"])
+	assert.Equal(t, float64(1), entries[0]["n"])
+	assert.NotContains(t, entries[0], "caller")
+}
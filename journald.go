@@ -0,0 +1,204 @@
+package zap
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultJournalSocket = "/run/systemd/journal/socket"
+
+func init() {
+	registerSink("journald", newJournaldSink)
+}
+
+// journaldSink writes entries to systemd journald with the native protocol.
+//
+// The output path is like:
+//
+//	journald://
+//	journald:///run/systemd/journal/socket?identifier=demo
+//
+// The path is the journal socket, "/run/systemd/journal/socket" as default.
+// The query parameter "identifier" is the SYSLOG_IDENTIFIER field, the executable name as default.
+//
+// Entries are written as journal fields, rather than in the encoding configured in Options.
+// MESSAGE, PRIORITY, SYSLOG_IDENTIFIER, CODE_FILE, CODE_LINE, CODE_FUNC, LOGGER and STACKTRACE are
+// set from the entry. Keys of fields are uppercased with invalid characters replaced by '_',
+// keys of nested fields are joined by '_', e.g. HTTP_REQUEST_METHOD.
+// Keys colliding with the fields set from the entry are prefixed with "F_", e.g. F_MESSAGE.
+//
+// Entries larger than the datagram limit are written to sealed memfds, or unlinked temporary files
+// if memfds are not supported, whose file descriptors are passed to journald, as the native protocol specifies.
+type journaldSink struct {
+	conn       *redialConn
+	path       string
+	identifier string
+}
+
+// journaldReservedFields are the fields set from entries, which fields of entries must not overwrite.
+var journaldReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+	"LOGGER":            true,
+	"STACKTRACE":        true,
+}
+
+func newJournaldSink(u *url.URL, _ *Options) (zap.Sink, error) {
+	path := u.Path
+	if path == "" || path == "/" {
+		path = defaultJournalSocket
+	}
+	identifier := u.Query().Get("identifier")
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	conn, err := newRedialConn(func() (net.Conn, error) {
+		return net.Dial("unixgram", path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldSink{conn: conn, path: path, identifier: identifier}, nil
+}
+
+func (s *journaldSink) newEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return &journaldEncoder{
+		flatEncoder: &flatEncoder{
+			EncoderConfig: &config,
+			buf:           flatPool.Get(),
+			appendPair:    appendJournaldPair,
+		},
+		identifier: s.identifier,
+	}
+}
+
+// Write writes a datagram in the native protocol, encoded by the journaldEncoder.
+func (s *journaldSink) Write(p []byte) (int, error) {
+	n, err := s.conn.Write(p)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		if err = s.writeFile(p); err == nil {
+			n = len(p)
+		}
+	}
+	return n, err
+}
+
+func (s *journaldSink) Sync() error {
+	return nil
+}
+
+func (s *journaldSink) Close() error {
+	return s.conn.Close()
+}
+
+// journaldEncoder encodes entries as datagrams of the journald native protocol.
+type journaldEncoder struct {
+	*flatEncoder
+	identifier string
+}
+
+func (enc *journaldEncoder) Clone() zapcore.Encoder {
+	return &journaldEncoder{flatEncoder: enc.clone(), identifier: enc.identifier}
+}
+
+func (enc *journaldEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	final.namespaces = nil
+	appendJournaldField(final.buf, "MESSAGE", ent.Message)
+	appendJournaldField(final.buf, "PRIORITY", strconv.Itoa(syslogSeverity(ent.Level)))
+	appendJournaldField(final.buf, "SYSLOG_IDENTIFIER", enc.identifier)
+	if ent.LoggerName != "" {
+		appendJournaldField(final.buf, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		appendJournaldField(final.buf, "CODE_FILE", ent.Caller.File)
+		appendJournaldField(final.buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			appendJournaldField(final.buf, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		appendJournaldField(final.buf, "STACKTRACE", ent.Stack)
+	}
+	final.namespaces = append(final.namespaces, enc.namespaces...)
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	return final.buf, nil
+}
+
+func appendJournaldPair(buf *buffer.Buffer, namespaces []string, key, value string) {
+	name := key
+	if len(namespaces) != 0 {
+		name = strings.Join(namespaces, "_") + "_" + key
+	}
+	name = journaldFieldName(name)
+	if journaldReservedFields[name] {
+		name = "F_" + name
+	}
+	appendJournaldField(buf, name, value)
+}
+
+// appendJournaldField appends the field in the native protocol.
+// Values containing newlines are written with their lengths, others are written as NAME=value lines.
+func appendJournaldField(buf *buffer.Buffer, name, value string) {
+	buf.AppendString(name)
+	if !strings.ContainsRune(value, '\n') {
+		buf.AppendByte('=')
+		buf.AppendString(value)
+		buf.AppendByte('\n')
+		return
+	}
+	buf.AppendByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	_, _ = buf.Write(size[:])
+	buf.AppendString(value)
+	buf.AppendByte('\n')
+}
+
+// journaldFieldName sanitizes the name as a journal field name,
+// which consists of uppercase letters, digits and underscores, and does not start with an underscore or a digit.
+func journaldFieldName(name string) string {
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name) && len(b) < 64; i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		if len(b) == 0 && c == '_' {
+			// Fields starting with '_' are trusted fields set by journald.
+			continue
+		}
+		b = append(b, c)
+	}
+	if len(b) == 0 {
+		return "FIELD"
+	}
+	if b[0] >= '0' && b[0] <= '9' {
+		b = append([]byte("F_"), b...)
+		if len(b) > 64 {
+			b = b[:64]
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package zap
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"go.uber.org/multierr"
+	"golang.org/x/sys/unix"
+)
+
+// writeFile passes the datagram to journald with the file descriptor of a file containing it.
+func (s *journaldSink) writeFile(p []byte) error {
+	f, err := journaldMemfd(p)
+	if err != nil {
+		if f, err = journaldTempFile(p); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: s.path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	// UnixConn.WriteMsgUnix refuses connected datagram sockets.
+	ctrlErr := raw.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, syscall.UnixRights(int(f.Fd())), nil, 0)
+		return err != syscall.EAGAIN
+	})
+	return multierr.Append(ctrlErr, err)
+}
+
+// journaldMemfd returns a sealed memfd containing the datagram, which journald requires of memfds.
+func journaldMemfd(p []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("journald", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "journald")
+	if _, err = f.Write(p); err == nil {
+		_, err = unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS,
+			unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// journaldTempFile returns an unlinked temporary file containing the datagram,
+// in /dev/shm if possible as journald does not accept files on every file system.
+func journaldTempFile(p []byte) (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "journald-")
+	if err != nil {
+		if f, err = os.CreateTemp("", "journald-"); err != nil {
+			return nil, err
+		}
+	}
+	_ = os.Remove(f.Name())
+	if _, err = f.Write(p); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,65 @@
+package zap
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_journaldSink_largeEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn := listenUnixgram(t, path)
+	defer func() {
+		_ = conn.Close()
+	}()
+	factory := NewFactory(NewOptions(OutputPaths("journald://" + path)))
+	message := strings.Repeat("a", 4<<20)
+	factory.Logger("foo").Info(message)
+
+	buf, oob := make([]byte, 1024), make([]byte, syscall.CmsgSpace(4))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	assert.Nil(t, err)
+	assert.Zero(t, n)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	assert.Nil(t, err)
+	assert.Len(t, msgs, 1)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	assert.Nil(t, err)
+	assert.Len(t, fds, 1)
+	f := os.NewFile(uintptr(fds[0]), "journald")
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err = f.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	p, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	fields := parseJournald(t, p)
+	assert.Equal(t, message, fields["MESSAGE"])
+	assert.Equal(t, "6", fields["PRIORITY"])
+	// memfds are sealed.
+	_, err = f.Write([]byte("x"))
+	assert.NotNil(t, err)
+}
+
+func Test_journaldTempFile(t *testing.T) {
+	f, err := journaldTempFile([]byte("MESSAGE=m\n"))
+	assert.Nil(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err = os.Stat(f.Name())
+	assert.True(t, os.IsNotExist(err))
+	_, err = f.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	p, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	assert.Equal(t, "MESSAGE=m\n", string(p))
+}
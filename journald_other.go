@@ -0,0 +1,10 @@
+//go:build !linux
+
+package zap
+
+import "fmt"
+
+// writeFile fails, as passing file descriptors to journald is supported on linux only.
+func (s *journaldSink) writeFile(p []byte) error {
+	return fmt.Errorf("journald: entry of %d bytes exceeds the datagram limit", len(p))
+}
@@ -0,0 +1,126 @@
+package zap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// parseJournald parses a datagram of the journald native protocol.
+func parseJournald(t *testing.T, p []byte) map[string]string {
+	fields := map[string]string{}
+	for len(p) != 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			t.Fatalf("bad datagram: %q", p)
+		}
+		line := p[:i]
+		p = p[i+1:]
+		if j := bytes.IndexByte(line, '='); j >= 0 {
+			fields[string(line[:j])] = string(line[j+1:])
+			continue
+		}
+		size := binary.LittleEndian.Uint64(p[:8])
+		fields[string(line)] = string(p[8 : 8+size])
+		p = p[8+size+1:]
+	}
+	return fields
+}
+
+func Test_journaldSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn := listenUnixgram(t, path)
+	defer func() {
+		_ = conn.Close()
+	}()
+	factory := NewFactory(NewOptions(
+		OutputPaths("journald://"+path+"?identifier=demo"),
+		GlobalFields(logging.String("service", "svc")),
+	))
+	logger := WithGroup(factory.Logger("foo"), "http")
+	logger.Warnw("hello\nworld",
+		Group("request", logging.String("method", "GET")),
+		logging.String("_trusted", "no"),
+		logging.Int("2xx", 3),
+	)
+	fields := parseJournald(t, []byte(readDatagram(t, conn)))
+	t.Log(fields)
+	assert.Equal(t, "hello\nworld", fields["MESSAGE"])
+	assert.Equal(t, "4", fields["PRIORITY"])
+	assert.Equal(t, "demo", fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "foo", fields["LOGGER"])
+	assert.Equal(t, "svc", fields["SERVICE"])
+	assert.Equal(t, "GET", fields["HTTP_REQUEST_METHOD"])
+	assert.Equal(t, "no", fields["HTTP__TRUSTED"])
+	assert.Equal(t, "3", fields["HTTP_2XX"])
+	assert.Contains(t, fields["CODE_FILE"], "journald_test.go")
+	assert.NotEmpty(t, fields["CODE_LINE"])
+	assert.Contains(t, fields["CODE_FUNC"], "Test_journaldSink")
+
+	logger.Error("oops")
+	fields = parseJournald(t, []byte(readDatagram(t, conn)))
+	assert.Equal(t, "3", fields["PRIORITY"])
+	assert.NotEmpty(t, fields["STACKTRACE"])
+}
+
+func Test_journaldEncoder_reserved(t *testing.T) {
+	enc := (&journaldSink{identifier: "demo"}).newEncoder(zapcore.EncoderConfig{})
+	enc.AddString("message", "field")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "m"}, []zapcore.Field{zap.String("Priority", "p")})
+	assert.Nil(t, err)
+	fields := parseJournald(t, buf.Bytes())
+	assert.Equal(t, "m", fields["MESSAGE"])
+	assert.Equal(t, "field", fields["F_MESSAGE"])
+	assert.Equal(t, "6", fields["PRIORITY"])
+	assert.Equal(t, "p", fields["F_PRIORITY"])
+}
+
+func Test_journaldEncoder(t *testing.T) {
+	enc := (&journaldSink{identifier: "demo"}).newEncoder(zapcore.EncoderConfig{})
+	enc.AddString("a", "b")
+	clone := enc.Clone()
+	clone.AddString("c", "d")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "A=b\nMESSAGE=m\nPRIORITY=6\nSYSLOG_IDENTIFIER=demo\n", buf.String())
+	buf, err = clone.EncodeEntry(zapcore.Entry{Message: "m", Level: zapcore.DebugLevel}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "A=b\nC=d\nMESSAGE=m\nPRIORITY=7\nSYSLOG_IDENTIFIER=demo\n", buf.String())
+}
+
+func Test_journaldFieldName(t *testing.T) {
+	assert.Equal(t, "FOO_BAR", journaldFieldName("foo.bar"))
+	assert.Equal(t, "FOO", journaldFieldName("__foo"))
+	assert.Equal(t, "FIELD", journaldFieldName("__"))
+	assert.Equal(t, "FIELD", journaldFieldName(""))
+	assert.Equal(t, "F_1A", journaldFieldName("1a"))
+	long := journaldFieldName(string(bytes.Repeat([]byte("a"), 100)))
+	assert.Len(t, long, 64)
+	long = journaldFieldName("1" + string(bytes.Repeat([]byte("a"), 100)))
+	assert.Len(t, long, 64)
+}
+
+func Test_newJournaldSink(t *testing.T) {
+	u, _ := url.Parse("journald:///not/exist.sock")
+	_, err := newJournaldSink(u, NewOptions())
+	assert.NotNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn := listenUnixgram(t, path)
+	defer func() {
+		_ = conn.Close()
+	}()
+	u, _ = url.Parse("journald://" + path)
+	sink, err := newJournaldSink(u, NewOptions())
+	assert.Nil(t, err)
+	assert.NotEmpty(t, sink.(*journaldSink).identifier)
+	assert.Nil(t, sink.Sync())
+	assert.Nil(t, sink.Close())
+}
@@ -0,0 +1,112 @@
+// Package kitlogging adapts a logging.Factory to go-kit/log.
+package kitlogging
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/keyvals"
+)
+
+// MessageKey is the key of messages of entries.
+const MessageKey = "msg"
+
+// maxWrappers is the maximum number of go-kit loggers wrapping a Logger, e.g. by log.With and level.NewFilter,
+// skipped by callers of entries.
+const maxWrappers = 8
+
+// Logger is a log.Logger writing to a logger of a factory.
+//
+// The level of an entry is the value of level.Key(), e.g. added by level.Info, InfoLevel if absent,
+// and the message is the value of MessageKey. Other keys and values are written as fields.
+// Callers of entries skip the go-kit loggers wrapping the Logger.
+type Logger struct {
+	// loggers are the loggers skipping callers of go-kit loggers, indexed by the number of them.
+	loggers [maxWrappers + 1]logging.Logger
+}
+
+var _ log.Logger = (*Logger)(nil)
+
+// New creates a Logger writing to the logger of the name of the factory.
+func New(factory logging.Factory, name string) *Logger {
+	l := &Logger{}
+	logger := factory.Logger(name)
+	for i := range l.loggers {
+		l.loggers[i] = zaplogging.AddCallerSkip(logger, 1+i)
+	}
+	return l
+}
+
+// Log writes the entry of the keys and values.
+func (l *Logger) Log(kvs ...any) error {
+	logger := l.loggers[wrappers()]
+	lvl := logging.InfoLevel
+	msg := ""
+	rest := make([]any, 0, len(kvs))
+	for i := 0; i < len(kvs); i += 2 {
+		if i+1 == len(kvs) {
+			rest = append(rest, kvs[i])
+			break
+		}
+		switch keyvals.Key(kvs[i]) {
+		case level.Key():
+			if parsed, ok := parseLevel(kvs[i+1]); ok {
+				lvl = parsed
+				continue
+			}
+		case MessageKey:
+			msg = fmt.Sprint(kvs[i+1])
+			continue
+		}
+		rest = append(rest, kvs[i], kvs[i+1])
+	}
+	if !logger.Enabled(lvl) {
+		return nil
+	}
+	fields := keyvals.Fields(rest)
+	zaplogging.Logw(logger, lvl, msg, fields...)
+	return nil
+}
+
+// parseLevel returns the level of the value of level.Key().
+func parseLevel(v any) (logging.Level, bool) {
+	var name string
+	switch v := v.(type) {
+	case level.Value:
+		name = v.String()
+	case string:
+		name = v
+	default:
+		return 0, false
+	}
+	l, ok := logging.LevelValue[strings.ToUpper(name)]
+	if !ok || l == logging.OffLevel {
+		return 0, false
+	}
+	return l, true
+}
+
+// wrappers returns the number of go-kit loggers calling Log.
+func wrappers() int {
+	var pcs [maxWrappers]uintptr
+	// skips runtime.Callers, wrappers and Log.
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	count := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/go-kit/log.") &&
+			!strings.HasPrefix(frame.Function, "github.com/go-kit/log/level.") {
+			return count
+		}
+		count++
+		if !more {
+			return count
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package kitlogging
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  logging.Level
+		ok    bool
+	}{
+		{name: "value", value: level.WarnValue(), want: logging.WarnLevel, ok: true},
+		{name: "string", value: "debug", want: logging.DebugLevel, ok: true},
+		{name: "off", value: "off", ok: false},
+		{name: "unknown", value: "verbose", ok: false},
+		{name: "other", value: 1, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLevel(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"kit": logging.WarnLevel}))
+	var logger log.Logger = New(factory, "kit")
+	_, file, line, _ := runtime.Caller(0)
+	assert.Nil(t, logger.Log("msg", "a", "level", "error", "n", 1))
+	assert.Nil(t, level.Info(logger).Log("msg", "b"))
+	assert.Nil(t, level.Warn(log.With(logger, "k", "v")).Log("msg", "c", "odd"))
+	assert.Nil(t, level.NewFilter(logger, level.AllowAll()).Log("level", level.ErrorValue(), "x", "y"))
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "a", entries[0]["msg"])
+	assert.Equal(t, "ERROR", entries[0]["level"])
+	assert.Equal(t, float64(1), entries[0]["n"])
+	assert.Equal(t, "c", entries[1]["msg"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "v", entries[1]["k"])
+	assert.Equal(t, log.ErrMissingValue.Error(), entries[1]["odd"])
+	assert.Equal(t, "", entries[2]["msg"])
+	assert.Equal(t, "y", entries[2]["x"])
+	for i, want := range []int{line + 1, line + 3, line + 4} {
+		assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), want),
+			entries[i]["caller"])
+	}
+}
@@ -0,0 +1,56 @@
+// Package kloglogging writes the output of k8s.io/klog to a logging.Factory.
+package kloglogging
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/yimi-go/logging"
+	"github.com/yimi-go/zap-logging/logrlogging"
+	"k8s.io/klog/v2"
+)
+
+// SetLogger makes klog write to the logger of the name of the factory, "klog" if empty.
+//
+// Entries of klog are written at InfoLevel, including those of klog.V(n) for any n, or ErrorLevel for errors,
+// when enabled by the level of the logger. Verbosity of klog.V is still controlled by the flags of klog.
+func SetLogger(factory logging.Factory, name string) {
+	klog.SetLogger(NewLogger(factory, name))
+}
+
+// NewLogger creates the logr.Logger set by SetLogger, whose messages are trimmed of the new lines klog adds.
+func NewLogger(factory logging.Factory, name string) logr.Logger {
+	if name == "" {
+		name = "klog"
+	}
+	// skips the frame of sink.
+	// klog filters V-levels by its flags, so all of them are written at InfoLevel.
+	options := logrlogging.Options{Levels: map[int]logging.Level{0: logging.InfoLevel}}
+	inner := logrlogging.NewLogSink(factory, name, options).(logr.CallDepthLogSink).WithCallDepth(1)
+	return logr.New(&sink{LogSink: inner})
+}
+
+// sink trims messages of the LogSink.
+type sink struct {
+	logr.LogSink
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...any) {
+	s.LogSink.Info(level, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...any) {
+	s.LogSink.Error(err, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+func (s *sink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithValues(keysAndValues...)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithName(name)}
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	return &sink{LogSink: s.LogSink.(logr.CallDepthLogSink).WithCallDepth(depth)}
+}
@@ -0,0 +1,78 @@
+package kloglogging
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+	"k8s.io/klog/v2"
+)
+
+func TestSetLogger(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	SetLogger(factory, "")
+	defer klog.ClearLogger()
+
+	_, file, line, _ := runtime.Caller(0)
+	klog.Info("a")
+	klog.Errorf("b %d", 1)
+	klog.InfoS("c", "k", "v")
+	klog.ErrorS(errors.New("failed"), "d")
+	klog.V(5).Info("e")
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 4)
+	var got []string
+	for _, e := range entries {
+		assert.Equal(t, "klog", e["logger"])
+		got = append(got, e["level"].(string)+" "+e["msg"].(string))
+	}
+	assert.Equal(t, []string{"INFO a", "ERROR b 1", "INFO c", "ERROR d"}, got)
+	assert.Equal(t, "v", entries[2]["k"])
+	assert.Equal(t, "failed", entries[3]["error"])
+	for i, want := range []int{line + 1, line + 2, line + 3, line + 4} {
+		assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), want),
+			entries[i]["caller"])
+	}
+}
+
+func TestSetLogger_verbosity(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	SetLogger(factory, "")
+	defer klog.ClearLogger()
+	var fs flag.FlagSet
+	klog.InitFlags(&fs)
+	assert.Nil(t, fs.Set("v", "2"))
+	defer func() {
+		_ = fs.Set("v", "0")
+	}()
+
+	klog.V(1).Info("a")
+	klog.V(2).InfoS("b")
+	klog.V(3).Info("c")
+	entries := rec.Entries()
+	assert.Len(t, entries, 2)
+	for i, msg := range []string{"a", "b"} {
+		assert.Equal(t, msg, entries[i]["msg"])
+		assert.Equal(t, "INFO", entries[i]["level"])
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"kube": logging.ErrorLevel}))
+	logger := NewLogger(factory, "kube").WithValues("k", "v").WithName("client")
+	logger.Info("a\n")
+	logger.Error(nil, "b\n")
+	entries := rec.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "b", entries[0]["msg"])
+	assert.Equal(t, "kube.client", entries[0]["logger"])
+	assert.Equal(t, "v", entries[0]["k"])
+}
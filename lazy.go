@@ -0,0 +1,37 @@
+package zap
+
+import (
+	"sync"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+// Lazy creates a field whose value is computed by the function only when the log entry is actually written,
+// that is, after it passes level and sampling checks.
+//
+// Lazy fields added to loggers by WithField are evaluated once for every entry written.
+func Lazy(key string, value func() any) logging.Field {
+	return field{key: key, typ: LazyType, val: value}
+}
+
+// lazyMarshaler adds the lazy field to the encoder inline, evaluating the value at most once.
+type lazyMarshaler struct {
+	value func() any
+	field zapcore.Field
+	key   string
+	once  sync.Once
+}
+
+func (l *lazyMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	l.resolve().AddTo(enc)
+	return nil
+}
+
+// resolve returns the field of the lazy value, evaluating it on the first call.
+func (l *lazyMarshaler) resolve() zapcore.Field {
+	l.once.Do(func() {
+		l.field = mapZapValue(l.key, l.value())
+	})
+	return l.field
+}
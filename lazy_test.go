@@ -0,0 +1,72 @@
+package zap
+
+import (
+	"bufio"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLazy(t *testing.T) {
+	f := Lazy("key", func() any { return "val" })
+	assert.Equal(t, "key", f.Key())
+	assert.Equal(t, LazyType, f.Type())
+	zf := mapZapField(f)
+	assert.Equal(t, zapcore.InlineMarshalerType, zf.Type)
+	assert.Equal(t, map[string]any{"key": "val"}, encodeFields(zf))
+}
+
+func Test_lazyMarshaler(t *testing.T) {
+	calls := 0
+	zf := mapZapField(Lazy("key", func() any {
+		calls++
+		return []string{"a"}
+	}))
+	assert.Equal(t, map[string]any{"key": []any{"a"}}, encodeFields(zf))
+	assert.Equal(t, map[string]any{"key": []any{"a"}}, encodeFields(zf))
+	assert.Equal(t, 1, calls)
+}
+
+func TestLazy_levelDisabled(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = writeCloser.Close()
+		_ = readCloser.Close()
+	}()
+	calls := 0
+	lazy := Lazy("key", func() any {
+		calls++
+		return 1
+	})
+	l := factory.Logger("foo")
+	l.Debugw("hello", lazy)
+	l.WithField(lazy).Debug("hello")
+	assert.Equal(t, 0, calls)
+}
+
+func TestLazy_sampled(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	calls := 0
+	lazy := Lazy("key", func() any {
+		calls++
+		return calls
+	})
+	l := factory.Logger("foo").WithField(lazy)
+	// The sampler lets the first 100 entries with the same message per second pass.
+	for i := 0; i < 150; i++ {
+		l.Infow("hello", lazy)
+	}
+	_ = writeCloser.Close()
+	assert.Equal(t, 200, calls)
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.Equal(t, float64(2), m["key"])
+}
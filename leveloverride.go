@@ -0,0 +1,152 @@
+package zap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/yimi-go/logging"
+)
+
+// levelOverride is the interface value of zap fields mapped from LevelOverride fields.
+type levelOverride logging.Level
+
+type levelOverrideKey struct{}
+
+// LevelOverride creates a field which makes loggers taking it enable entries of the level and above,
+// in addition to the levels of Options.Levels. The field is not written.
+func LevelOverride(level logging.Level) logging.Field {
+	return field{typ: LevelOverrideType, val: level}
+}
+
+// ContextWithLevel returns a context carrying a LevelOverride field of the level,
+// which loggers created by logging.WithContextField with the context take.
+func ContextWithLevel(ctx context.Context, level logging.Level) context.Context {
+	ctx = context.WithValue(ctx, levelOverrideKey{}, level)
+	return logging.NewContext(ctx, LevelOverride(level))
+}
+
+// ContextLevel returns the level set by ContextWithLevel.
+func ContextLevel(ctx context.Context) (logging.Level, bool) {
+	level, ok := ctx.Value(levelOverrideKey{}).(logging.Level)
+	return level, ok
+}
+
+// LevelOverrideOptions configures LevelOverrideMiddleware.
+type LevelOverrideOptions struct {
+	// Header is the header of requests overriding levels, whose value is the level, e.g. "debug",
+	// or "1" and "true" for DebugLevel. "X-Debug-Log" as default.
+	Header string `json:"header,omitempty" yaml:"header,omitempty"`
+	// AllowedNetworks are the networks of remote addresses allowed to override levels, in CIDR notation,
+	// e.g. "10.0.0.0/8". Headers of requests from other addresses are ignored.
+	// None as default, so that levels are not overridden until networks are set explicitly.
+	// Behind a local reverse proxy or sidecar all requests come from loopback addresses,
+	// so allow loopback networks only if no such proxy forwards external requests.
+	AllowedNetworks []string `json:"allowed_networks,omitempty" yaml:"allowed_networks,omitempty,flow"`
+}
+
+// Defaulted returns a new LevelOverrideOptions filling blank items with default values.
+func (l LevelOverrideOptions) Defaulted() LevelOverrideOptions {
+	l.Header = strings.TrimSpace(l.Header)
+	if l.Header == "" {
+		l.Header = "X-Debug-Log"
+	}
+	l.AllowedNetworks = trimStrings(l.AllowedNetworks)
+	return l
+}
+
+// LevelOverrideMiddleware sets level overrides of requests from their headers by ContextWithLevel,
+// so that e.g. debug entries of a single request are written without lowering levels of loggers.
+//
+// Overrides take effect only with loggers created by logging.WithContextField with the request contexts.
+type LevelOverrideMiddleware struct {
+	header   string
+	networks []*net.IPNet
+}
+
+// NewLevelOverrideMiddleware creates a LevelOverrideMiddleware.
+func NewLevelOverrideMiddleware(options LevelOverrideOptions) (*LevelOverrideMiddleware, error) {
+	options = options.Defaulted()
+	m := &LevelOverrideMiddleware{header: options.Header}
+	for _, n := range options.AllowedNetworks {
+		_, network, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", n, err)
+		}
+		m.networks = append(m.networks, network)
+	}
+	return m, nil
+}
+
+// Handler returns a handler calling next with requests whose contexts carry the level overrides.
+func (m *LevelOverrideMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctx, ok := m.context(r.Context(), r.Header.Get(m.header), r.RemoteAddr); ok {
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ContextFromMetadata returns the context carrying the level override of gRPC-style metadata,
+// whose keys are lower case, e.g. in a gRPC server interceptor:
+//
+//	md, _ := metadata.FromIncomingContext(ctx)
+//	p, _ := peer.FromContext(ctx)
+//	ctx = m.ContextFromMetadata(ctx, md, p.Addr.String())
+func (m *LevelOverrideMiddleware) ContextFromMetadata(
+	ctx context.Context, md map[string][]string, remoteAddr string,
+) context.Context {
+	values := md[strings.ToLower(m.header)]
+	if len(values) == 0 {
+		return ctx
+	}
+	if overridden, ok := m.context(ctx, values[0], remoteAddr); ok {
+		return overridden
+	}
+	return ctx
+}
+
+// context returns the context carrying the level of the header value, if the remote address is allowed.
+func (m *LevelOverrideMiddleware) context(ctx context.Context, value, remoteAddr string) (context.Context, bool) {
+	level, ok := parseLevelOverride(value)
+	if !ok || !m.allowed(remoteAddr) {
+		return ctx, false
+	}
+	return ContextWithLevel(ctx, level), true
+}
+
+func (m *LevelOverrideMiddleware) allowed(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range m.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLevelOverride parses the header value of level overrides.
+func parseLevelOverride(value string) (logging.Level, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	switch value {
+	case "":
+		return 0, false
+	case "1", "TRUE":
+		return logging.DebugLevel, true
+	}
+	level, ok := logging.LevelValue[value]
+	if !ok || level == logging.OffLevel {
+		return 0, false
+	}
+	return level, true
+}
@@ -0,0 +1,145 @@
+package zap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+)
+
+func TestContextWithLevel(t *testing.T) {
+	_, ok := ContextLevel(context.Background())
+	assert.False(t, ok)
+	ctx := ContextWithLevel(context.Background(), logging.DebugLevel)
+	level, ok := ContextLevel(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, logging.DebugLevel, level)
+}
+
+func TestLevelOverrideOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, LevelOverrideOptions{
+		Header:          "X-Debug-Log",
+		AllowedNetworks: []string{},
+	}, LevelOverrideOptions{AllowedNetworks: []string{" "}}.Defaulted())
+	assert.Equal(t, LevelOverrideOptions{
+		Header:          "X-Debug",
+		AllowedNetworks: []string{"10.0.0.0/8"},
+	}, LevelOverrideOptions{Header: " X-Debug ", AllowedNetworks: []string{" 10.0.0.0/8 "}}.Defaulted())
+}
+
+func TestParseLevelOverride(t *testing.T) {
+	tests := []struct {
+		value string
+		want  logging.Level
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "1", want: logging.DebugLevel, ok: true},
+		{value: "true", want: logging.DebugLevel, ok: true},
+		{value: " warn ", want: logging.WarnLevel, ok: true},
+		{value: "DEBUG", want: logging.DebugLevel, ok: true},
+		{value: "off", ok: false},
+		{value: "verbose", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseLevelOverride(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewLevelOverrideMiddleware(t *testing.T) {
+	_, err := NewLevelOverrideMiddleware(LevelOverrideOptions{AllowedNetworks: []string{"foo"}})
+	assert.EqualError(t, err, `invalid allowed network "foo": invalid CIDR address: foo`)
+}
+
+func TestLevelOverrideMiddleware_Handler(t *testing.T) {
+	m, err := NewLevelOverrideMiddleware(LevelOverrideOptions{AllowedNetworks: []string{"127.0.0.0/8", "::1/128"}})
+	assert.Nil(t, err)
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		want       bool
+	}{
+		{name: "loopback", remoteAddr: "127.0.0.1:1234", header: "1", want: true},
+		{name: "ipv6", remoteAddr: "[::1]:1234", header: "debug", want: true},
+		{name: "no_header", remoteAddr: "127.0.0.1:1234", want: false},
+		{name: "not_allowed", remoteAddr: "192.0.2.1:1234", header: "1", want: false},
+		{name: "invalid_addr", remoteAddr: "foo", header: "1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, got = ContextLevel(r.Context())
+			}))
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				r.Header.Set("X-Debug-Log", tt.header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLevelOverrideMiddleware_denyByDefault(t *testing.T) {
+	m, err := NewLevelOverrideMiddleware(LevelOverrideOptions{})
+	assert.Nil(t, err)
+	ctx := context.Background()
+	md := map[string][]string{"x-debug-log": {"debug"}}
+	assert.Equal(t, ctx, m.ContextFromMetadata(ctx, md, "127.0.0.1:1234"))
+}
+
+func TestLevelOverrideMiddleware_ContextFromMetadata(t *testing.T) {
+	m, err := NewLevelOverrideMiddleware(LevelOverrideOptions{AllowedNetworks: []string{"10.0.0.0/8"}})
+	assert.Nil(t, err)
+	ctx := context.Background()
+	assert.Equal(t, ctx, m.ContextFromMetadata(ctx, nil, "10.0.0.1:1234"))
+	md := map[string][]string{"x-debug-log": {"warn"}}
+	assert.Equal(t, ctx, m.ContextFromMetadata(ctx, md, "127.0.0.1:1234"))
+	level, ok := ContextLevel(m.ContextFromMetadata(ctx, md, "10.0.0.1:1234"))
+	assert.True(t, ok)
+	assert.Equal(t, logging.WarnLevel, level)
+}
+
+func TestLevelOverride(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-leveloverride", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(OutputPaths("test-leveloverride://")))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	logger := factory.Logger("foo")
+	debug := logging.WithContextField(ContextWithLevel(context.Background(), logging.DebugLevel), logger)
+	assert.False(t, logger.Enabled(logging.DebugLevel))
+	assert.True(t, debug.Enabled(logging.DebugLevel))
+	assert.True(t, debug.WithField(logging.String("a", "b")).Enabled(logging.DebugLevel))
+	// overrides only lower levels.
+	assert.True(t, logger.WithField(LevelOverride(logging.ErrorLevel)).Enabled(logging.InfoLevel))
+
+	logger.Debugw("a")
+	debug.Debugw("b")
+	assert.Len(t, sink.entries, 1)
+	assert.Equal(t, "b", sink.entries[0].Message)
+	assert.NotContains(t, sink.lines[0], "LevelOverride")
+
+	// entries of overridden levels are written rather than ring buffered.
+	factory.(*zapFactory).SwitchOptions(NewOptions(
+		OutputPaths("test-leveloverride://"),
+		RingBuffer(RingBufferOptions{Size: 10}),
+	))
+	logger.Debugw("c")
+	debug.Debugw("d")
+	assert.Len(t, sink.entries, 2)
+	assert.Equal(t, "d", sink.entries[1].Message)
+}
@@ -0,0 +1,152 @@
+package zap
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerEncoder("logfmt", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewLogfmtEncoder(config), nil
+	})
+}
+
+// NewLogfmtEncoder creates an encoder writing entries as logfmt key=value pairs.
+//
+// Fields nested in objects and namespaces are flattened with dotted keys,
+// e.g. http.request.method=GET.
+func NewLogfmtEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{flatEncoder: newFlatLogfmtEncoder(&config)}
+}
+
+func newFlatLogfmtEncoder(config *zapcore.EncoderConfig) *flatEncoder {
+	return &flatEncoder{
+		EncoderConfig: config,
+		buf:           flatPool.Get(),
+		appendPair:    appendLogfmtPair,
+	}
+}
+
+type logfmtEncoder struct {
+	*flatEncoder
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{flatEncoder: enc.clone()}
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := newFlatLogfmtEncoder(enc.EncoderConfig)
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		arr := &flatArrayEncoder{config: final.EncoderConfig}
+		final.EncodeLevel(ent.Level, arr)
+		level := arr.value()
+		if level == "" {
+			level = ent.Level.String()
+		}
+		final.addString(final.LevelKey, level)
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		nameEncoder := final.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		arr := &flatArrayEncoder{config: final.EncoderConfig}
+		nameEncoder(ent.LoggerName, arr)
+		name := arr.value()
+		if name == "" {
+			name = ent.LoggerName
+		}
+		final.addString(final.NameKey, name)
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			arr := &flatArrayEncoder{config: final.EncoderConfig}
+			final.EncodeCaller(ent.Caller, arr)
+			caller := arr.value()
+			if caller == "" {
+				caller = ent.Caller.String()
+			}
+			final.addString(final.CallerKey, caller)
+		}
+		if final.FunctionKey != "" {
+			final.addString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.addString(final.MessageKey, ent.Message)
+	}
+	if enc.buf.Len() > 0 {
+		final.buf.AppendByte(' ')
+		_, _ = final.buf.Write(enc.buf.Bytes())
+	}
+	final.namespaces = append(final.namespaces, enc.namespaces...)
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.namespaces = nil
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.addString(final.StacktraceKey, ent.Stack)
+	}
+	if !final.SkipLineEnding {
+		if final.LineEnding != "" {
+			final.buf.AppendString(final.LineEnding)
+		} else {
+			final.buf.AppendString(zapcore.DefaultLineEnding)
+		}
+	}
+	return final.buf, nil
+}
+
+func appendLogfmtPair(buf *buffer.Buffer, namespaces []string, key, value string) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	for _, ns := range namespaces {
+		appendLogfmtKey(buf, ns)
+		buf.AppendByte('.')
+	}
+	appendLogfmtKey(buf, key)
+	buf.AppendByte('=')
+	appendLogfmtValue(buf, value)
+}
+
+func appendLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			buf.AppendByte('_')
+			continue
+		}
+		buf.AppendString(string(r))
+	}
+}
+
+func appendLogfmtValue(buf *buffer.Buffer, value string) {
+	if !needsLogfmtQuote(value) {
+		buf.AppendString(value)
+		return
+	}
+	buf.AppendString(strconv.Quote(value))
+}
+
+func needsLogfmtQuote(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
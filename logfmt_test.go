@@ -0,0 +1,107 @@
+package zap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func testLogfmtEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		TimeKey:        "ts",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    "func",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05.000"),
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+func TestLogfmtEncoder_EncodeEntry(t *testing.T) {
+	enc := NewLogfmtEncoder(testLogfmtEncoderConfig())
+	enc.AddString("service", "demo")
+	enc.OpenNamespace("ctx")
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2022, 7, 1, 8, 0, 0, 0, time.UTC),
+		LoggerName: "foo",
+		Message:    "hello world",
+		Caller:     zapcore.NewEntryCaller(0, "/a/b/c.go", 10, true),
+		Stack:      "line1\nline2",
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.String("k", "v"),
+		zap.Object("o", fieldsMarshaler{}),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `ts="2022-07-01 08:00:00.000" level=WARN logger=foo caller=b/c.go:10 func="" `+
+		`msg="hello world" service=demo ctx.k=v stacktrace="line1\nline2"`+"\n", buf.String())
+
+	// the encoder itself is not changed by encoding entries.
+	buf, err = enc.EncodeEntry(zapcore.Entry{Time: ent.Time}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `ts="2022-07-01 08:00:00.000" level=INFO msg="" service=demo`+"\n", buf.String())
+}
+
+func TestLogfmtEncoder_Clone(t *testing.T) {
+	enc := NewLogfmtEncoder(testLogfmtEncoderConfig())
+	enc.OpenNamespace("a")
+	enc.AddString("b", "c")
+	clone := enc.Clone()
+	clone.AddString("d", "e")
+	assert.Equal(t, "a.b=c", enc.(*logfmtEncoder).buf.String())
+	assert.Equal(t, "a.b=c a.d=e", clone.(*logfmtEncoder).buf.String())
+}
+
+func TestLogfmtEncoder_fields(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  string
+		field zapcore.Field
+	}{
+		{name: "binary", field: zap.Binary("k", []byte("abc")), want: "k=YWJj"},
+		{name: "byte_string", field: zap.ByteString("k", []byte("a b")), want: `k="a b"`},
+		{name: "bool", field: zap.Bool("k", true), want: "k=true"},
+		{name: "c128", field: zap.Complex128("k", 1+2i), want: "k=(1+2i)"},
+		{name: "c64", field: zap.Complex64("k", 1+2i), want: "k=(1+2i)"},
+		{name: "duration", field: zap.Duration("k", time.Second), want: "k=1s"},
+		{name: "f64", field: zap.Float64("k", 1.5), want: "k=1.5"},
+		{name: "f32", field: zap.Float32("k", 1.5), want: "k=1.5"},
+		{name: "int", field: zap.Int("k", -1), want: "k=-1"},
+		{name: "i32", field: zap.Int32("k", 1), want: "k=1"},
+		{name: "i16", field: zap.Int16("k", 1), want: "k=1"},
+		{name: "i8", field: zap.Int8("k", 1), want: "k=1"},
+		{name: "uint", field: zap.Uint("k", 1), want: "k=1"},
+		{name: "u32", field: zap.Uint32("k", 1), want: "k=1"},
+		{name: "u16", field: zap.Uint16("k", 1), want: "k=1"},
+		{name: "u8", field: zap.Uint8("k", 1), want: "k=1"},
+		{name: "uintptr", field: zap.Uintptr("k", 1), want: "k=1"},
+		{name: "string_quote", field: zap.String("k", `a="b"`), want: `k="a=\"b\""`},
+		{name: "empty", field: zap.String("k", ""), want: `k=""`},
+		{name: "key", field: zap.String("a b=c", "d"), want: `a_b_c=d`},
+		{name: "empty_key", field: zap.String("", "d"), want: `_=d`},
+		{name: "time", field: zap.Time("k", time.Date(2022, 7, 1, 8, 0, 0, 0, time.UTC)), want: `k="2022-07-01 08:00:00.000"`},
+		{name: "strings", field: zap.Strings("k", []string{"a", "b"}), want: "k=[a,b]"},
+		{name: "errors", field: zap.Errors("k", []error{errors.New("x")}), want: `k="[{error=x}]"`},
+		{name: "durations", field: zap.Durations("k", []time.Duration{time.Second}), want: "k=[1s]"},
+		{name: "reflected", field: zap.Reflect("k", map[string]int{"a": 1}), want: `k="{\"a\":1}"`},
+		{name: "object", field: zap.Object("k", tZapUser{"a"}), want: "k.name=a"},
+		{name: "nested_array", field: zap.Array("k", tZapUsers{{"a"}, {"b"}}), want: `k="[{name=a},{name=b}]"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := NewLogfmtEncoder(testLogfmtEncoderConfig()).(*logfmtEncoder)
+			tt.field.AddTo(enc)
+			assert.Equal(t, tt.want, enc.buf.String())
+		})
+	}
+}
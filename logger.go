@@ -2,8 +2,11 @@ package zap
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/yimi-go/logging"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -11,6 +14,23 @@ type zapLogger struct {
 	name    string
 	factory *zapFactory
 	fields  []logging.Field
+	// override is the level of the last LevelOverride field, nil if none.
+	override *logging.Level
+	// callerSkip is the number of frames skipped by callers, in addition to the ones of the factory.
+	callerSkip int
+	// ringBuffered reports whether the logger has a RingBufferType field.
+	ringBuffered bool
+	// cache holds a *fieldCache, the zap logger with fields pre-encoded.
+	cache atomic.Value
+}
+
+// fieldCache is a zap logger with the accumulated fields of a zapLogger already applied.
+// It is only valid for the factory generation it was built in.
+type fieldCache struct {
+	logger     *zap.Logger
+	generation uint64
+	// helpers are the loggers skipping frames of helpers, by the numbers of frames.
+	helpers sync.Map
 }
 
 func sprintln(v ...any) string {
@@ -18,134 +38,302 @@ func sprintln(v ...any) string {
 	return s[:len(s)-1]
 }
 
+// Enabled reports whether the level is enabled by Options.Levels, or by the LevelOverride field of the logger.
 func (z *zapLogger) Enabled(level logging.Level) bool {
-	return z.factory.level(z.name).Enabled(level)
+	return z.factory.level(z.name).Enabled(level) || z.override != nil && z.override.Enabled(level)
+}
+
+// enabled reports whether entries of the level are passed to zap,
+// which includes entries below the level of the logger when they are ring buffered.
+func (z *zapLogger) enabled(level logging.Level) bool {
+	if z.Enabled(level) {
+		return true
+	}
+	return z.ringBuffered && z.factory.options.Load().(*Options).RingBuffer.Size > 0
 }
 
 func (z *zapLogger) Debug(v ...any) {
-	if !z.Enabled(logging.DebugLevel) {
+	if !z.enabled(logging.DebugLevel) {
 		return
 	}
-	z.factory.zap(z.name).Debug(fmt.Sprint(v...), z.zapFields()...)
+	z.zapCall().Debug(fmt.Sprint(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Debugln(v ...any) {
-	if !z.Enabled(logging.DebugLevel) {
+	if !z.enabled(logging.DebugLevel) {
 		return
 	}
-	z.factory.zap(z.name).Debug(sprintln(v...), z.zapFields()...)
+	z.zapCall().Debug(sprintln(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Debugf(format string, v ...any) {
-	if !z.Enabled(logging.DebugLevel) {
+	if !z.enabled(logging.DebugLevel) {
 		return
 	}
-	z.factory.zap(z.name).Debug(fmt.Sprintf(format, v...), z.zapFields()...)
+	z.zapCall().Debug(fmt.Sprintf(format, v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Debugw(message string, field ...logging.Field) {
-	if !z.Enabled(logging.DebugLevel) {
+	if !z.enabled(logging.DebugLevel) {
 		return
 	}
-	z.factory.zap(z.name).Debug(message, z.zapFields(field...)...)
+	z.zapCall().Debug(message, z.zapFields(field...)...)
 }
 
 func (z *zapLogger) Info(v ...any) {
-	if !z.Enabled(logging.InfoLevel) {
+	if !z.enabled(logging.InfoLevel) {
 		return
 	}
-	z.factory.zap(z.name).Info(fmt.Sprint(v...), z.zapFields()...)
+	z.zapCall().Info(fmt.Sprint(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Infoln(v ...any) {
-	if !z.Enabled(logging.InfoLevel) {
+	if !z.enabled(logging.InfoLevel) {
 		return
 	}
-	z.factory.zap(z.name).Info(sprintln(v...), z.zapFields()...)
+	z.zapCall().Info(sprintln(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Infof(format string, v ...any) {
-	if !z.Enabled(logging.InfoLevel) {
+	if !z.enabled(logging.InfoLevel) {
 		return
 	}
-	z.factory.zap(z.name).Info(fmt.Sprintf(format, v...), z.zapFields()...)
+	z.zapCall().Info(fmt.Sprintf(format, v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Infow(message string, field ...logging.Field) {
-	if !z.Enabled(logging.InfoLevel) {
+	if !z.enabled(logging.InfoLevel) {
 		return
 	}
-	z.factory.zap(z.name).Info(message, z.zapFields(field...)...)
+	z.zapCall().Info(message, z.zapFields(field...)...)
 }
 
 func (z *zapLogger) Warn(v ...any) {
-	if !z.Enabled(logging.WarnLevel) {
+	if !z.enabled(logging.WarnLevel) {
 		return
 	}
-	z.factory.zap(z.name).Warn(fmt.Sprint(v...), z.zapFields()...)
+	z.zapCall().Warn(fmt.Sprint(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Warnln(v ...any) {
-	if !z.Enabled(logging.WarnLevel) {
+	if !z.enabled(logging.WarnLevel) {
 		return
 	}
-	z.factory.zap(z.name).Warn(sprintln(v...), z.zapFields()...)
+	z.zapCall().Warn(sprintln(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Warnf(format string, v ...any) {
-	if !z.Enabled(logging.WarnLevel) {
+	if !z.enabled(logging.WarnLevel) {
 		return
 	}
-	z.factory.zap(z.name).Warn(fmt.Sprintf(format, v...), z.zapFields()...)
+	z.zapCall().Warn(fmt.Sprintf(format, v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Warnw(message string, field ...logging.Field) {
-	if !z.Enabled(logging.WarnLevel) {
+	if !z.enabled(logging.WarnLevel) {
 		return
 	}
-	z.factory.zap(z.name).Warn(message, z.zapFields(field...)...)
+	z.zapCall().Warn(message, z.zapFields(field...)...)
 }
 
 func (z *zapLogger) Error(v ...any) {
-	if !z.Enabled(logging.ErrorLevel) {
+	if !z.enabled(logging.ErrorLevel) {
 		return
 	}
-	z.factory.zap(z.name).Error(fmt.Sprint(v...), z.zapFields()...)
+	z.zapCall().Error(fmt.Sprint(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Errorln(v ...any) {
-	if !z.Enabled(logging.ErrorLevel) {
+	if !z.enabled(logging.ErrorLevel) {
 		return
 	}
-	z.factory.zap(z.name).Error(sprintln(v...), z.zapFields()...)
+	z.zapCall().Error(sprintln(v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Errorf(format string, v ...any) {
-	if !z.Enabled(logging.ErrorLevel) {
+	if !z.enabled(logging.ErrorLevel) {
 		return
 	}
-	z.factory.zap(z.name).Error(fmt.Sprintf(format, v...), z.zapFields()...)
+	z.zapCall().Error(fmt.Sprintf(format, v...), z.zapFields()...)
 }
 
 func (z *zapLogger) Errorw(message string, field ...logging.Field) {
-	if !z.Enabled(logging.ErrorLevel) {
+	if !z.enabled(logging.ErrorLevel) {
 		return
 	}
-	z.factory.zap(z.name).Error(message, z.zapFields(field...)...)
+	z.zapCall().Error(message, z.zapFields(field...)...)
+}
+
+// Logw writes the entry at the level, as Debugw, Infow, Warnw or Errorw of the logger does,
+// for adapters mapping levels of other logger interfaces. Entries of other levels are dropped.
+//
+// The caller of Logw is the caller of entries, as if it called the method of the level.
+func Logw(logger logging.Logger, level logging.Level, message string, field ...logging.Field) {
+	z, ok := logger.(*zapLogger)
+	if !ok {
+		switch level {
+		case logging.DebugLevel:
+			logger.Debugw(message, field...)
+		case logging.InfoLevel:
+			logger.Infow(message, field...)
+		case logging.WarnLevel:
+			logger.Warnw(message, field...)
+		case logging.ErrorLevel:
+			logger.Errorw(message, field...)
+		}
+		return
+	}
+	if level < logging.DebugLevel || level > logging.ErrorLevel || !z.enabled(level) {
+		return
+	}
+	// Check is called at the same depth as the methods of zap.Logger, so that callers are resolved alike.
+	if ce := z.zapCall().Check(zapcore.Level(level), message); ce != nil {
+		ce.Write(z.zapFields(field...)...)
+	}
 }
 
 func (z *zapLogger) WithField(field ...logging.Field) logging.Logger {
+	fields := make([]logging.Field, 0, len(z.fields)+len(field))
+	fields = append(fields, z.fields...)
+	fields = append(fields, field...)
+	override := z.override
+	ringBuffered := z.ringBuffered
+	for _, f := range field {
+		switch f.Type() {
+		case LevelOverrideType:
+			level := f.Value().(logging.Level)
+			override = &level
+		case RingBufferType:
+			ringBuffered = true
+		}
+	}
+	return &zapLogger{
+		name:         z.name,
+		factory:      z.factory,
+		fields:       fields,
+		override:     override,
+		callerSkip:   z.callerSkip,
+		ringBuffered: ringBuffered,
+	}
+}
+
+// AddCallerSkip returns a child of the logger created by NewFactory whose callers skip the number of frames more,
+// for wrappers of loggers, e.g. adapters to other logger interfaces. Other loggers are returned as is.
+func AddCallerSkip(logger logging.Logger, skip int) logging.Logger {
+	z, ok := logger.(*zapLogger)
+	if !ok || skip == 0 {
+		return logger
+	}
 	return &zapLogger{
-		name:    z.name,
-		factory: z.factory,
-		fields:  append(z.fields, field...),
+		name:         z.name,
+		factory:      z.factory,
+		fields:       z.fields,
+		override:     z.override,
+		callerSkip:   z.callerSkip + skip,
+		ringBuffered: z.ringBuffered,
 	}
 }
 
+// Sync flushes entries buffered by the outputs of the logger created by NewFactory,
+// e.g. before the process exits. It is a no-op for other loggers.
+func Sync(logger logging.Logger) error {
+	z, ok := logger.(*zapLogger)
+	if !ok {
+		return nil
+	}
+	return z.zap().Sync()
+}
+
+// WithGroup returns a child logger whose following fields are nested under the name.
+func (z *zapLogger) WithGroup(name string) logging.Logger {
+	return z.WithField(Namespace(name))
+}
+
+// zap returns the zap logger with the accumulated fields pre-encoded.
+//
+// Fields from the first one which can not be pre-encoded on are mapped for each logging call instead,
+// so that they keep their positions relative to namespaces.
+// The pre-encoded logger is cached, and rebuilt after the factory switches options.
+func (z *zapLogger) zap() *zap.Logger {
+	generation := z.factory.generation.Load()
+	if cache, ok := z.cache.Load().(*fieldCache); ok && cache.generation == generation {
+		return cache.logger
+	}
+	logger := z.factory.zap(z.name)
+	if z.callerSkip != 0 {
+		logger = logger.WithOptions(zap.AddCallerSkip(z.callerSkip))
+	}
+	static := staticFields(z.fields)
+	fields := make([]zapcore.Field, 0, len(z.fields))
+	for i, f := range z.fields {
+		if i < static || isMarkerField(f) {
+			fields = append(fields, mapZapField(f))
+		}
+	}
+	if len(fields) != 0 {
+		logger = logger.With(fields...)
+	}
+	z.cache.Store(&fieldCache{logger: logger, generation: generation})
+	return logger
+}
+
+// isStaticField reports whether the field can be pre-encoded.
+// Stack fields must be captured at the logging call site, and lazy fields must be evaluated
+// for each written entry, so they are not static. Neither are stringers and marshalers,
+// whose values may change after added to loggers.
+func isStaticField(field logging.Field) bool {
+	switch field.Type() {
+	case logging.StackType, LazyType, logging.StringerType:
+		return false
+	case GroupType:
+		for _, f := range field.Value().([]logging.Field) {
+			if !isStaticField(f) {
+				return false
+			}
+		}
+		return true
+	case logging.UnknownType:
+		switch field.Value().(type) {
+		case zapcore.ObjectMarshaler, zapcore.ArrayMarshaler, ObjectMarshaler, fmt.Stringer:
+			return false
+		}
+	}
+	return true
+}
+
+// isMarkerField reports whether the field is not written, but taken by cores from their With.
+// Marker fields are always pre-encoded, whose positions do not matter.
+func isMarkerField(field logging.Field) bool {
+	switch field.Type() {
+	case RingBufferType, LevelOverrideType:
+		return true
+	default:
+		return false
+	}
+}
+
+// staticFields returns the number of the leading fields which can be pre-encoded.
+func staticFields(fields []logging.Field) int {
+	for i, f := range fields {
+		if !isStaticField(f) {
+			return i
+		}
+	}
+	return len(fields)
+}
+
+// zapFields maps the fields given to a logging call, along with the accumulated fields
+// which are not pre-encoded.
 func (z *zapLogger) zapFields(field ...logging.Field) []zapcore.Field {
-	fields := make([]zapcore.Field, 0, len(field)+len(z.fields))
-	for _, f := range z.fields {
-		fields = append(fields, mapZapField(f))
+	accumulated := z.fields[staticFields(z.fields):]
+	if len(accumulated) == 0 && len(field) == 0 {
+		return nil
+	}
+	fields := make([]zapcore.Field, 0, len(accumulated)+len(field))
+	for _, f := range accumulated {
+		if !isMarkerField(f) {
+			fields = append(fields, mapZapField(f))
+		}
 	}
 	for _, f := range field {
 		fields = append(fields, mapZapField(f))
@@ -3,12 +3,20 @@ package zap
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func prepareZapFactory(name string, level logging.Level, opts ...Option) (*zapFactory, io.WriteCloser, io.ReadCloser) {
@@ -587,3 +595,296 @@ func Test_zapLogger_WithField(t *testing.T) {
 	assert.Equal(t, "bar", m["foo"])
 	assert.Equal(t, "INFO", m["level"])
 }
+
+func Test_zapLogger_zap(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = writeCloser.Close()
+		_ = readCloser.Close()
+	}()
+	l := factory.Logger("foo").WithField(logging.String("foo", "bar")).(*zapLogger)
+	zl := l.zap()
+	assert.Same(t, zl, l.zap())
+	assert.NotSame(t, factory.zap("foo"), zl)
+
+	factory.SwitchOptions(NewOptions())
+	zl2 := l.zap()
+	assert.NotSame(t, zl, zl2)
+	assert.Same(t, zl2, l.zap())
+}
+
+func Test_zapLogger_zapFields(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = writeCloser.Close()
+		_ = readCloser.Close()
+	}()
+	l := &zapLogger{fields: []logging.Field{logging.String("foo", "bar")}, factory: factory, name: "foo"}
+	assert.Nil(t, l.zapFields())
+	assert.Len(t, l.zapFields(logging.Int("a", 1)), 1)
+
+	l = &zapLogger{fields: []logging.Field{logging.Stack("stack")}, factory: factory, name: "foo"}
+	fields := l.zapFields(logging.Int("a", 1))
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "stack", fields[0].Key)
+
+	// fields from the first dynamic one on keep their positions, except marker fields.
+	l = &zapLogger{fields: []logging.Field{
+		logging.String("a", "b"),
+		logging.Stack("stack"),
+		Namespace("ns"),
+		LevelOverride(logging.DebugLevel),
+		logging.String("c", "d"),
+	}, factory: factory, name: "foo"}
+	var keys []string
+	for _, f := range l.zapFields(logging.Int("e", 1)) {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal(t, []string{"stack", "ns", "c", "e"}, keys)
+}
+
+func Test_zapLogger_dynamicFieldsInNamespace(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	l := factory.Logger("foo").WithField(Lazy("lazy", func() any { return "x" }))
+	l = WithGroup(l, "http").WithField(logging.String("method", "GET"))
+	l.Infow("hello", logging.Int("status", 200))
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.Equal(t, "x", m["lazy"])
+	assert.Equal(t, map[string]any{"method": "GET", "status": float64(200)}, m["http"])
+}
+
+func Test_zapLogger_WithField_stack(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	l := &zapLogger{fields: nil, factory: factory, name: "foo"}
+	l.WithField(logging.String("foo", "bar"), logging.Stack("stack")).Infow("hello")
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.Equal(t, "bar", m["foo"])
+	assert.Contains(t, m["stack"], "Test_zapLogger_WithField_stack")
+}
+
+func benchmarkFields() []logging.Field {
+	return []logging.Field{
+		logging.String("request_id", "0f8fad5b-d9cb-469f-a165-70867728950e"),
+		logging.String("method", "GET"),
+		logging.String("path", "/api/v1/users"),
+		logging.String("remote_addr", "127.0.0.1:54321"),
+		logging.String("user_agent", "Mozilla/5.0"),
+		logging.Int("status", 200),
+		logging.Int64("bytes", 1024),
+		logging.Duration("elapsed", time.Millisecond),
+		logging.Bool("tls", true),
+		logging.String("tenant", "yimi"),
+		logging.Uint32("shard", 7),
+		logging.Float64("ratio", 0.5),
+	}
+}
+
+// benchmarkFactory creates a factory writing to /dev/null without sampling,
+// which would drop nearly every entry of benchmarks logging the same message.
+func benchmarkFactory(b *testing.B) *zapFactory {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		_ = devNull.Close()
+	})
+	return NewFactory(NewOptions(OutputPaths(devNull.Name()), func(o *Options) {
+		o.disableSampling = true
+	})).(*zapFactory)
+}
+
+// BenchmarkZapLogger_Infow_mapEveryCall measures mapping all accumulated fields on every call,
+// as the logger did before pre-encoding, and still does for fields after one which can not be pre-encoded.
+func BenchmarkZapLogger_Infow_mapEveryCall(b *testing.B) {
+	factory := benchmarkFactory(b)
+	fields := append([]logging.Field{logging.Stringer("counter", &tCounter{})}, benchmarkFields()...)
+	l := factory.Logger("foo").WithField(fields...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infow("hello", logging.Int("i", i))
+	}
+}
+
+// BenchmarkZapLogger_Infow measures the same fields as BenchmarkZapLogger_Infow_mapEveryCall,
+// all but the last of which are pre-encoded.
+// The fields given to Infow escape as it is called by the logging.Logger interface, as callers usually do.
+// The logger was devirtualized by the compiler before WithField grew too large to inline, without that allocation.
+func BenchmarkZapLogger_Infow(b *testing.B) {
+	factory := benchmarkFactory(b)
+	fields := append(benchmarkFields(), logging.Stringer("counter", &tCounter{}))
+	l := factory.Logger("foo").WithField(fields...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infow("hello", logging.Int("i", i))
+	}
+}
+
+func BenchmarkZapLogger_Info(b *testing.B) {
+	factory := benchmarkFactory(b)
+	l := factory.Logger("foo").WithField(benchmarkFields()...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("hello")
+	}
+}
+
+func helperInfo(l logging.Logger) {
+	AddCallerSkip(l, 1).WithField(logging.String("foo", "bar")).Infow("hello")
+}
+
+func TestAddCallerSkip(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	l := factory.Logger("foo")
+	assert.Same(t, l, AddCallerSkip(l, 0))
+	_, _, line, _ := runtime.Caller(0)
+	helperInfo(l)
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	assert.True(t, scanner.Scan())
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+	assert.Equal(t, "bar", m["foo"])
+	assert.True(t, strings.HasSuffix(m["caller"].(string), fmt.Sprintf("/logger_test.go:%d", line+1)))
+
+	nop := logging.NewNopLoggerFactory().Logger("foo")
+	assert.Same(t, nop, AddCallerSkip(nop, 1))
+}
+
+func TestLogw(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	l := factory.Logger("foo").WithField(logging.String("a", "b"))
+	_, _, line, _ := runtime.Caller(0)
+	for _, level := range []logging.Level{logging.DebugLevel, logging.InfoLevel, logging.WarnLevel, logging.ErrorLevel, logging.OffLevel} {
+		Logw(l, level, level.String(), logging.Int("n", 1))
+	}
+	_ = writeCloser.Close()
+	var got []string
+	scanner := bufio.NewScanner(readCloser)
+	for scanner.Scan() {
+		m := map[string]any{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+		assert.Equal(t, "b", m["a"])
+		assert.Equal(t, float64(1), m["n"])
+		assert.True(t, strings.HasSuffix(m["caller"].(string), fmt.Sprintf("/logger_test.go:%d", line+2)))
+		got = append(got, m["level"].(string)+" "+m["msg"].(string))
+	}
+	assert.Equal(t, []string{"INFO INFO", "WARN WARN", "ERROR ERROR"}, got)
+
+	// Other loggers are called by the methods of the levels.
+	rec := &tLevelLogger{}
+	for _, level := range []logging.Level{logging.DebugLevel, logging.InfoLevel, logging.WarnLevel, logging.ErrorLevel, logging.OffLevel} {
+		Logw(rec, level, "a")
+	}
+	assert.Equal(t, []string{"debug", "info", "warn", "error"}, rec.calls)
+}
+
+type tLevelLogger struct {
+	logging.Logger
+	calls []string
+}
+
+func (l *tLevelLogger) Debugw(string, ...logging.Field) { l.calls = append(l.calls, "debug") }
+func (l *tLevelLogger) Infow(string, ...logging.Field)  { l.calls = append(l.calls, "info") }
+func (l *tLevelLogger) Warnw(string, ...logging.Field)  { l.calls = append(l.calls, "warn") }
+func (l *tLevelLogger) Errorw(string, ...logging.Field) { l.calls = append(l.calls, "error") }
+
+func TestSync(t *testing.T) {
+	var sinks []*tEntrySink
+	registerSink("test-sync", func(u *url.URL, o *Options) (zap.Sink, error) {
+		sink := &tEntrySink{}
+		sinks = append(sinks, sink)
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(OutputPaths("test-sync://")))
+	l := factory.Logger("foo")
+	l.Info("abc")
+	assert.Nil(t, Sync(l))
+	assert.Len(t, sinks, 1)
+	assert.Equal(t, 1, sinks[0].synced)
+
+	assert.Nil(t, Sync(logging.NewNopLoggerFactory().Logger("foo")))
+}
+
+type tCounter struct {
+	n int
+}
+
+func (c *tCounter) String() string {
+	return strconv.Itoa(c.n)
+}
+
+func (c *tCounter) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("n", c.n)
+	return nil
+}
+
+func Test_isStaticField(t *testing.T) {
+	c := &tCounter{}
+	tests := []struct {
+		name  string
+		field logging.Field
+		want  bool
+	}{
+		{name: "string", field: logging.String("k", "v"), want: true},
+		{name: "time", field: logging.Time("k", time.Now()), want: true},
+		{name: "stack", field: logging.Stack("k"), want: false},
+		{name: "lazy", field: Lazy("k", func() any { return 1 }), want: false},
+		{name: "stringer", field: logging.Stringer("k", c), want: false},
+		{name: "any stringer", field: logging.Any("k", time.Second), want: false},
+		{name: "any marshaler", field: logging.Any("k", zapcore.ObjectMarshaler(c)), want: false},
+		{name: "any", field: logging.Any("k", []int{1}), want: true},
+		{name: "group", field: Group("g", logging.String("k", "v")), want: true},
+		{name: "dynamic group", field: Group("g", logging.Stringer("k", c)), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isStaticField(tt.field))
+		})
+	}
+}
+
+func Test_zapLogger_mutableFields(t *testing.T) {
+	factory, writeCloser, readCloser := prepareZapFactory("foo", logging.InfoLevel)
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	c := &tCounter{}
+	l := factory.Logger("foo").WithField(logging.Stringer("s", c), logging.Any("o", zapcore.ObjectMarshaler(c)))
+	l.Infow("a")
+	c.n = 1
+	l.Infow("b")
+	_ = writeCloser.Close()
+	scanner := bufio.NewScanner(readCloser)
+	// values are taken by every logging call.
+	for _, want := range []int{0, 1} {
+		assert.True(t, scanner.Scan())
+		m := map[string]any{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+		assert.Equal(t, strconv.Itoa(want), m["s"])
+		assert.Equal(t, map[string]any{"n": float64(want)}, m["o"])
+	}
+}
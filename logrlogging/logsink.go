@@ -0,0 +1,167 @@
+// Package logrlogging adapts a logging.Factory to go-logr/logr, used by Kubernetes-style libraries.
+package logrlogging
+
+import (
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/keyvals"
+)
+
+// Options configures LogSinks.
+type Options struct {
+	// Levels maps V-levels to levels. V-levels missing take the level of the greatest V-level below them.
+	// {0: InfoLevel, 1: DebugLevel} as default.
+	Levels map[int]logging.Level `json:"levels,omitempty" yaml:"levels,omitempty"`
+}
+
+// Defaulted returns a new Options filling blank items with default values.
+func (o Options) Defaulted() Options {
+	levels := map[int]logging.Level{0: logging.InfoLevel, 1: logging.DebugLevel}
+	if len(o.Levels) != 0 {
+		levels = make(map[int]logging.Level, len(o.Levels))
+		for v, level := range o.Levels {
+			levels[v] = level
+		}
+	}
+	o.Levels = levels
+	return o
+}
+
+type vLevel struct {
+	v     int
+	level logging.Level
+}
+
+// vLevels are the V-levels of Options.Levels in descending order, along with their levels.
+type vLevels []vLevel
+
+func newVLevels(levels map[int]logging.Level) vLevels {
+	res := make(vLevels, 0, len(levels))
+	for v, level := range levels {
+		res = append(res, vLevel{v: v, level: level})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].v > res[j].v })
+	return res
+}
+
+// level returns the level of the V-level, InfoLevel if below all V-levels.
+func (l vLevels) level(v int) logging.Level {
+	for _, vl := range l {
+		if vl.v <= v {
+			return vl.level
+		}
+	}
+	return logging.InfoLevel
+}
+
+// logSink is a logr.LogSink writing to loggers of a factory.
+type logSink struct {
+	factory logging.Factory
+	levels  vLevels
+	name    string
+	fields  []logging.Field
+	// depth is the number of frames between callers and the logger, 1 for the sink itself.
+	depth  int
+	logger logging.Logger
+}
+
+// NewLogSink creates a logr.LogSink writing to the logger of the name of the factory.
+//
+// Names added by WithName are joined to the name by dots, so that prefixes of Options.Levels apply to them.
+// Callers of entries are the callers of logr.Logger, as the LogSink is a logr.CallDepthLogSink.
+func NewLogSink(factory logging.Factory, name string, options Options) logr.LogSink {
+	options = options.Defaulted()
+	s := &logSink{
+		factory: factory,
+		levels:  newVLevels(options.Levels),
+		name:    name,
+		depth:   1,
+	}
+	s.logger = s.newLogger()
+	return s
+}
+
+// newLogger creates the logger of the name, fields and depth of the sink.
+func (s *logSink) newLogger() logging.Logger {
+	logger := s.factory.Logger(s.name)
+	if len(s.fields) != 0 {
+		logger = logger.WithField(s.fields...)
+	}
+	return zaplogging.AddCallerSkip(logger, s.depth)
+}
+
+// NewLogger creates a logr.Logger of a LogSink created by NewLogSink.
+func NewLogger(factory logging.Factory, name string, options Options) logr.Logger {
+	return logr.New(NewLogSink(factory, name, options))
+}
+
+// Init adds the call depth of logr.
+func (s *logSink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+	s.logger = s.newLogger()
+}
+
+func (s *logSink) Enabled(level int) bool {
+	return s.logger.Enabled(s.levels.level(level))
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...any) {
+	fields := mapFields(keysAndValues)
+	zaplogging.Logw(s.logger, s.levels.level(level), msg, fields...)
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...any) {
+	fields := make([]logging.Field, 0, len(keysAndValues)/2+1)
+	if err != nil {
+		fields = append(fields, logging.Error(err))
+	}
+	s.logger.Errorw(msg, append(fields, mapFields(keysAndValues)...)...)
+}
+
+func (s *logSink) WithValues(keysAndValues ...any) logr.LogSink {
+	clone := *s
+	fields := mapFields(keysAndValues)
+	clone.fields = append(s.fields[:len(s.fields):len(s.fields)], fields...)
+	clone.logger = s.logger.WithField(fields...)
+	return &clone
+}
+
+func (s *logSink) WithName(name string) logr.LogSink {
+	clone := *s
+	if s.name == "" {
+		clone.name = name
+	} else {
+		clone.name = s.name + "." + name
+	}
+	clone.logger = clone.newLogger()
+	return &clone
+}
+
+// WithCallDepth returns a sink whose callers skip the number of frames more.
+func (s *logSink) WithCallDepth(depth int) logr.LogSink {
+	clone := *s
+	clone.depth += depth
+	clone.logger = clone.newLogger()
+	return &clone
+}
+
+// mapFields maps logr key value pairs to fields, marshaling values implementing logr.Marshaler.
+func mapFields(keysAndValues []any) []logging.Field {
+	for i := 1; i < len(keysAndValues); i += 2 {
+		if _, ok := keysAndValues[i].(logr.Marshaler); ok {
+			marshaled := make([]any, len(keysAndValues))
+			copy(marshaled, keysAndValues)
+			for j := i; j < len(marshaled); j += 2 {
+				if m, ok := marshaled[j].(logr.Marshaler); ok {
+					marshaled[j] = m.MarshalLog()
+				}
+			}
+			keysAndValues = marshaled
+			break
+		}
+	}
+	return keyvals.Fields(keysAndValues)
+}
@@ -0,0 +1,111 @@
+package logrlogging
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+)
+
+func TestOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, map[int]logging.Level{0: logging.InfoLevel, 1: logging.DebugLevel}, Options{}.Defaulted().Levels)
+	levels := map[int]logging.Level{2: logging.DebugLevel}
+	assert.Equal(t, levels, Options{Levels: levels}.Defaulted().Levels)
+}
+
+func TestVLevels_level(t *testing.T) {
+	levels := newVLevels(map[int]logging.Level{1: logging.WarnLevel, 3: logging.DebugLevel, 0: logging.ErrorLevel})
+	tests := []struct {
+		v    int
+		want logging.Level
+	}{
+		{v: -1, want: logging.InfoLevel},
+		{v: 0, want: logging.ErrorLevel},
+		{v: 1, want: logging.WarnLevel},
+		{v: 2, want: logging.WarnLevel},
+		{v: 3, want: logging.DebugLevel},
+		{v: 10, want: logging.DebugLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, levels.level(tt.v))
+		})
+	}
+}
+
+type tMarshaler struct{}
+
+func (tMarshaler) MarshalLog() any { return "marshaled" }
+
+func TestMapFields(t *testing.T) {
+	err := errors.New("failed")
+	assert.Equal(t, []logging.Field{
+		logging.Any("a", 1),
+		logging.Any("2", "b"),
+		logging.NamedError("err", err),
+		logging.Any("m", "marshaled"),
+		logging.Any("!BADKEY", "c"),
+	}, mapFields([]any{"a", 1, 2, "b", "err", err, "m", tMarshaler{}, "c"}))
+}
+
+func TestLogSink(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{
+		"k8s.controller": logging.DebugLevel,
+	}))
+	logger := NewLogger(factory, "k8s", Options{})
+	assert.True(t, logger.Enabled())
+	assert.False(t, logger.V(1).Enabled())
+
+	logger.Info("a", "n", 1)
+	logger.V(1).Info("b")
+	controller := logger.WithValues("kind", "Pod").WithName("controller")
+	controller.V(1).Info("c")
+	controller.V(2).Info("d", "n", 2)
+	controller.Error(errors.New("failed"), "e")
+	controller.WithName("sub").WithValues("x", "y").Error(nil, "f")
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "k8s", entries[0]["logger"])
+	assert.Equal(t, "INFO", entries[0]["level"])
+	assert.Equal(t, float64(1), entries[0]["n"])
+	assert.Equal(t, "k8s.controller", entries[1]["logger"])
+	assert.Equal(t, "DEBUG", entries[1]["level"])
+	assert.Equal(t, "c", entries[1]["msg"])
+	assert.Equal(t, "Pod", entries[1]["kind"])
+	assert.Equal(t, "d", entries[2]["msg"])
+	assert.Equal(t, "ERROR", entries[3]["level"])
+	assert.Equal(t, "failed", entries[3]["error"])
+	assert.Equal(t, "k8s.controller.sub", entries[4]["logger"])
+	assert.Equal(t, "Pod", entries[4]["kind"])
+	assert.Equal(t, "y", entries[4]["x"])
+	assert.NotContains(t, entries[4], "error")
+
+	root := NewLogger(factory, "", Options{Levels: map[int]logging.Level{0: logging.WarnLevel}})
+	root.WithName("foo").Info("g")
+	entries = rec.Entries()
+	assert.Equal(t, "foo", entries[5]["logger"])
+	assert.Equal(t, "WARN", entries[5]["level"])
+}
+
+func TestLogSink_caller(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	logger := NewLogger(factory, "foo", Options{})
+	_, file, line, _ := runtime.Caller(0)
+	logger.Info("a")
+	logger.WithCallDepth(0).WithName("bar").Error(nil, "b")
+	helper := func() { logger.WithCallDepth(1).Info("c") }
+	helper()
+	entries := rec.Entries()
+	assert.Len(t, entries, 3)
+	for i, want := range []int{line + 1, line + 2, line + 4} {
+		assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), want),
+			entries[i]["caller"])
+	}
+}
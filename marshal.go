@@ -0,0 +1,136 @@
+package zap
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ObjectMarshaler allows user types to be logged as objects without depending on zap.
+//
+// The fields returned by MarshalLogFields are nested under the key of the logged field.
+type ObjectMarshaler interface {
+	MarshalLogFields() []logging.Field
+}
+
+// FieldEncoder maps a value of a domain type to a zap field.
+type FieldEncoder func(key string, value any) zapcore.Field
+
+var (
+	fieldEncodersMu sync.Mutex
+	fieldEncoders   atomic.Value
+)
+
+func init() {
+	fieldEncoders.Store(map[reflect.Type]FieldEncoder{})
+}
+
+// RegisterFieldEncoder registers the encoder used to log values of type T.
+//
+// The encoder is looked up by the dynamic type of logged values, so T should be a concrete type.
+// Registering a type twice replaces the previous encoder.
+func RegisterFieldEncoder[T any](encoder func(key string, value T) zapcore.Field) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if encoder == nil {
+		registerFieldEncoder(typ, nil)
+		return
+	}
+	registerFieldEncoder(typ, func(key string, value any) zapcore.Field {
+		return encoder(key, value.(T))
+	})
+}
+
+// UnregisterFieldEncoder removes the encoder registered for type T.
+func UnregisterFieldEncoder[T any]() {
+	registerFieldEncoder(reflect.TypeOf((*T)(nil)).Elem(), nil)
+}
+
+func registerFieldEncoder(typ reflect.Type, encoder FieldEncoder) {
+	fieldEncodersMu.Lock()
+	defer fieldEncodersMu.Unlock()
+	m := fieldEncoders.Load().(map[reflect.Type]FieldEncoder)
+	nm := make(map[reflect.Type]FieldEncoder, len(m)+1)
+	for t, e := range m {
+		nm[t] = e
+	}
+	if encoder == nil {
+		delete(nm, typ)
+	} else {
+		nm[typ] = encoder
+	}
+	fieldEncoders.Store(nm)
+}
+
+func lookupFieldEncoder(value any) (FieldEncoder, bool) {
+	if value == nil {
+		return nil, false
+	}
+	m := fieldEncoders.Load().(map[reflect.Type]FieldEncoder)
+	if len(m) == 0 {
+		return nil, false
+	}
+	encoder, ok := m[reflect.TypeOf(value)]
+	return encoder, ok
+}
+
+// mapZapValue maps a value of a field without a specified type.
+func mapZapValue(key string, value any) zapcore.Field {
+	if encoder, ok := lookupFieldEncoder(value); ok {
+		return encoder(key, value)
+	}
+	switch v := value.(type) {
+	case zapcore.ObjectMarshaler:
+		return zap.Object(key, v)
+	case zapcore.ArrayMarshaler:
+		return zap.Array(key, v)
+	case ObjectMarshaler:
+		return zap.Object(key, fieldsMarshaler(v.MarshalLogFields()))
+	case []bool:
+		return zap.Bools(key, v)
+	case [][]byte:
+		return zap.ByteStrings(key, v)
+	case []complex128:
+		return zap.Complex128s(key, v)
+	case []complex64:
+		return zap.Complex64s(key, v)
+	case []time.Duration:
+		return zap.Durations(key, v)
+	case []float64:
+		return zap.Float64s(key, v)
+	case []float32:
+		return zap.Float32s(key, v)
+	case []int:
+		return zap.Ints(key, v)
+	case []int64:
+		return zap.Int64s(key, v)
+	case []int32:
+		return zap.Int32s(key, v)
+	case []int16:
+		return zap.Int16s(key, v)
+	case []int8:
+		return zap.Int8s(key, v)
+	case []string:
+		return zap.Strings(key, v)
+	case []time.Time:
+		return zap.Times(key, v)
+	case []uint:
+		return zap.Uints(key, v)
+	case []uint64:
+		return zap.Uint64s(key, v)
+	case []uint32:
+		return zap.Uint32s(key, v)
+	case []uint16:
+		return zap.Uint16s(key, v)
+	case []uintptr:
+		return zap.Uintptrs(key, v)
+	case []error:
+		return zap.Errors(key, v)
+	default:
+		return zap.Any(key, value)
+	}
+}
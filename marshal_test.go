@@ -0,0 +1,131 @@
+package zap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type tUser struct {
+	Name string
+	Age  int
+}
+
+func (u tUser) MarshalLogFields() []logging.Field {
+	return []logging.Field{
+		logging.String("name", u.Name),
+		logging.Int("age", u.Age),
+	}
+}
+
+type tZapUser struct {
+	Name string
+}
+
+func (u tZapUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	return nil
+}
+
+type tZapUsers []tZapUser
+
+func (us tZapUsers) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, u := range us {
+		if err := enc.AppendObject(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tMoney struct {
+	Currency string
+	Cents    int64
+}
+
+func encodeFields(fields ...zapcore.Field) map[string]any {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func Test_mapZapValue(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		value any
+		want  zapcore.Field
+		name  string
+	}{
+		{name: "zap_object", value: tZapUser{"a"}, want: zap.Object("key", tZapUser{"a"})},
+		{name: "zap_array", value: tZapUsers{{"a"}}, want: zap.Array("key", tZapUsers{{"a"}})},
+		{name: "bools", value: []bool{true}, want: zap.Bools("key", []bool{true})},
+		{name: "byte_strings", value: [][]byte{[]byte("a")}, want: zap.ByteStrings("key", [][]byte{[]byte("a")})},
+		{name: "c128s", value: []complex128{1 + 2i}, want: zap.Complex128s("key", []complex128{1 + 2i})},
+		{name: "c64s", value: []complex64{1 + 2i}, want: zap.Complex64s("key", []complex64{1 + 2i})},
+		{name: "durations", value: []time.Duration{time.Second}, want: zap.Durations("key", []time.Duration{time.Second})},
+		{name: "f64s", value: []float64{1.2}, want: zap.Float64s("key", []float64{1.2})},
+		{name: "f32s", value: []float32{1.2}, want: zap.Float32s("key", []float32{1.2})},
+		{name: "ints", value: []int{1}, want: zap.Ints("key", []int{1})},
+		{name: "i64s", value: []int64{1}, want: zap.Int64s("key", []int64{1})},
+		{name: "i32s", value: []int32{1}, want: zap.Int32s("key", []int32{1})},
+		{name: "i16s", value: []int16{1}, want: zap.Int16s("key", []int16{1})},
+		{name: "i8s", value: []int8{1}, want: zap.Int8s("key", []int8{1})},
+		{name: "strings", value: []string{"a"}, want: zap.Strings("key", []string{"a"})},
+		{name: "times", value: []time.Time{now}, want: zap.Times("key", []time.Time{now})},
+		{name: "uints", value: []uint{1}, want: zap.Uints("key", []uint{1})},
+		{name: "u64s", value: []uint64{1}, want: zap.Uint64s("key", []uint64{1})},
+		{name: "u32s", value: []uint32{1}, want: zap.Uint32s("key", []uint32{1})},
+		{name: "u16s", value: []uint16{1}, want: zap.Uint16s("key", []uint16{1})},
+		{name: "uintptrs", value: []uintptr{1}, want: zap.Uintptrs("key", []uintptr{1})},
+		{name: "errors", value: []error{errors.New("a")}, want: zap.Errors("key", []error{errors.New("a")})},
+		{name: "bytes", value: []byte("a"), want: zap.Binary("key", []byte("a"))},
+		{name: "nil", value: nil, want: zap.Any("key", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mapZapValue("key", tt.value))
+		})
+	}
+}
+
+func Test_mapZapValue_ObjectMarshaler(t *testing.T) {
+	field := mapZapField(logging.Any("user", tUser{Name: "foo", Age: 18}))
+	assert.Equal(t, zapcore.ObjectMarshalerType, field.Type)
+	assert.Equal(t, map[string]any{
+		"user": map[string]any{"name": "foo", "age": int64(18)},
+	}, encodeFields(field))
+}
+
+func TestRegisterFieldEncoder(t *testing.T) {
+	RegisterFieldEncoder(func(key string, value tMoney) zapcore.Field {
+		return zap.String(key, value.Currency)
+	})
+	defer UnregisterFieldEncoder[tMoney]()
+	assert.Equal(t, zap.String("price", "CNY"), mapZapField(logging.Any("price", tMoney{Currency: "CNY"})))
+	// Pointers are different types.
+	assert.Equal(t, zapcore.ReflectType, mapZapField(logging.Any("price", &tMoney{})).Type)
+
+	RegisterFieldEncoder(func(key string, value tMoney) zapcore.Field {
+		return zap.Int64(key, value.Cents)
+	})
+	assert.Equal(t, zap.Int64("price", 100), mapZapField(logging.Any("price", tMoney{Cents: 100})))
+
+	RegisterFieldEncoder[tMoney](nil)
+	assert.Equal(t, zapcore.ReflectType, mapZapField(logging.Any("price", tMoney{})).Type)
+}
+
+func TestUnregisterFieldEncoder(t *testing.T) {
+	RegisterFieldEncoder(func(key string, value tMoney) zapcore.Field {
+		return zap.String(key, value.Currency)
+	})
+	UnregisterFieldEncoder[tMoney]()
+	_, ok := lookupFieldEncoder(tMoney{})
+	assert.False(t, ok)
+}
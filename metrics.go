@@ -0,0 +1,222 @@
+package zap
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/atomic"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Metrics counts entries of loggers created by a factory, by logger names and levels.
+//
+// Metrics is an http.Handler writing the counts in the Prometheus text format.
+type Metrics struct {
+	// counters maps metricsKeys to *entryCounters.
+	counters sync.Map
+}
+
+type metricsKey struct {
+	name  string
+	level zapcore.Level
+}
+
+type entryCounters struct {
+	emitted     atomic.Uint64
+	sampled     atomic.Uint64
+	rateLimited atomic.Uint64
+	writeFailed atomic.Uint64
+}
+
+// EntryCounts are the counts of entries of a logger name and a level.
+type EntryCounts struct {
+	// Logger is the logger name.
+	Logger string `json:"logger"`
+	// Level is the level, e.g. "info".
+	Level string `json:"level"`
+	// Emitted is the number of entries written to the outputs.
+	Emitted uint64 `json:"emitted"`
+	// Sampled is the number of entries dropped by sampling.
+	Sampled uint64 `json:"sampled"`
+	// RateLimited is the number of entries dropped by RateLimit.
+	RateLimited uint64 `json:"rate_limited"`
+	// WriteFailed is the number of entries failed to be written to the outputs.
+	WriteFailed uint64 `json:"write_failed"`
+}
+
+// NewMetrics creates Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// FactoryMetrics returns the Metrics of the factory created by NewFactory, nil for other factories.
+func FactoryMetrics(factory logging.Factory) *Metrics {
+	zf, ok := factory.(*zapFactory)
+	if !ok {
+		return nil
+	}
+	return zf.metrics
+}
+
+// entry returns the counters of the entry of the logger name, nil if the Metrics is nil.
+// Names are passed along with entries, which are not named with DisableLogger.
+func (m *Metrics) entry(name string, ent zapcore.Entry) *entryCounters {
+	if m == nil {
+		return nil
+	}
+	key := metricsKey{name: name, level: ent.Level}
+	if c, ok := m.counters.Load(key); ok {
+		return c.(*entryCounters)
+	}
+	c, _ := m.counters.LoadOrStore(key, &entryCounters{})
+	return c.(*entryCounters)
+}
+
+func (m *Metrics) sampled(name string, ent zapcore.Entry) {
+	if c := m.entry(name, ent); c != nil {
+		c.sampled.Inc()
+	}
+}
+
+func (m *Metrics) rateLimited(name string, ent zapcore.Entry) {
+	if c := m.entry(name, ent); c != nil {
+		c.rateLimited.Inc()
+	}
+}
+
+// written counts the entry as emitted, or write failed if err is not nil.
+func (m *Metrics) written(name string, ent zapcore.Entry, err error) {
+	c := m.entry(name, ent)
+	if c == nil {
+		return
+	}
+	if err != nil {
+		c.writeFailed.Inc()
+	} else {
+		c.emitted.Inc()
+	}
+}
+
+// Snapshot returns the counts of entries, sorted by logger names and levels. None if the Metrics is nil.
+func (m *Metrics) Snapshot() []EntryCounts {
+	if m == nil {
+		return nil
+	}
+	var keys []metricsKey
+	counters := map[metricsKey]*entryCounters{}
+	m.counters.Range(func(key, value any) bool {
+		k := key.(metricsKey)
+		keys = append(keys, k)
+		counters[k] = value.(*entryCounters)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].level < keys[j].level
+	})
+	res := make([]EntryCounts, len(keys))
+	for i, k := range keys {
+		c := counters[k]
+		res[i] = EntryCounts{
+			Logger:      k.name,
+			Level:       k.level.String(),
+			Emitted:     c.emitted.Load(),
+			Sampled:     c.sampled.Load(),
+			RateLimited: c.rateLimited.Load(),
+			WriteFailed: c.writeFailed.Load(),
+		}
+	}
+	return res
+}
+
+var metricsFamilies = []struct {
+	name  string
+	help  string
+	count func(c EntryCounts) uint64
+}{
+	{
+		name:  "zap_logging_entries_emitted_total",
+		help:  "Log entries written to the outputs.",
+		count: func(c EntryCounts) uint64 { return c.Emitted },
+	},
+	{
+		name:  "zap_logging_entries_sampled_total",
+		help:  "Log entries dropped by sampling.",
+		count: func(c EntryCounts) uint64 { return c.Sampled },
+	},
+	{
+		name:  "zap_logging_entries_rate_limited_total",
+		help:  "Log entries dropped by rate limiting.",
+		count: func(c EntryCounts) uint64 { return c.RateLimited },
+	},
+	{
+		name:  "zap_logging_entries_write_failed_total",
+		help:  "Log entries failed to be written to the outputs.",
+		count: func(c EntryCounts) uint64 { return c.WriteFailed },
+	},
+}
+
+// ServeHTTP writes the counts of entries in the Prometheus text format, labeled by logger and level.
+// Only the metadata of the metric families is written if the Metrics is nil.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	counts := m.Snapshot()
+	buf := &buffer.Buffer{}
+	for _, f := range metricsFamilies {
+		buf.AppendString("# HELP " + f.name + " " + f.help + "\n")
+		buf.AppendString("# TYPE " + f.name + " counter\n")
+		for _, c := range counts {
+			buf.AppendString(f.name)
+			buf.AppendString(`{logger="`)
+			buf.AppendString(escapeMetricsLabel(c.Logger))
+			buf.AppendString(`",level="`)
+			buf.AppendString(c.Level)
+			buf.AppendString(`"} `)
+			buf.AppendString(strconv.FormatUint(f.count(c), 10))
+			buf.AppendByte('\n')
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+var metricsLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeMetricsLabel(s string) string {
+	return metricsLabelReplacer.Replace(s)
+}
+
+// metricsCore counts entries written to the outputs.
+type metricsCore struct {
+	zapcore.Core
+	metrics *Metrics
+	// name is the logger name taken from the fields added by With.
+	name string
+}
+
+func (c *metricsCore) With(fields []zapcore.Field) zapcore.Core {
+	name, ok := fieldsLoggerName(fields)
+	if !ok {
+		name = c.name
+	}
+	return &metricsCore{Core: c.Core.With(fields), metrics: c.metrics, name: name}
+}
+
+func (c *metricsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *metricsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	c.metrics.written(c.name, ent, err)
+	return err
+}
@@ -0,0 +1,127 @@
+package zap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// tFailCore fails to write entries.
+type tFailCore struct {
+	zapcore.LevelEnabler
+}
+
+func (c tFailCore) With([]zapcore.Field) zapcore.Core { return c }
+
+func (c tFailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c tFailCore) Write(zapcore.Entry, []zapcore.Field) error { return errors.New("failed") }
+
+func (c tFailCore) Sync() error { return nil }
+
+func TestMetrics(t *testing.T) {
+	var nilMetrics *Metrics
+	// nil Metrics count nothing.
+	nilMetrics.written("", zapcore.Entry{}, nil)
+	nilMetrics.sampled("", zapcore.Entry{})
+	nilMetrics.rateLimited("", zapcore.Entry{})
+	assert.Empty(t, nilMetrics.Snapshot())
+	w := httptest.NewRecorder()
+	nilMetrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "{logger=")
+
+	m := NewMetrics()
+	foo := zapcore.Entry{Level: zapcore.ErrorLevel}
+	m.written("foo", foo, nil)
+	m.written("foo", foo, nil)
+	m.written("foo", foo, errors.New("failed"))
+	m.sampled("foo", foo)
+	m.rateLimited("foo", foo)
+	m.written("foo", zapcore.Entry{Level: zapcore.InfoLevel}, nil)
+	m.written("bar", zapcore.Entry{Level: zapcore.WarnLevel}, nil)
+	assert.Equal(t, []EntryCounts{
+		{Logger: "bar", Level: "warn", Emitted: 1},
+		{Logger: "foo", Level: "info", Emitted: 1},
+		{Logger: "foo", Level: "error", Emitted: 2, Sampled: 1, RateLimited: 1, WriteFailed: 1},
+	}, m.Snapshot())
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := NewMetrics()
+	m.written(`a"b\c`, zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+	m.sampled("foo", zapcore.Entry{Level: zapcore.InfoLevel})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP zap_logging_entries_emitted_total Log entries written to the outputs.
+# TYPE zap_logging_entries_emitted_total counter
+zap_logging_entries_emitted_total{logger="a\"b\\c",level="error"} 1
+zap_logging_entries_emitted_total{logger="foo",level="info"} 0
+# HELP zap_logging_entries_sampled_total Log entries dropped by sampling.
+# TYPE zap_logging_entries_sampled_total counter
+zap_logging_entries_sampled_total{logger="a\"b\\c",level="error"} 0
+zap_logging_entries_sampled_total{logger="foo",level="info"} 1
+# HELP zap_logging_entries_rate_limited_total Log entries dropped by rate limiting.
+# TYPE zap_logging_entries_rate_limited_total counter
+zap_logging_entries_rate_limited_total{logger="a\"b\\c",level="error"} 0
+zap_logging_entries_rate_limited_total{logger="foo",level="info"} 0
+# HELP zap_logging_entries_write_failed_total Log entries failed to be written to the outputs.
+# TYPE zap_logging_entries_write_failed_total counter
+zap_logging_entries_write_failed_total{logger="a\"b\\c",level="error"} 0
+zap_logging_entries_write_failed_total{logger="foo",level="info"} 0
+`, w.Body.String())
+}
+
+func TestMetricsCore(t *testing.T) {
+	m := NewMetrics()
+	o := &Options{metrics: m}
+	core, _ := o.wrapCore(tFailCore{LevelEnabler: zapcore.DebugLevel})
+	core = o.namedCore(core, "foo", nil)
+	// entries are not named with DisableLogger.
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Message: "a"}
+	for i := 0; i < 101; i++ {
+		ce := core.With([]zapcore.Field{zap.Int("i", i)}).Check(ent, nil)
+		if ce != nil {
+			ce.Write()
+		}
+	}
+	assert.Equal(t, []EntryCounts{
+		{Logger: "foo", Level: "info", Sampled: 1, WriteFailed: 100},
+	}, m.Snapshot())
+}
+
+func TestFactoryMetrics(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-metrics", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-metrics://"),
+		RateLimit(RateLimitOptions{Limits: map[string]TokenBucket{"foo": {Rate: 0.001}}}),
+	))
+	m := FactoryMetrics(factory)
+	factory.Logger("foo").Errorw("failed")
+	factory.Logger("foo").Errorw("failed")
+	factory.Logger("bar").Infow("ok")
+	assert.Equal(t, []EntryCounts{
+		{Logger: "bar", Level: "info", Emitted: 1},
+		{Logger: "foo", Level: "error", Emitted: 1, RateLimited: 1},
+	}, m.Snapshot())
+
+	// counts survive switching options.
+	factory.(*zapFactory).SwitchOptions(NewOptions(OutputPaths("test-metrics://")))
+	factory.Logger("bar").Infow("ok")
+	assert.Equal(t, uint64(2), m.Snapshot()[0].Emitted)
+
+	assert.Nil(t, FactoryMetrics(logging.NewNopLoggerFactory()))
+}
@@ -0,0 +1,355 @@
+package zap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// msgpackTimestamp is the extension type of timestamps.
+const msgpackTimestamp = -1
+
+func init() {
+	registerEncoder("msgpack", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewMsgpackEncoder(config), nil
+	})
+}
+
+// NewMsgpackEncoder creates an encoder writing entries as MessagePack maps.
+//
+// Times are written as the timestamp extension type, durations as integers of nanoseconds,
+// binaries as bin, and complex numbers as arrays of real and imaginary parts.
+// Maps and arrays are written with 32-bit lengths filled in when they end, so that entries are written in a single pass.
+// DecodeBinary converts entries back to JSON.
+func NewMsgpackEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return newBinaryEncoder(&config, msgpackFormat{})
+}
+
+type msgpackFormat struct{}
+
+// appendMsgpackHead appends the head of a string, bin, array or map of the length,
+// in the smallest of the fix form, if there is fix, and the forms of 8, 16 or 32-bit lengths.
+func appendMsgpackHead(buf *buffer.Buffer, fix byte, fixMax int, forms [3]byte, n int) {
+	var b [5]byte
+	switch {
+	case n <= fixMax:
+		buf.AppendByte(fix | byte(n))
+	case forms[0] != 0 && n <= math.MaxUint8:
+		buf.AppendByte(forms[0])
+		buf.AppendByte(byte(n))
+	case n <= math.MaxUint16:
+		b[0] = forms[1]
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		_, _ = buf.Write(b[:3])
+	default:
+		b[0] = forms[2]
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		_, _ = buf.Write(b[:5])
+	}
+}
+
+func (msgpackFormat) appendMapStart(buf *buffer.Buffer) {
+	// map 32, the length of which is filled in at the end.
+	_, _ = buf.Write([]byte{0xdf, 0, 0, 0, 0})
+}
+
+func (msgpackFormat) appendMapEnd(buf *buffer.Buffer, start, count int) {
+	binary.BigEndian.PutUint32(buf.Bytes()[start+1:], uint32(count))
+}
+
+func (msgpackFormat) appendArrayStart(buf *buffer.Buffer) {
+	// array 32, the length of which is filled in at the end.
+	_, _ = buf.Write([]byte{0xdd, 0, 0, 0, 0})
+}
+
+func (msgpackFormat) appendArrayEnd(buf *buffer.Buffer, start, count int) {
+	binary.BigEndian.PutUint32(buf.Bytes()[start+1:], uint32(count))
+}
+
+func (msgpackFormat) appendString(buf *buffer.Buffer, s string) {
+	appendMsgpackHead(buf, 0xa0, 31, [3]byte{0xd9, 0xda, 0xdb}, len(s))
+	buf.AppendString(s)
+}
+
+func (msgpackFormat) appendBytes(buf *buffer.Buffer, b []byte) {
+	appendMsgpackHead(buf, 0, -1, [3]byte{0xc4, 0xc5, 0xc6}, len(b))
+	_, _ = buf.Write(b)
+}
+
+func (f msgpackFormat) appendInt(buf *buffer.Buffer, i int64) {
+	var b [9]byte
+	switch {
+	case i >= 0:
+		f.appendUint(buf, uint64(i))
+	case i >= -32:
+		// negative fixint.
+		buf.AppendByte(byte(i))
+	case i >= math.MinInt8:
+		buf.AppendByte(0xd0)
+		buf.AppendByte(byte(i))
+	case i >= math.MinInt16:
+		b[0] = 0xd1
+		binary.BigEndian.PutUint16(b[1:], uint16(i))
+		_, _ = buf.Write(b[:3])
+	case i >= math.MinInt32:
+		b[0] = 0xd2
+		binary.BigEndian.PutUint32(b[1:], uint32(i))
+		_, _ = buf.Write(b[:5])
+	default:
+		b[0] = 0xd3
+		binary.BigEndian.PutUint64(b[1:], uint64(i))
+		_, _ = buf.Write(b[:9])
+	}
+}
+
+func (msgpackFormat) appendUint(buf *buffer.Buffer, u uint64) {
+	var b [9]byte
+	switch {
+	case u <= math.MaxInt8:
+		// positive fixint.
+		buf.AppendByte(byte(u))
+	case u <= math.MaxUint8:
+		buf.AppendByte(0xcc)
+		buf.AppendByte(byte(u))
+	case u <= math.MaxUint16:
+		b[0] = 0xcd
+		binary.BigEndian.PutUint16(b[1:], uint16(u))
+		_, _ = buf.Write(b[:3])
+	case u <= math.MaxUint32:
+		b[0] = 0xce
+		binary.BigEndian.PutUint32(b[1:], uint32(u))
+		_, _ = buf.Write(b[:5])
+	default:
+		b[0] = 0xcf
+		binary.BigEndian.PutUint64(b[1:], u)
+		_, _ = buf.Write(b[:9])
+	}
+}
+
+func (msgpackFormat) appendFloat64(buf *buffer.Buffer, f float64) {
+	var b [9]byte
+	b[0] = 0xcb
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	_, _ = buf.Write(b[:])
+}
+
+func (msgpackFormat) appendFloat32(buf *buffer.Buffer, f float32) {
+	var b [5]byte
+	b[0] = 0xca
+	binary.BigEndian.PutUint32(b[1:], math.Float32bits(f))
+	_, _ = buf.Write(b[:])
+}
+
+func (msgpackFormat) appendBool(buf *buffer.Buffer, b bool) {
+	if b {
+		buf.AppendByte(0xc3)
+	} else {
+		buf.AppendByte(0xc2)
+	}
+}
+
+// appendTime appends the time as the timestamp extension type, in the smallest of the 32, 64 and 96-bit forms.
+func (msgpackFormat) appendTime(buf *buffer.Buffer, t time.Time) {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	var b [15]byte
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		// fixext 4.
+		b[0], b[1] = 0xd6, byte(msgpackTimestamp&0xff)
+		binary.BigEndian.PutUint32(b[2:], uint32(sec))
+		_, _ = buf.Write(b[:6])
+	case sec>>34 == 0:
+		// fixext 8, nanoseconds in the high 30 bits and seconds in the low 34 bits.
+		b[0], b[1] = 0xd7, byte(msgpackTimestamp&0xff)
+		binary.BigEndian.PutUint64(b[2:], nsec<<34|uint64(sec))
+		_, _ = buf.Write(b[:10])
+	default:
+		// ext 8 of 12 bytes.
+		b[0], b[1], b[2] = 0xc7, 12, byte(msgpackTimestamp&0xff)
+		binary.BigEndian.PutUint32(b[3:], uint32(nsec))
+		binary.BigEndian.PutUint64(b[7:], uint64(sec))
+		_, _ = buf.Write(b[:15])
+	}
+}
+
+// decodeMsgpack decodes a MessagePack object and appends it as JSON.
+func decodeMsgpack(r *bufio.Reader, buf *buffer.Buffer) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case b <= 0x7f:
+		buf.AppendUint(uint64(b))
+		return nil
+	case b >= 0xe0:
+		buf.AppendInt(int64(int8(b)))
+		return nil
+	case b <= 0x8f:
+		return decodeMsgpackContainer(r, buf, true, uint64(b&0x0f))
+	case b <= 0x9f:
+		return decodeMsgpackContainer(r, buf, false, uint64(b&0x0f))
+	case b <= 0xbf:
+		return decodeMsgpackString(r, buf, uint64(b&0x1f))
+	}
+	switch b {
+	case 0xc0:
+		buf.AppendString("null")
+	case 0xc2:
+		buf.AppendBool(false)
+	case 0xc3:
+		buf.AppendBool(true)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackUint(r, 1<<(b-0xc4))
+		if err != nil {
+			return err
+		}
+		data, err := readFull(r, n)
+		if err != nil {
+			return err
+		}
+		appendJSONString(buf, base64.StdEncoding.EncodeToString(data))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackUint(r, 1<<(b-0xc7))
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackExt(r, buf, n)
+	case 0xca:
+		bits, err := readMsgpackUint(r, 4)
+		if err != nil {
+			return err
+		}
+		appendJSONFloat(buf, float64(math.Float32frombits(uint32(bits))))
+	case 0xcb:
+		bits, err := readMsgpackUint(r, 8)
+		if err != nil {
+			return err
+		}
+		appendJSONFloat(buf, math.Float64frombits(bits))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readMsgpackUint(r, 1<<(b-0xcc))
+		if err != nil {
+			return err
+		}
+		buf.AppendUint(n)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := readMsgpackUint(r, size)
+		if err != nil {
+			return err
+		}
+		// Sign-extends the integer of the size.
+		shift := 64 - 8*size
+		buf.AppendInt(int64(n<<shift) >> shift)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decodeMsgpackExt(r, buf, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackUint(r, 1<<(b-0xd9))
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackString(r, buf, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackUint(r, 2<<(b-0xdc))
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackContainer(r, buf, false, n)
+	case 0xde, 0xdf:
+		n, err := readMsgpackUint(r, 2<<(b-0xde))
+		if err != nil {
+			return err
+		}
+		return decodeMsgpackContainer(r, buf, true, n)
+	default:
+		return fmt.Errorf("invalid msgpack format %#x", b)
+	}
+	return nil
+}
+
+func decodeMsgpackString(r *bufio.Reader, buf *buffer.Buffer, n uint64) error {
+	s, err := readFull(r, n)
+	if err != nil {
+		return err
+	}
+	appendJSONString(buf, string(s))
+	return nil
+}
+
+// decodeMsgpackContainer decodes the n pairs of a map or the n elements of an array.
+func decodeMsgpackContainer(r *bufio.Reader, buf *buffer.Buffer, isMap bool, n uint64) error {
+	open, close := byte('['), byte(']')
+	if isMap {
+		open, close = '{', '}'
+	}
+	buf.AppendByte(open)
+	for i := uint64(0); i < n; i++ {
+		if i != 0 {
+			buf.AppendByte(',')
+		}
+		if isMap {
+			// Keys which are not strings are converted to strings.
+			key := &buffer.Buffer{}
+			if err := decodeMsgpack(r, key); err != nil {
+				return err
+			}
+			appendJSONKey(buf, key.Bytes())
+			buf.AppendByte(':')
+		}
+		if err := decodeMsgpack(r, buf); err != nil {
+			return err
+		}
+	}
+	buf.AppendByte(close)
+	return nil
+}
+
+// decodeMsgpackExt decodes an extension of n bytes of data, the type of which is not read.
+//
+// Timestamps are appended as RFC 3339 strings, the data of other types as base64 strings.
+func decodeMsgpackExt(r *bufio.Reader, buf *buffer.Buffer, n uint64) error {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	data, err := readFull(r, n)
+	if err != nil {
+		return err
+	}
+	if int8(typ) != msgpackTimestamp {
+		appendJSONString(buf, base64.StdEncoding.EncodeToString(data))
+		return nil
+	}
+	switch n {
+	case 4:
+		appendJSONTime(buf, time.Unix(int64(binary.BigEndian.Uint32(data)), 0))
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		appendJSONTime(buf, time.Unix(int64(v&(1<<34-1)), int64(v>>34)))
+	case 12:
+		appendJSONTime(buf, time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))))
+	default:
+		return fmt.Errorf("invalid msgpack timestamp of %d bytes", n)
+	}
+	return nil
+}
+
+// readMsgpackUint reads a big-endian unsigned integer of the size.
+func readMsgpackUint(r *bufio.Reader, size int) (uint64, error) {
+	b, err := readFull(r, uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
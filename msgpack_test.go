@@ -0,0 +1,113 @@
+package zap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/buffer"
+)
+
+func Test_msgpackFormat(t *testing.T) {
+	f := msgpackFormat{}
+	tests := []struct {
+		name   string
+		want   string
+		append func(buf *buffer.Buffer)
+	}{
+		{name: "uint_fix", want: "7f", append: func(buf *buffer.Buffer) { f.appendUint(buf, 127) }},
+		{name: "uint_8", want: "cc80", append: func(buf *buffer.Buffer) { f.appendUint(buf, 128) }},
+		{name: "uint_16", want: "cd0100", append: func(buf *buffer.Buffer) { f.appendUint(buf, 256) }},
+		{name: "uint_32", want: "ce00010000", append: func(buf *buffer.Buffer) { f.appendUint(buf, 1<<16) }},
+		{name: "uint_64", want: "cf0000000100000000", append: func(buf *buffer.Buffer) { f.appendUint(buf, 1<<32) }},
+		{name: "int_fix", want: "e0", append: func(buf *buffer.Buffer) { f.appendInt(buf, -32) }},
+		{name: "int_8", want: "d0df", append: func(buf *buffer.Buffer) { f.appendInt(buf, -33) }},
+		{name: "int_16", want: "d1ff7f", append: func(buf *buffer.Buffer) { f.appendInt(buf, -129) }},
+		{name: "int_32", want: "d2ffff7fff", append: func(buf *buffer.Buffer) { f.appendInt(buf, -32769) }},
+		{name: "int_64", want: "d38000000000000000", append: func(buf *buffer.Buffer) { f.appendInt(buf, math.MinInt64) }},
+		{name: "string_fix", want: "a161", append: func(buf *buffer.Buffer) { f.appendString(buf, "a") }},
+		{name: "string_8", want: "d920" + strings.Repeat("61", 32), append: func(buf *buffer.Buffer) {
+			f.appendString(buf, strings.Repeat("a", 32))
+		}},
+		{name: "bytes", want: "c40161", append: func(buf *buffer.Buffer) { f.appendBytes(buf, []byte("a")) }},
+		{name: "float64", want: "cb3ff8000000000000", append: func(buf *buffer.Buffer) { f.appendFloat64(buf, 1.5) }},
+		{name: "float32", want: "ca3fc00000", append: func(buf *buffer.Buffer) { f.appendFloat32(buf, 1.5) }},
+		{name: "bool", want: "c3c2", append: func(buf *buffer.Buffer) { f.appendBool(buf, true); f.appendBool(buf, false) }},
+		{name: "time_32", want: "d6ff514b67b0", append: func(buf *buffer.Buffer) {
+			f.appendTime(buf, time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC))
+		}},
+		{name: "time_64", want: "d7ff00000004514b67b0", append: func(buf *buffer.Buffer) {
+			f.appendTime(buf, time.Date(2013, 3, 21, 20, 4, 0, 1, time.UTC))
+		}},
+		{name: "time_96", want: "c70cff00000001ffffffffffffffff", append: func(buf *buffer.Buffer) {
+			f.appendTime(buf, time.Unix(-1, 1))
+		}},
+		{name: "map", want: "df00000001a161c3", append: func(buf *buffer.Buffer) {
+			f.appendMapStart(buf)
+			f.appendString(buf, "a")
+			f.appendBool(buf, true)
+			f.appendMapEnd(buf, 0, 1)
+		}},
+		{name: "array_offset", want: "c0dd0000000201c3", append: func(buf *buffer.Buffer) {
+			buf.AppendByte(0xc0)
+			f.appendArrayStart(buf)
+			f.appendInt(buf, 1)
+			f.appendBool(buf, true)
+			f.appendArrayEnd(buf, 1, 2)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &buffer.Buffer{}
+			tt.append(buf)
+			assert.Equal(t, tt.want, hex.EncodeToString(buf.Bytes()))
+		})
+	}
+}
+
+func Test_decodeMsgpack(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{name: "int_fix", in: "ff", want: "-1"},
+		{name: "int_16", in: "d1ff7f", want: "-129"},
+		{name: "null", in: "c0", want: "null"},
+		{name: "fixmap", in: "82a1610101c2", want: `{"a":1,"1":false}`},
+		{name: "fixarray", in: "920102", want: "[1,2]"},
+		{name: "array_16", in: "dc000101", want: "[1]"},
+		{name: "map_16", in: "de0001a16101", want: `{"a":1}`},
+		{name: "bin_16", in: "c500016161", want: `"YQ=="`},
+		{name: "float32_inf", in: "ca7f800000", want: `"+Inf"`},
+		{name: "time_32", in: "d6ff514b67b0", want: `"2013-03-21T20:04:00Z"`},
+		{name: "time_64", in: "d7ff00000004514b67b0", want: `"2013-03-21T20:04:00.000000001Z"`},
+		{name: "time_96", in: "c70cff00000001ffffffffffffffff", want: `"1969-12-31T23:59:59.000000001Z"`},
+		{name: "other_ext", in: "d40161", want: `"YQ=="`},
+		{name: "invalid_timestamp", in: "d4ff61", err: "invalid msgpack timestamp of 1 bytes"},
+		{name: "invalid_format", in: "c1", err: "invalid msgpack format 0xc1"},
+		{name: "truncated", in: "a261", err: "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := hex.DecodeString(tt.in)
+			if !assert.Nil(t, err) {
+				return
+			}
+			buf := &buffer.Buffer{}
+			err = decodeMsgpack(bufio.NewReader(bytes.NewReader(in)), buf)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
@@ -0,0 +1,583 @@
+package zap
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+func init() {
+	registerSink("tcp", newNetworkSink)
+	registerSink("udp", newNetworkSink)
+}
+
+const (
+	networkSyncTimeout  = time.Second
+	networkWriteTimeout = 5 * time.Second
+)
+
+var errNetworkSinkClosed = errors.New("network sink closed")
+
+// NetworkStats are counters of a network output.
+type NetworkStats struct {
+	// Written is the number of entries written to the peer.
+	Written uint64
+	// Spilled is the number of entries spilled to disk as the memory buffer was full.
+	Spilled uint64
+	// Dropped is the number of entries dropped as the buffers were full, or the output was closed.
+	Dropped uint64
+	// Reconnects is the number of connections made after the first one, by tcp and udp outputs.
+	Reconnects uint64
+	// Retries is the number of requests retried, by http and https outputs.
+	Retries uint64
+}
+
+type networkCounters struct {
+	written    atomic.Uint64
+	spilled    atomic.Uint64
+	dropped    atomic.Uint64
+	reconnects atomic.Uint64
+	retries    atomic.Uint64
+}
+
+var networkCountersByPath sync.Map
+
+func networkCountersOf(path string) *networkCounters {
+	counters, _ := networkCountersByPath.LoadOrStore(path, &networkCounters{})
+	return counters.(*networkCounters)
+}
+
+// NetworkOutputStats returns counters of tcp, udp, http and https outputs by their output paths,
+// with passwords redacted.
+//
+// Counters accumulate since the outputs of the paths are first opened, across SwitchOptions.
+func NetworkOutputStats() map[string]NetworkStats {
+	stats := map[string]NetworkStats{}
+	networkCountersByPath.Range(func(key, value any) bool {
+		counters := value.(*networkCounters)
+		stats[key.(string)] = NetworkStats{
+			Written:    counters.written.Load(),
+			Spilled:    counters.spilled.Load(),
+			Dropped:    counters.dropped.Load(),
+			Reconnects: counters.reconnects.Load(),
+			Retries:    counters.retries.Load(),
+		}
+		return true
+	})
+	return stats
+}
+
+// networkSink ships entries to a log agent, e.g. Fluent Bit or Vector, over TCP or UDP.
+//
+// The output path is like:
+//
+//	tcp://127.0.0.1:5170?framing=length&spill_dir=/var/spool/demo
+//	udp://127.0.0.1:5170
+//
+// Query parameters:
+//   - framing: "newline", "length" or "null". "newline" as default. With "length", each entry is prefixed by
+//     its length as a 4 bytes big-endian integer, without the line ending. With "null", each entry is
+//     terminated by a null byte instead of the line ending. UDP datagrams are not framed.
+//   - tls: "true" to connect with TLS, only for TCP.
+//   - tls_server_name: server name to verify, the host of the address as default.
+//   - tls_ca: PEM file of the CAs to verify the server with, the system CAs as default.
+//   - tls_skip_verify: "true" to skip verifying the server.
+//   - buffer: number of entries buffered in memory while disconnected. 1024 as default.
+//   - spill_dir: directory to spill entries to when the memory buffer is full. Entries are dropped without it.
+//   - spill_max_bytes: size limit of the spill file. 64MiB as default.
+//   - backoff_min, backoff_max: bounds of the exponential backoff between reconnections. 100ms and 30s as default.
+//   - dial_timeout: timeout of connecting. 5s as default.
+//
+// Entries are written in the background, so that logging does not block on the network.
+// The sink opens even if the peer is not reachable yet. Spilled entries are written first after
+// reconnected, so entries may be out of order during outages.
+// Closing the sink writes the buffered entries if connected, spills or drops them otherwise.
+type networkSink struct {
+	network    string
+	address    string
+	path       string
+	dial       func() (net.Conn, error)
+	framing    string
+	backoffMin time.Duration
+	backoffMax time.Duration
+	queue      chan []byte
+	spill      *spillFile
+	counters   *networkCounters
+	// pending is the number of entries accepted but neither written, spilled nor dropped.
+	pending atomic.Int64
+	// mu guards enqueuing against closing, so that entries enqueued are always drained by run.
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{}
+	done    chan struct{}
+
+	// Owned by the run goroutine.
+	conn      net.Conn
+	connected bool
+	backoff   time.Duration
+}
+
+func newNetworkSink(u *url.URL, _ *Options) (zap.Sink, error) {
+	sink, err := openNetworkSink(u, u.Scheme, u.Query().Get("framing"))
+	if err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// openNetworkSink opens a networkSink of the output path, connecting over the network with the framing.
+func openNetworkSink(u *url.URL, network, framing string) (*networkSink, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing address of %s output", u.Scheme)
+	}
+	query := u.Query()
+	s := &networkSink{
+		network:  network,
+		address:  u.Host,
+		path:     u.Redacted(),
+		counters: networkCountersOf(u.Redacted()),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	switch framing {
+	case "", "newline":
+		s.framing = "newline"
+	case "length", "null":
+		s.framing = framing
+	default:
+		return nil, fmt.Errorf("unknown framing: %q", framing)
+	}
+	size, err := queryValue(query, "buffer", 1024, strconv.Atoi)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("invalid buffer: %d", size)
+	}
+	if s.backoffMin, err = queryValue(query, "backoff_min", 100*time.Millisecond, time.ParseDuration); err != nil {
+		return nil, err
+	}
+	if s.backoffMax, err = queryValue(query, "backoff_max", 30*time.Second, time.ParseDuration); err != nil {
+		return nil, err
+	}
+	if s.backoffMin <= 0 || s.backoffMax < s.backoffMin {
+		return nil, fmt.Errorf("invalid backoff: %v to %v", s.backoffMin, s.backoffMax)
+	}
+	dialTimeout, err := queryValue(query, "dial_timeout", 5*time.Second, time.ParseDuration)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	s.dial = func() (net.Conn, error) {
+		return dialer.Dial(s.network, s.address)
+	}
+	useTLS, err := queryValue(query, "tls", false, strconv.ParseBool)
+	if err != nil {
+		return nil, err
+	}
+	if useTLS {
+		if s.network != "tcp" {
+			return nil, fmt.Errorf("tls is not supported by %s output", s.network)
+		}
+		config, err := networkTLSConfig(query, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		s.dial = func() (net.Conn, error) {
+			return tls.DialWithDialer(dialer, s.network, s.address, config)
+		}
+	}
+	if dir := query.Get("spill_dir"); dir != "" {
+		maxBytes, err := queryValue(query, "spill_max_bytes", int64(64<<20), func(v string) (int64, error) {
+			return strconv.ParseInt(v, 10, 64)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if s.spill, err = newSpillFile(dir, u.Scheme+"_"+s.address, maxBytes); err != nil {
+			return nil, err
+		}
+	}
+	s.queue = make(chan []byte, size)
+	s.backoff = s.backoffMin
+	go s.run()
+	return s, nil
+}
+
+// queryValue parses the query parameter of the key, or returns the default value if absent.
+func queryValue[T any](query url.Values, key string, def T, parse func(string) (T, error)) (T, error) {
+	v := query.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	res, err := parse(v)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s: %q", key, v)
+	}
+	return res, nil
+}
+
+func networkTLSConfig(query url.Values, host string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: query.Get("tls_server_name")}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	skip, err := queryValue(query, "tls_skip_verify", false, strconv.ParseBool)
+	if err != nil {
+		return nil, err
+	}
+	config.InsecureSkipVerify = skip
+	if ca := query.Get("tls_ca"); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in tls_ca: %q", ca)
+		}
+	}
+	return config, nil
+}
+
+// Write buffers a copy of the entry, spilling or dropping it if the memory buffer is full.
+func (s *networkSink) Write(p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.counters.dropped.Inc()
+		return 0, errNetworkSinkClosed
+	}
+	entry := make([]byte, len(p))
+	copy(entry, p)
+	s.pending.Inc()
+	select {
+	case s.queue <- entry:
+	default:
+		s.overflow(entry)
+	}
+	return len(p), nil
+}
+
+// overflow spills the pending entry if possible, drops it otherwise.
+func (s *networkSink) overflow(entry []byte) {
+	if s.spill != nil && s.spill.append(entry) == nil {
+		s.counters.spilled.Inc()
+	} else {
+		s.counters.dropped.Inc()
+	}
+	s.pending.Dec()
+}
+
+// Sync waits for the buffered entries to be written, for at most a second.
+func (s *networkSink) Sync() error {
+	deadline := time.Now().Add(networkSyncTimeout)
+	for s.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d entries not yet written to %s", s.pending.Load(), s.path)
+		}
+		select {
+		case <-s.done:
+			return errNetworkSinkClosed
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+func (s *networkSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.closing)
+	}
+	s.mu.Unlock()
+	<-s.done
+	if s.spill != nil {
+		return s.spill.close()
+	}
+	return nil
+}
+
+func (s *networkSink) run() {
+	defer close(s.done)
+	for {
+		select {
+		case entry := <-s.queue:
+			if !s.deliver(entry) {
+				s.overflow(entry)
+			}
+		case <-s.closing:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush writes the buffered entries if connected, spills or drops them otherwise.
+func (s *networkSink) flush() {
+	for {
+		select {
+		case entry := <-s.queue:
+			if s.conn == nil || s.write(entry) != nil {
+				s.overflow(entry)
+				continue
+			}
+			s.counters.written.Inc()
+			s.pending.Dec()
+		default:
+			if s.conn != nil {
+				_ = s.conn.Close()
+				s.conn = nil
+			}
+			return
+		}
+	}
+}
+
+// deliver writes the entry, reconnecting with exponential backoff until written or the sink is closing.
+func (s *networkSink) deliver(entry []byte) bool {
+	for {
+		if s.conn == nil && !s.connect() {
+			select {
+			case <-s.closing:
+				return false
+			case <-time.After(s.backoff):
+			}
+			s.backoff *= 2
+			if s.backoff > s.backoffMax {
+				s.backoff = s.backoffMax
+			}
+			continue
+		}
+		if s.write(entry) == nil {
+			s.counters.written.Inc()
+			s.pending.Dec()
+			return true
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// connect dials the peer, then writes the spilled entries.
+func (s *networkSink) connect() bool {
+	conn, err := s.dial()
+	if err != nil {
+		return false
+	}
+	if s.connected {
+		s.counters.reconnects.Inc()
+	}
+	s.connected = true
+	s.conn = conn
+	s.backoff = s.backoffMin
+	if s.spill == nil {
+		return true
+	}
+	err = s.spill.replay(func(entry []byte) error {
+		if err := s.write(entry); err != nil {
+			return err
+		}
+		s.counters.written.Inc()
+		return nil
+	})
+	if err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return false
+	}
+	return true
+}
+
+func (s *networkSink) write(entry []byte) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(networkWriteTimeout))
+	_, err := s.conn.Write(s.frame(entry))
+	return err
+}
+
+// frame frames the entry for stream transports.
+func (s *networkSink) frame(entry []byte) []byte {
+	if s.network == "udp" {
+		return entry
+	}
+	switch s.framing {
+	case "length":
+		entry = bytes.TrimRight(entry, "\r\n")
+		framed := make([]byte, 4+len(entry))
+		binary.BigEndian.PutUint32(framed, uint32(len(entry)))
+		copy(framed[4:], entry)
+		return framed
+	case "null":
+		return append(bytes.TrimRight(entry, "\r\n"), 0)
+	}
+	if len(entry) == 0 || entry[len(entry)-1] != '\n' {
+		entry = append(entry, '\n')
+	}
+	return entry
+}
+
+var errSpillFileFull = errors.New("spill file full")
+
+// spillStates are states of spill files by paths, shared by sinks of different options.
+var spillStates sync.Map
+
+// spillFile buffers entries on disk, each prefixed by its length as a 4 bytes big-endian integer.
+// Entries left by previous processes are written after connected as well.
+type spillFile struct {
+	*spillState
+	path     string
+	maxBytes int64
+}
+
+// spillState is the state of a spill file, which is kept open while entries are spilled,
+// and closed when they are taken out or the sink is closed.
+type spillState struct {
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newSpillFile(dir, name string, maxBytes int64) (*spillFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == ':' || r == '/' || r == '\\' || r == '[' || r == ']' {
+			return '_'
+		}
+		return r
+	}, name)
+	path := filepath.Join(dir, name+".spill")
+	state, _ := spillStates.LoadOrStore(path, &spillState{})
+	return &spillFile{spillState: state.(*spillState), path: path, maxBytes: maxBytes}, nil
+}
+
+func (f *spillFile) append(entry []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.appendLocked([][]byte{entry}, f.maxBytes)
+}
+
+func (f *spillFile) appendLocked(entries [][]byte, maxBytes int64) error {
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		f.file, f.size = file, info.Size()
+	}
+	size := f.size
+	buf := bytes.Buffer{}
+	for _, entry := range entries {
+		size += int64(4 + len(entry))
+		if size > maxBytes {
+			return errSpillFileFull
+		}
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(entry)))
+		buf.Write(header[:])
+		buf.Write(entry)
+	}
+	n, err := f.file.Write(buf.Bytes())
+	f.size += int64(n)
+	return err
+}
+
+// close closes the spill file kept open, which is opened again by the next spilling.
+func (f *spillFile) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closeLocked()
+}
+
+func (f *spillFile) closeLocked() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// take takes the spilled entries out.
+func (f *spillFile) take() ([][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.closeLocked()
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Remove(f.path); err != nil {
+		return nil, err
+	}
+	return splitSpilled(data), nil
+}
+
+// restore spills the entries taken out back, before the entries spilled meanwhile.
+func (f *spillFile) restore(entries [][]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.closeLocked()
+	entries = entries[:len(entries):len(entries)]
+	if spilled, err := os.ReadFile(f.path); err == nil {
+		entries = append(entries, splitSpilled(spilled)...)
+	}
+	_ = os.Remove(f.path)
+	// Entries taken out were within the limit, spill them back regardless.
+	_ = f.appendLocked(entries, math.MaxInt64)
+}
+
+// replay takes the spilled entries out and writes them in order.
+// Entries not written are restored.
+func (f *spillFile) replay(write func(entry []byte) error) error {
+	entries, err := f.take()
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if err := write(entry); err != nil {
+			f.restore(entries[i:])
+			return err
+		}
+	}
+	return nil
+}
+
+// splitSpilled splits the spill file content into entries, ignoring a truncated tail.
+func splitSpilled(data []byte) [][]byte {
+	var entries [][]byte
+	for len(data) >= 4 {
+		size := binary.BigEndian.Uint32(data)
+		if uint64(len(data)-4) < uint64(size) {
+			break
+		}
+		entries = append(entries, data[4:4+size])
+		data = data[4+size:]
+	}
+	return entries
+}
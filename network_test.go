@@ -0,0 +1,378 @@
+package zap
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startNetworkSink(t *testing.T, path string) *networkSink {
+	t.Helper()
+	u, err := url.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := newNetworkSink(u, NewOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = sink.Close()
+	})
+	return sink.(*networkSink)
+}
+
+// reserveAddr returns a local address nothing listens on.
+func reserveAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+func acceptLines(t *testing.T, ln net.Listener, n int) []string {
+	t.Helper()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner := bufio.NewScanner(conn)
+	var lines []string
+	for len(lines) < n && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func Test_newNetworkSink_error(t *testing.T) {
+	for _, path := range []string{
+		"tcp://",
+		"tcp://127.0.0.1:1?framing=foo",
+		"tcp://127.0.0.1:1?buffer=foo",
+		"tcp://127.0.0.1:1?buffer=-1",
+		"tcp://127.0.0.1:1?backoff_min=foo",
+		"tcp://127.0.0.1:1?backoff_max=foo",
+		"tcp://127.0.0.1:1?backoff_min=1s&backoff_max=1ms",
+		"tcp://127.0.0.1:1?dial_timeout=foo",
+		"tcp://127.0.0.1:1?tls=foo",
+		"udp://127.0.0.1:1?tls=true",
+		"tcp://127.0.0.1:1?tls=true&tls_skip_verify=foo",
+		"tcp://127.0.0.1:1?tls=true&tls_ca=/not/exists.pem",
+		"tcp://127.0.0.1:1?spill_dir=/tmp&spill_max_bytes=foo",
+	} {
+		t.Run(path, func(t *testing.T) {
+			u, err := url.Parse(path)
+			assert.Nil(t, err)
+			_, err = newNetworkSink(u, NewOptions())
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestNetworkSink_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	path := "tcp://" + ln.Addr().String()
+	sink := startNetworkSink(t, path)
+	_, _ = sink.Write([]byte(`{"msg":"a"}` + "\n"))
+	_, _ = sink.Write([]byte(`{"msg":"b"}`))
+	assert.Equal(t, []string{`{"msg":"a"}`, `{"msg":"b"}`}, acceptLines(t, ln, 2))
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, uint64(2), NetworkOutputStats()[path].Written)
+}
+
+func TestNetworkSink_length(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	sink := startNetworkSink(t, "tcp://"+ln.Addr().String()+"?framing=length")
+	_, _ = sink.Write([]byte("abc\n"))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	frame := make([]byte, 7)
+	_, err = io.ReadFull(conn, frame)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), binary.BigEndian.Uint32(frame))
+	assert.Equal(t, "abc", string(frame[4:]))
+}
+
+func TestNetworkSink_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	sink := startNetworkSink(t, "udp://"+conn.LocalAddr().String())
+	_, _ = sink.Write([]byte("abc\n"))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64)
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc\n", string(buf[:n]))
+}
+
+func TestNetworkSink_reconnect(t *testing.T) {
+	addr := reserveAddr(t)
+	path := "tcp://" + addr + "?backoff_min=10ms&backoff_max=20ms"
+	sink := startNetworkSink(t, path)
+	for _, msg := range []string{"a", "b", "c"} {
+		_, _ = sink.Write([]byte(msg + "\n"))
+	}
+	// Not connected yet.
+	assert.NotNil(t, sink.Sync())
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	assert.Equal(t, []string{"a", "b", "c"}, acceptLines(t, ln, 3))
+	assert.Nil(t, sink.Sync())
+
+	// Peer restarted.
+	_ = ln.Close()
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for i := 0; i < 10 && sink.counters.reconnects.Load() == 0; i++ {
+			_, _ = sink.Write([]byte("d\n"))
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	lines := acceptLines(t, ln, 1)
+	assert.Equal(t, []string{"d"}, lines)
+	assert.Equal(t, uint64(1), NetworkOutputStats()[path].Reconnects)
+}
+
+func TestNetworkSink_spill(t *testing.T) {
+	addr := reserveAddr(t)
+	dir := t.TempDir()
+	path := "tcp://" + addr + "?buffer=1&backoff_min=10ms&backoff_max=20ms&spill_dir=" + dir
+	sink := startNetworkSink(t, path)
+	_, _ = sink.Write([]byte("a\n"))
+	// Wait for the run goroutine to take the first entry.
+	for len(sink.queue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, msg := range []string{"b", "c", "d"} {
+		_, _ = sink.Write([]byte(msg + "\n"))
+	}
+	assert.Equal(t, uint64(2), NetworkOutputStats()[path].Spilled)
+	_, err := os.Stat(spillPath(dir, addr))
+	assert.Nil(t, err)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	// Spilled entries first.
+	assert.Equal(t, []string{"c", "d", "a", "b"}, acceptLines(t, ln, 4))
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, uint64(4), NetworkOutputStats()[path].Written)
+}
+
+func TestNetworkSink_drop(t *testing.T) {
+	path := "tcp://" + reserveAddr(t) + "?buffer=1&backoff_min=10ms&backoff_max=20ms"
+	sink := startNetworkSink(t, path)
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		_, _ = sink.Write([]byte(msg + "\n"))
+	}
+	assert.Nil(t, sink.Close())
+	_, err := sink.Write([]byte("e\n"))
+	assert.Equal(t, errNetworkSinkClosed, err)
+	assert.Nil(t, sink.Sync())
+	stats := NetworkOutputStats()[path]
+	assert.Equal(t, uint64(5), stats.Dropped)
+	assert.Equal(t, uint64(0), stats.Written)
+	assert.Equal(t, int64(0), sink.pending.Load())
+}
+
+func TestNetworkSink_closeConcurrently(t *testing.T) {
+	path := "tcp://" + reserveAddr(t) + "?buffer=1000&backoff_min=10ms&backoff_max=20ms"
+	sink := startNetworkSink(t, path)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = sink.Write([]byte("a\n"))
+			}
+		}()
+	}
+	assert.Nil(t, sink.Close())
+	wg.Wait()
+	// every entry is either dropped when writing, or drained when closing.
+	assert.Equal(t, int64(0), sink.pending.Load())
+	assert.Equal(t, uint64(400), NetworkOutputStats()[path].Dropped)
+}
+
+func TestNetworkSink_tls(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	sink := startNetworkSink(t, "tcp://"+ln.Addr().String()+"?tls=true&tls_skip_verify=true")
+	_, _ = sink.Write([]byte("abc\n"))
+	assert.Equal(t, []string{"abc"}, acceptLines(t, ln, 1))
+}
+
+func TestNetworkSink_outputPaths(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	factory := NewFactory(NewOptions(OutputPaths("tcp://" + ln.Addr().String()))).(*zapFactory)
+	factory.Logger("foo").Info("abc")
+	factory.Logger("bar").Info("def")
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	// Loggers of all names share one connection.
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Contains(t, line, `"msg":"abc"`)
+	line, err = reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Contains(t, line, `"msg":"def"`)
+
+	// Closed when options switched.
+	factory.SwitchOptions(NewOptions())
+	_, err = reader.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_spillFile(t *testing.T) {
+	spill, err := newSpillFile(t.TempDir(), "tcp_[::1]:5170", 20)
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp____1__5170.spill", filepath.Base(spill.path))
+	assert.Nil(t, spill.replay(func(entry []byte) error {
+		t.Errorf("unexpected entry: %q", entry)
+		return nil
+	}))
+	assert.Nil(t, spill.append([]byte("abcdef")))
+	// Kept open while spilling.
+	file := spill.file
+	assert.NotNil(t, file)
+	assert.Nil(t, spill.append([]byte("ghijkl")))
+	assert.Same(t, file, spill.file)
+	assert.Equal(t, errSpillFileFull, spill.append([]byte("mno")))
+
+	// Entries not written are spilled back before the entries spilled meanwhile.
+	var written []string
+	failure := errors.New("failure")
+	err = spill.replay(func(entry []byte) error {
+		if string(entry) == "ghijkl" {
+			assert.Nil(t, spill.append([]byte("mno")))
+			return failure
+		}
+		written = append(written, string(entry))
+		return nil
+	})
+	assert.Same(t, failure, err)
+	assert.Equal(t, []string{"abcdef"}, written)
+	written = nil
+	assert.Nil(t, spill.replay(func(entry []byte) error {
+		written = append(written, string(entry))
+		return nil
+	}))
+	assert.Equal(t, []string{"ghijkl", "mno"}, written)
+	assert.Nil(t, spill.file)
+
+	// Closed by closing sinks, and opened again by the next spilling.
+	assert.Nil(t, spill.append([]byte("pqr")))
+	assert.Nil(t, spill.close())
+	assert.Nil(t, spill.file)
+	assert.Nil(t, spill.append([]byte("stu")))
+	written = nil
+	assert.Nil(t, spill.replay(func(entry []byte) error {
+		written = append(written, string(entry))
+		return nil
+	}))
+	assert.Equal(t, []string{"pqr", "stu"}, written)
+}
+
+func Test_splitSpilled(t *testing.T) {
+	assert.Nil(t, splitSpilled(nil))
+	assert.Equal(t, [][]byte{[]byte("ab")}, splitSpilled([]byte{0, 0, 0, 2, 'a', 'b', 0, 0, 0, 3, 'c'}))
+}
+
+func spillPath(dir, addr string) string {
+	spill, _ := newSpillFile(dir, "tcp_"+addr, 0)
+	return spill.path
+}
@@ -1,7 +1,9 @@
 package zap
 
 import (
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/yimi-go/logging"
 	"go.uber.org/zap"
@@ -21,6 +23,8 @@ type FieldKeys struct {
 	Message string `json:"message,omitempty"    yaml:"message,omitempty"`
 	// Stacktrace is log stacktrace field name. "stacktrace" as default.
 	Stacktrace string `json:"stacktrace,omitempty" yaml:"stacktrace,omitempty"`
+	// Function is log caller function field name. Omitted as default.
+	Function string `json:"function,omitempty"   yaml:"function,omitempty"`
 }
 
 // Defaulted returns a new FieldKeys filling blank items with default values.
@@ -57,6 +61,7 @@ func (f FieldKeys) Defaulted() FieldKeys {
 	if len(stacktrace) != 0 {
 		res.Stacktrace = stacktrace
 	}
+	res.Function = strings.TrimSpace(f.Function)
 	return res
 }
 
@@ -66,8 +71,30 @@ type Options struct {
 	Levels map[string]logging.Level `json:"levels,omitempty" yaml:"levels,omitempty,flow"`
 	// AddCallerSkipAdjusts is the adjustment for adjusting caller skips of caller annotation of specific logger.
 	AddCallerSkipAdjusts map[string]int `json:"add_caller_skip_adjusts,omitempty" yaml:"add_caller_skip_adjusts,omitempty"`
+	// Errors configures how error fields are rendered.
+	Errors ErrorOptions `json:"errors,omitempty" yaml:"errors,omitempty"`
+	// HTTP configures http and https outputs.
+	HTTP HTTPOptions `json:"http,omitempty" yaml:"http,omitempty"`
+	// Dev configures the dev encoding.
+	Dev DevOptions `json:"dev,omitempty" yaml:"dev,omitempty"`
+	// RateLimit configures rate limiting of similar entries.
+	RateLimit RateLimitOptions `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	// Hooks are the names of hooks registered by RegisterHook, which are called with entries written.
+	Hooks []string `json:"hooks,omitempty" yaml:"hooks,omitempty,flow"`
+	// RingBuffer configures buffering of entries below the levels of loggers, written when something goes wrong.
+	RingBuffer RingBufferOptions `json:"ring_buffer,omitempty" yaml:"ring_buffer,omitempty"`
 	// FieldKeys is names of fixed log globalFields.
 	FieldKeys FieldKeys `json:"field_keys,omitempty" yaml:"field_keys,omitempty"`
+	// Schema is the preset shape of entries for a log ingestion system, overriding FieldKeys.
+	// "ecs" for Elastic Common Schema, "gcp" for Google Cloud Logging. None as default.
+	Schema string `json:"schema,omitempty" yaml:"schema,omitempty"`
+	// GCPProject is the Google Cloud project id, to write trace ids as resource names with "gcp" Schema.
+	// The GOOGLE_CLOUD_PROJECT environment variable as default.
+	GCPProject string `json:"gcp_project,omitempty" yaml:"gcp_project,omitempty"`
+	// Encoding is the log entry encoding, one of "json", "console", "dev", "logfmt", "gelf", "otlp", "cbor" or "msgpack",
+	// or an encoding registered by zap.RegisterEncoder, whose entries are written to all outputs as is.
+	// "json" with Schema, "dev" in development and "json" otherwise as default.
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
 	// TimeLayout is log time field formatting layout. "2006-01-02 15:04:05.000" as default.
 	TimeLayout string `json:"time_layout,omitempty" yaml:"time_layout,omitempty"`
 	// OutputPaths is user log output paths. ["stdout"] as default.
@@ -75,6 +102,11 @@ type Options struct {
 	// ErrorOutputPaths is log's error output path. ["stderr"] as default.
 	ErrorOutputPaths []string `json:"error_output_paths,omitempty" yaml:"error_output_paths,omitempty,flow"`
 	globalFields     []logging.Field
+	httpClient       *http.Client
+	metrics          *Metrics
+	factoryHooks     *hookList
+	// disableSampling disables sampling of entries, for benchmarks measuring writes rather than sampling.
+	disableSampling bool
 	// GlobalAddCallerSkipAdjust is the global adjustment for adjusting caller skips of caller annotation.
 	// This effects all loggers.
 	GlobalAddCallerSkipAdjust int `json:"global_add_caller_skip_adjust,omitempty" yaml:"global_add_caller_skip_adjust,omitempty"`
@@ -95,6 +127,9 @@ func (o *Options) Defaulted() *Options {
 			"": logging.InfoLevel,
 		},
 		Development:               o.Development,
+		Schema:                    strings.TrimSpace(o.Schema),
+		GCPProject:                strings.TrimSpace(o.GCPProject),
+		Encoding:                  strings.TrimSpace(o.Encoding),
 		TimeLayout:                "2006-01-02 15:04:05.000",
 		DisableCaller:             o.DisableCaller,
 		DisableStacktrace:         o.DisableStacktrace,
@@ -104,6 +139,11 @@ func (o *Options) Defaulted() *Options {
 		GlobalAddCallerSkipAdjust: o.GlobalAddCallerSkipAdjust,
 		AddCallerSkipAdjusts:      map[string]int{},
 		globalFields:              o.globalFields,
+		httpClient:                o.httpClient,
+		metrics:                   o.metrics,
+		factoryHooks:              o.factoryHooks,
+		disableSampling:           o.disableSampling,
+		RingBuffer:                o.RingBuffer,
 	}
 	for name, level := range o.Levels {
 		res.Levels[name] = level
@@ -113,6 +153,10 @@ func (o *Options) Defaulted() *Options {
 		res.TimeLayout = timeLayout
 	}
 	res.FieldKeys = o.FieldKeys.Defaulted()
+	res.Errors = o.Errors.Defaulted()
+	res.HTTP = o.HTTP.Defaulted()
+	res.Dev = o.Dev.Defaulted()
+	res.RateLimit = o.RateLimit.Defaulted()
 	outputPaths := make([]string, 0, len(o.OutputPaths))
 	for _, path := range o.OutputPaths {
 		path = strings.TrimSpace(path)
@@ -136,6 +180,12 @@ func (o *Options) Defaulted() *Options {
 	for name, adj := range o.AddCallerSkipAdjusts {
 		res.AddCallerSkipAdjusts[name] = adj
 	}
+	for _, name := range o.Hooks {
+		name = strings.TrimSpace(name)
+		if len(name) != 0 {
+			res.Hooks = append(res.Hooks, name)
+		}
+	}
 	return res
 }
 
@@ -158,6 +208,31 @@ func Development(dev bool) Option {
 	}
 }
 
+// Encoding returns an Option that set log entry encoding.
+//
+// If the parameter is empty, "dev" would be used in development and "json" otherwise.
+func Encoding(encoding string) Option {
+	return func(o *Options) {
+		o.Encoding = encoding
+	}
+}
+
+// Schema returns an Option that set the preset shape of entries, e.g. "ecs" or "gcp".
+//
+// Field keys, the level and time formats are preset by the schema, overriding FieldKeys and TimeLayout.
+func Schema(schema string) Option {
+	return func(o *Options) {
+		o.Schema = schema
+	}
+}
+
+// GCPProject returns an Option that set the Google Cloud project id of trace ids written with "gcp" Schema.
+func GCPProject(project string) Option {
+	return func(o *Options) {
+		o.GCPProject = project
+	}
+}
+
 // TimeLayout returns an Option that set time field formatting layout.
 //
 // If the parameter is empty, the default value would be used, which is "2006-01-02 15:04:05.000".
@@ -221,6 +296,15 @@ func StacktraceFieldKey(key string) Option {
 	}
 }
 
+// FunctionFieldKey returns an Option that set caller function field name.
+//
+// If the parameter is empty, the caller function is omitted, which is the default.
+func FunctionFieldKey(key string) Option {
+	return func(o *Options) {
+		o.FieldKeys.Function = key
+	}
+}
+
 // DisableCaller returns an Option that set whether disable caller field.
 func DisableCaller(disable bool) Option {
 	return func(o *Options) {
@@ -242,6 +326,62 @@ func DisableLogger(disable bool) Option {
 	}
 }
 
+// StructuredErrors returns an Option that set whether render error fields as objects.
+func StructuredErrors(structured bool) Option {
+	return func(o *Options) {
+		o.Errors.Structured = structured
+	}
+}
+
+// Errors returns an Option that set how error fields are rendered.
+func Errors(errors ErrorOptions) Option {
+	return func(o *Options) {
+		o.Errors = errors
+	}
+}
+
+// HTTP returns an Option that set how http and https outputs post entries.
+func HTTP(options HTTPOptions) Option {
+	return func(o *Options) {
+		o.HTTP = options
+	}
+}
+
+// HTTPClient returns an Option that set the client of http and https outputs, http.DefaultClient as default.
+func HTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.httpClient = client
+	}
+}
+
+// Dev returns an Option that set how the dev encoding writes entries.
+func Dev(options DevOptions) Option {
+	return func(o *Options) {
+		o.Dev = options
+	}
+}
+
+// RateLimit returns an Option that set how similar entries are rate limited.
+func RateLimit(options RateLimitOptions) Option {
+	return func(o *Options) {
+		o.RateLimit = options
+	}
+}
+
+// Hooks returns an Option that set the names of hooks registered by RegisterHook to be called with entries.
+func Hooks(names ...string) Option {
+	return func(o *Options) {
+		o.Hooks = names
+	}
+}
+
+// RingBuffer returns an Option that set how entries below the levels of loggers are buffered.
+func RingBuffer(options RingBufferOptions) Option {
+	return func(o *Options) {
+		o.RingBuffer = options
+	}
+}
+
 // OutputPaths returns an Option that set user log output paths.
 //
 // If the parameters are empty, the default value would be used, which is ["stdout"].
@@ -291,20 +431,18 @@ func NewOptions(options ...Option) *Options {
 	return res
 }
 
-func (o *Options) newZapLogger(name string) *zap.Logger {
-	levelEncoder := zapcore.CapitalColorLevelEncoder
-	encoding := "console"
-	if !o.Development {
-		levelEncoder = zapcore.CapitalLevelEncoder
-		encoding = "json"
+func (o *Options) encoderConfig() zapcore.EncoderConfig {
+	levelEncoder := zapcore.CapitalLevelEncoder
+	if o.Development && o.encoding() == "console" {
+		levelEncoder = zapcore.CapitalColorLevelEncoder
 	}
-	encoderConfig := zapcore.EncoderConfig{
+	config := zapcore.EncoderConfig{
 		MessageKey:     o.FieldKeys.Message,
 		LevelKey:       o.FieldKeys.Level,
 		TimeKey:        o.FieldKeys.Time,
 		NameKey:        o.FieldKeys.Logger,
 		CallerKey:      o.FieldKeys.Caller,
-		FunctionKey:    zapcore.OmitKey,
+		FunctionKey:    o.FieldKeys.Function,
 		StacktraceKey:  o.FieldKeys.Stacktrace,
 		LineEnding:     zapcore.DefaultLineEnding,
 		EncodeLevel:    levelEncoder,
@@ -312,27 +450,27 @@ func (o *Options) newZapLogger(name string) *zap.Logger {
 		EncodeDuration: zapcore.MillisDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
-	loggerConfig := &zap.Config{
-		Level:             zap.NewAtomicLevelAt(zapcore.DebugLevel),
-		Development:       o.Development,
-		DisableCaller:     o.DisableCaller,
-		DisableStacktrace: o.DisableStacktrace,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding:         encoding,
-		EncoderConfig:    encoderConfig,
-		OutputPaths:      o.OutputPaths,
-		ErrorOutputPaths: o.ErrorOutputPaths,
+	if s, _ := o.schema(); s != nil {
+		s.encoderConfig(&config)
 	}
+	return config
+}
 
-	l, _ := loggerConfig.Build(
+// zapLogger creates the zap logger of the name writing to the outputs.
+func (out *outputs) zapLogger(name string) *zap.Logger {
+	o := out.options
+	opts := []zap.Option{
+		zap.ErrorOutput(out.errSink),
 		zap.AddStacktrace(zapcore.ErrorLevel),
-		zap.AddCallerSkip(1),
-		zap.AddCallerSkip(o.GlobalAddCallerSkipAdjust),
-		zap.AddCallerSkip(o.AddCallerSkipAdjusts[name]),
-	)
+		zap.AddCallerSkip(1 + o.GlobalAddCallerSkipAdjust + o.AddCallerSkipAdjusts[name]),
+	}
+	if o.Development {
+		opts = append(opts, zap.Development())
+	}
+	if !o.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+	l := zap.New(o.namedCore(out.core, name, out.closing), opts...)
 	if !o.DisableLogger {
 		name = strings.TrimSpace(name)
 		l = l.Named(name)
@@ -340,6 +478,79 @@ func (o *Options) newZapLogger(name string) *zap.Logger {
 	return l
 }
 
+// wrapCore wraps the core writing entries to outputs, returning the function to call before the outputs are closed.
+func (o *Options) wrapCore(core zapcore.Core) (zapcore.Core, func()) {
+	stop := func() {}
+	if o.metrics != nil {
+		core = &metricsCore{Core: core, metrics: o.metrics}
+	}
+	if o.Errors.Structured {
+		core = newErrorCore(core, o.Errors)
+	}
+	if s, _ := o.schema(); s != nil {
+		core = s.wrapCore(core, o)
+	}
+	if len(o.RateLimit.Limits) != 0 {
+		c := newRateLimitCore(core, o.RateLimit.Defaulted(), o.metrics)
+		core, stop = c, c.limiter.stop
+	}
+	return core, stop
+}
+
+// loggerName is the interface value of the zap field adding the logger name to the cores of outputs,
+// as entries do not carry the names with DisableLogger.
+type loggerName string
+
+func loggerNameField(name string) zapcore.Field {
+	return zapcore.Field{Type: zapcore.SkipType, Interface: loggerName(name)}
+}
+
+// fieldsLoggerName returns the logger name added by the fields, and whether there is one.
+func fieldsLoggerName(fields []zapcore.Field) (string, bool) {
+	for _, f := range fields {
+		if name, ok := f.Interface.(loggerName); ok && f.Type == zapcore.SkipType {
+			return string(name), true
+		}
+	}
+	return "", false
+}
+
+// namedCore wraps the core of outputs for the zap logger of the name,
+// so that loggers of different names are sampled separately.
+// The name is added to the core as a field, which cores of outputs take the logger name from.
+// Hooks are called outside sampling and RateLimit, with every entry matching them,
+// and inside the ring buffer, with entries when they are written rather than buffered.
+// Buffered entries are dropped once the outputs, whose closing state is given, are closed.
+func (o *Options) namedCore(core zapcore.Core, name string, closing *closingState) zapcore.Core {
+	name = strings.TrimSpace(name)
+	core = core.With([]zapcore.Field{loggerNameField(name)})
+	if !o.disableSampling {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100,
+			zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+				if dec&zapcore.LogDropped != 0 {
+					o.metrics.sampled(name, ent)
+				}
+			}))
+	}
+	if hooks, _ := o.hooks(); len(hooks) != 0 || o.factoryHooks != nil {
+		core = &hookCore{Core: core, hooks: hooks, factory: o.factoryHooks, name: name}
+	}
+	if o.RingBuffer.Size > 0 {
+		core = newRingCore(core, o, closing).named(name)
+	}
+	return core
+}
+
+func (o *Options) encoding() string {
+	if len(o.Encoding) != 0 {
+		return o.Encoding
+	}
+	if o.Development && len(o.Schema) == 0 {
+		return "dev"
+	}
+	return "json"
+}
+
 func (o *Options) level(name string) logging.Level {
 	name = strings.TrimSpace(name)
 	if level, ok := o.Levels[name]; ok {
@@ -3,12 +3,15 @@ package zap
 import (
 	"bufio"
 	"encoding/json"
+	"net/url"
 	"os"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestLevels(t *testing.T) {
@@ -73,6 +76,14 @@ func TestStacktraceFieldKey(t *testing.T) {
 	assert.Equal(t, key, o.FieldKeys.Stacktrace)
 }
 
+func TestFunctionFieldKey(t *testing.T) {
+	o := &Options{}
+	key := "aaa"
+	FunctionFieldKey(key)(o)
+	assert.Equal(t, key, o.FieldKeys.Function)
+	assert.Equal(t, "", FieldKeys{Function: " "}.Defaulted().Function)
+}
+
 func TestDisableCaller(t *testing.T) {
 	o := &Options{}
 	DisableCaller(true)(o)
@@ -306,6 +317,20 @@ func TestOptions_level(t *testing.T) {
 	}
 }
 
+// newZapLogger opens the outputs of the options, which are closed when the test finishes,
+// and creates the zap logger of the name.
+func newZapLogger(t *testing.T, options *Options, name string) *zap.Logger {
+	t.Helper()
+	out, err := options.openOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = out.close()
+	})
+	return out.zapLogger(name)
+}
+
 func TestOptions_newZapLogger(t *testing.T) {
 	t.Run("prod", func(t *testing.T) {
 		stdout := os.Stdout
@@ -324,7 +349,7 @@ func TestOptions_newZapLogger(t *testing.T) {
 			options.Development = false
 		})
 		assert.Equal(t, logging.InfoLevel, options.level(""))
-		logger := options.newZapLogger("foo")
+		logger := newZapLogger(t, options, "foo")
 		logger.Info("abc")
 		_ = w.Close()
 		scanner := bufio.NewScanner(r)
@@ -353,7 +378,7 @@ func TestOptions_newZapLogger(t *testing.T) {
 			options.Development = true
 		})
 		assert.Equal(t, logging.InfoLevel, options.level(""))
-		logger := options.newZapLogger("foo")
+		logger := newZapLogger(t, options, "foo")
 		logger.Info("abc")
 		_ = w.Close()
 		scanner := bufio.NewScanner(r)
@@ -382,7 +407,7 @@ func TestOptions_newZapLogger(t *testing.T) {
 			options.Development = false
 		})
 		assert.Equal(t, logging.InfoLevel, options.level(""))
-		logger := options.newZapLogger("")
+		logger := newZapLogger(t, options, "")
 		logger.Info("abc")
 		_ = w.Close()
 		scanner := bufio.NewScanner(r)
@@ -412,7 +437,7 @@ func TestOptions_newZapLogger(t *testing.T) {
 			options.DisableLogger = true
 		})
 		assert.Equal(t, logging.InfoLevel, options.level(""))
-		logger := options.newZapLogger("foo")
+		logger := newZapLogger(t, options, "foo")
 		logger.Info("abc")
 		_ = w.Close()
 		scanner := bufio.NewScanner(r)
@@ -425,3 +450,112 @@ func TestOptions_newZapLogger(t *testing.T) {
 		assert.Empty(t, m["logger"])
 	})
 }
+
+func TestEncoding(t *testing.T) {
+	o := &Options{}
+	Encoding("logfmt")(o)
+	assert.Equal(t, "logfmt", o.Encoding)
+}
+
+func TestSchema(t *testing.T) {
+	o := &Options{}
+	Schema("ecs")(o)
+	assert.Equal(t, "ecs", o.Schema)
+}
+
+func TestDev(t *testing.T) {
+	o := &Options{}
+	Dev(DevOptions{Color: "never"})(o)
+	assert.Equal(t, DevOptions{Color: "never"}, o.Dev)
+}
+
+func TestRateLimit(t *testing.T) {
+	o := &Options{}
+	RateLimit(RateLimitOptions{Limits: map[string]TokenBucket{"": {Rate: 1}}})(o)
+	assert.Equal(t, RateLimitOptions{Limits: map[string]TokenBucket{"": {Rate: 1}}}, o.RateLimit)
+}
+
+func TestHooks(t *testing.T) {
+	o := &Options{}
+	Hooks("a", "b")(o)
+	assert.Equal(t, []string{"a", "b"}, o.Hooks)
+	assert.Equal(t, []string{"a"}, (&Options{Hooks: []string{" a ", " "}}).Defaulted().Hooks)
+}
+
+func TestRingBuffer(t *testing.T) {
+	o := &Options{}
+	RingBuffer(RingBufferOptions{Size: 10})(o)
+	assert.Equal(t, 10, o.RingBuffer.Size)
+	assert.Equal(t, 10, o.Defaulted().RingBuffer.Size)
+}
+
+func TestGCPProject(t *testing.T) {
+	o := &Options{}
+	GCPProject("my-project")(o)
+	assert.Equal(t, "my-project", o.GCPProject)
+}
+
+func TestOptions_encoding(t *testing.T) {
+	assert.Equal(t, "json", (&Options{}).encoding())
+	assert.Equal(t, "dev", (&Options{Development: true}).encoding())
+	assert.Equal(t, "console", (&Options{Development: true, Encoding: "console"}).encoding())
+	assert.Equal(t, "json", (&Options{Development: true, Schema: "ecs"}).encoding())
+	assert.Equal(t, "logfmt", (&Options{Development: true, Encoding: "logfmt"}).encoding())
+	assert.Equal(t, "logfmt", (&Options{Encoding: " logfmt "}).Defaulted().encoding())
+}
+
+func TestOptions_openOutputs_error(t *testing.T) {
+	for _, options := range []*Options{
+		NewOptions(Encoding("unknown")),
+		NewOptions(OutputPaths("unknown-scheme://foo")),
+		NewOptions(ErrorOutputPaths("unknown-scheme://foo")),
+	} {
+		_, err := options.openOutputs()
+		assert.NotNil(t, err)
+	}
+}
+
+func TestOptions_openOutputs_zapEncoding(t *testing.T) {
+	// Encodings registered to zap only are supported as well.
+	assert.Nil(t, zap.RegisterEncoder("test-zap-encoding", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewJSONEncoder(config), nil
+	}))
+	sink := &tEntrySink{}
+	registerSink("test-zap-encoding", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	options := NewOptions(OutputPaths("test-zap-encoding://"), Encoding("test-zap-encoding"), DisableCaller(true))
+	newZapLogger(t, options, "foo").Info("abc")
+	assert.Len(t, sink.lines, 1)
+	m := map[string]any{}
+	assert.Nil(t, json.Unmarshal([]byte(sink.lines[0]), &m))
+	assert.Equal(t, "abc", m["msg"])
+	assert.Equal(t, "foo", m["logger"])
+}
+
+func TestOptions_zapLogger_stacktrace(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-stack", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	newZapLogger(t, NewOptions(OutputPaths("test-stack://")), "foo").Error("abc")
+	newZapLogger(t, NewOptions(OutputPaths("test-stack://"), DisableStacktrace(true)), "foo").Error("abc")
+	assert.Len(t, sink.entries, 2)
+	// Stacktraces of error level logs are always written, as they have been.
+	assert.NotEmpty(t, sink.entries[0].Stack)
+	assert.NotEmpty(t, sink.entries[1].Stack)
+}
+
+func TestOptions_namedCore(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.DebugLevel}
+	o := NewOptions()
+	noisy := zap.New(o.namedCore(rec, "noisy", nil))
+	quiet := zap.New(o.namedCore(rec, "quiet", nil))
+	for i := 0; i < 200; i++ {
+		noisy.Info("m")
+	}
+	assert.Len(t, rec.messages(), 101)
+	// loggers of other names are sampled separately.
+	quiet.Info("m")
+	assert.Len(t, rec.messages(), 102)
+}
@@ -0,0 +1,640 @@
+package zap
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yimi-go/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var otlpPool = buffer.NewPool()
+
+func init() {
+	registerEncoder("otlp", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewOTLPEncoder(config), nil
+	})
+	registerSink("otlp+http", newOTLPHTTPSink)
+	registerSink("otlp+https", newOTLPHTTPSink)
+}
+
+// NewOTLPEncoder creates an encoder writing entries in the OpenTelemetry log data model, as OTLP/JSON.
+//
+// Each entry is written as an ExportLogsServiceRequest in a line, as the file exporter of OpenTelemetry Collector does.
+// The logger name is written as the name of the instrumentation scope, the caller as code.filepath, code.lineno
+// and code.function attributes, the stacktrace as exception.stacktrace attribute,
+// and the error field as exception.message and exception.type attributes.
+// Fields keyed as trace and span ids, e.g. trace_id and span_id, are written as the trace context of the record,
+// other fields are written as attributes keeping their types.
+// Without such fields, the span context of the last context.Context field, e.g. logging.Any("ctx", ctx),
+// is written as the trace context, as trace.SpanContextFromContext returns. Context fields are not written as attributes.
+// Global fields are written as attributes of the resource, rather than attributes of records.
+func NewOTLPEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return newOTLPEncoder(&config, nil, false)
+}
+
+func newOTLPEncoder(config *zapcore.EncoderConfig, resource []logging.Field, batched bool) *otlpEncoder {
+	enc := &otlpEncoder{
+		otlpObjectEncoder: newOTLPObjectEncoder(config),
+		resource:          otlpAttributes(config, resource),
+		batched:           batched,
+	}
+	enc.skip = make(map[string]bool, len(resource))
+	for _, f := range resource {
+		enc.skip[f.Key()] = true
+	}
+	return enc
+}
+
+// otlpAttributes maps the fields to attributes.
+func otlpAttributes(config *zapcore.EncoderConfig, fields []logging.Field) *otlpKVList {
+	enc := newOTLPObjectEncoder(config)
+	for _, f := range fields {
+		mapZapField(f).AddTo(enc)
+	}
+	return enc.root
+}
+
+// otlpEncoder writes entries as OTLP/JSON.
+type otlpEncoder struct {
+	*otlpObjectEncoder
+	resource *otlpKVList
+	// batched indicates whether only records are written, prefixed by the scope name and a tab,
+	// to be batched into requests by otlpHTTPSink.
+	batched bool
+}
+
+// withOptions writes global fields of the Options as attributes of the resource.
+func (enc *otlpEncoder) withOptions(o *Options) zapcore.Encoder {
+	return newOTLPEncoder(enc.EncoderConfig, o.globalFields, enc.batched)
+}
+
+func (enc *otlpEncoder) Clone() zapcore.Encoder {
+	return &otlpEncoder{otlpObjectEncoder: enc.clone(), resource: enc.resource, batched: enc.batched}
+}
+
+func (enc *otlpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	// Global fields are skipped when accumulated, fields of the entry are always written.
+	final.skip = nil
+	var attrs []otlpKeyValue
+	if ent.Caller.Defined {
+		attrs = append(attrs,
+			otlpKeyValue{key: "code.filepath", value: ent.Caller.File},
+			otlpKeyValue{key: "code.lineno", value: int64(ent.Caller.Line)},
+		)
+		if ent.Caller.Function != "" {
+			attrs = append(attrs, otlpKeyValue{key: "code.function", value: ent.Caller.Function})
+		}
+	}
+	if ent.Stack != "" {
+		attrs = append(attrs, otlpKeyValue{key: "exception.stacktrace", value: ent.Stack})
+	}
+	for _, f := range fields {
+		if ctx, ok := f.Interface.(context.Context); ok {
+			final.ctx = ctx
+			continue
+		}
+		if f.Type == zapcore.ErrorType && f.Key == "error" {
+			err, _ := f.Interface.(error)
+			attrs = append(attrs,
+				otlpKeyValue{key: "exception.message", value: errorMessage(err)},
+				otlpKeyValue{key: "exception.type", value: fmt.Sprintf("%T", err)},
+			)
+			continue
+		}
+		f.AddTo(final)
+	}
+	attrs = append(attrs, final.root.values...)
+	traceID, spanID, attrs := otlpTraceContext(attrs)
+	if final.ctx != nil {
+		if sc := trace.SpanContextFromContext(final.ctx); sc.IsValid() {
+			if traceID == "" {
+				traceID = sc.TraceID().String()
+			}
+			if spanID == "" {
+				spanID = sc.SpanID().String()
+			}
+		}
+	}
+
+	buf := otlpPool.Get()
+	if enc.batched {
+		appendJSONString(buf, ent.LoggerName)
+		buf.AppendByte('\t')
+		appendOTLPRecord(buf, ent, attrs, traceID, spanID)
+	} else {
+		buf.AppendString(`{"resourceLogs":[{"resource":{"attributes":`)
+		appendOTLPKeyValues(buf, enc.resource.values)
+		buf.AppendString(`},"scopeLogs":[{"scope":{"name":`)
+		appendJSONString(buf, ent.LoggerName)
+		buf.AppendString(`},"logRecords":[`)
+		appendOTLPRecord(buf, ent, attrs, traceID, spanID)
+		buf.AppendString(`]}]}]}`)
+	}
+	if !enc.SkipLineEnding {
+		if enc.LineEnding != "" {
+			buf.AppendString(enc.LineEnding)
+		} else {
+			buf.AppendString(zapcore.DefaultLineEnding)
+		}
+	}
+	return buf, nil
+}
+
+// otlpTraceContext extracts the trace and span ids from the attributes keyed as them, e.g. trace_id and span_id.
+// Attributes with invalid ids are kept.
+func otlpTraceContext(attrs []otlpKeyValue) (traceID, spanID string, rest []otlpKeyValue) {
+	rest = attrs[:0:0]
+	for _, kv := range attrs {
+		key := kv.key
+		if k, ok := ecsFieldKeys[key]; ok {
+			key = k
+		}
+		id, _ := kv.value.(string)
+		switch {
+		case key == "trace.id" && traceID == "" && isHexID(id, 16):
+			traceID = strings.ToLower(id)
+		case key == "span.id" && spanID == "" && isHexID(id, 8):
+			spanID = strings.ToLower(id)
+		default:
+			rest = append(rest, kv)
+		}
+	}
+	return traceID, spanID, rest
+}
+
+// isHexID reports whether the id is the hex form of a non-zero id of size bytes.
+func isHexID(id string, size int) bool {
+	if len(id) != 2*size {
+		return false
+	}
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func appendOTLPRecord(buf *buffer.Buffer, ent zapcore.Entry, attrs []otlpKeyValue, traceID, spanID string) {
+	// 0 is for unknown time.
+	ts := "0"
+	if !ent.Time.IsZero() {
+		ts = strconv.FormatInt(ent.Time.UnixNano(), 10)
+	}
+	buf.AppendString(`{"timeUnixNano":"`)
+	buf.AppendString(ts)
+	buf.AppendString(`","observedTimeUnixNano":"`)
+	buf.AppendString(ts)
+	buf.AppendString(`","severityNumber":`)
+	buf.AppendInt(int64(otlpSeverity(ent.Level)))
+	buf.AppendString(`,"severityText":"`)
+	buf.AppendString(ent.Level.CapitalString())
+	buf.AppendString(`","body":`)
+	appendOTLPValue(buf, ent.Message)
+	if len(attrs) != 0 {
+		buf.AppendString(`,"attributes":`)
+		appendOTLPKeyValues(buf, attrs)
+	}
+	if traceID != "" {
+		buf.AppendString(`,"traceId":"`)
+		buf.AppendString(traceID)
+		buf.AppendByte('"')
+	}
+	if spanID != "" {
+		buf.AppendString(`,"spanId":"`)
+		buf.AppendString(spanID)
+		buf.AppendByte('"')
+	}
+	buf.AppendByte('}')
+}
+
+// otlpSeverity maps the level to the severity number of the OpenTelemetry log data model.
+func otlpSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel, zapcore.FatalLevel:
+		return 21
+	default:
+		return 0
+	}
+}
+
+// otlpKeyValue is an attribute.
+//
+// Values are string, bool, int64, float64, []byte, []any of values, or *otlpKVList.
+type otlpKeyValue struct {
+	key   string
+	value any
+}
+
+// otlpKVList is a list of attributes, referenced by pointer so that fields can be added to open namespaces.
+type otlpKVList struct {
+	values []otlpKeyValue
+}
+
+// clone copies the list and the lists nested in it. Arrays are never changed once added, so they are shared.
+func (l *otlpKVList) clone() *otlpKVList {
+	res := &otlpKVList{values: make([]otlpKeyValue, len(l.values))}
+	for i, kv := range l.values {
+		if nested, ok := kv.value.(*otlpKVList); ok {
+			kv.value = nested.clone()
+		}
+		res.values[i] = kv
+	}
+	return res
+}
+
+func appendOTLPKeyValues(buf *buffer.Buffer, values []otlpKeyValue) {
+	buf.AppendByte('[')
+	for i, kv := range values {
+		if i != 0 {
+			buf.AppendByte(',')
+		}
+		buf.AppendString(`{"key":`)
+		appendJSONString(buf, kv.key)
+		buf.AppendString(`,"value":`)
+		appendOTLPValue(buf, kv.value)
+		buf.AppendByte('}')
+	}
+	buf.AppendByte(']')
+}
+
+// appendOTLPValue appends the value as an AnyValue of OTLP/JSON.
+func appendOTLPValue(buf *buffer.Buffer, value any) {
+	switch v := value.(type) {
+	case string:
+		buf.AppendString(`{"stringValue":`)
+		appendJSONString(buf, v)
+	case bool:
+		buf.AppendString(`{"boolValue":`)
+		buf.AppendBool(v)
+	case int64:
+		// 64-bit integers are strings in OTLP/JSON.
+		buf.AppendString(`{"intValue":"`)
+		buf.AppendInt(v)
+		buf.AppendByte('"')
+	case float64:
+		buf.AppendString(`{"doubleValue":`)
+		switch {
+		case math.IsNaN(v):
+			buf.AppendString(`"NaN"`)
+		case math.IsInf(v, 1):
+			buf.AppendString(`"Infinity"`)
+		case math.IsInf(v, -1):
+			buf.AppendString(`"-Infinity"`)
+		default:
+			buf.AppendString(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	case []byte:
+		buf.AppendString(`{"bytesValue":"`)
+		buf.AppendString(base64.StdEncoding.EncodeToString(v))
+		buf.AppendByte('"')
+	case []any:
+		buf.AppendString(`{"arrayValue":{"values":[`)
+		for i, elem := range v {
+			if i != 0 {
+				buf.AppendByte(',')
+			}
+			appendOTLPValue(buf, elem)
+		}
+		buf.AppendString(`]}`)
+	case *otlpKVList:
+		buf.AppendString(`{"kvlistValue":{"values":`)
+		appendOTLPKeyValues(buf, v.values)
+		buf.AppendByte('}')
+	default:
+		buf.AppendString(`{"stringValue":`)
+		appendJSONString(buf, fmt.Sprint(v))
+	}
+	buf.AppendByte('}')
+}
+
+// otlpUint returns the unsigned integer as int64 if it fits, as string otherwise.
+func otlpUint(v uint64) any {
+	if v > math.MaxInt64 {
+		return strconv.FormatUint(v, 10)
+	}
+	return int64(v)
+}
+
+// otlpObjectEncoder collects fields as attributes.
+type otlpObjectEncoder struct {
+	*zapcore.EncoderConfig
+	root *otlpKVList
+	// cur is the list fields are added to, the innermost open namespace or root.
+	cur *otlpKVList
+	// depth is the number of open namespaces.
+	depth int
+	// skip are the keys of fields not added to root.
+	skip map[string]bool
+	// ctx is the context of the last context field, carrying the span context.
+	// Contexts are usually Stringers, which are added by EncodeEntry, others are reflected.
+	ctx context.Context
+}
+
+func newOTLPObjectEncoder(config *zapcore.EncoderConfig) *otlpObjectEncoder {
+	root := &otlpKVList{}
+	return &otlpObjectEncoder{EncoderConfig: config, root: root, cur: root}
+}
+
+// clone copies the encoder, along with the attributes added.
+func (enc *otlpObjectEncoder) clone() *otlpObjectEncoder {
+	root := enc.root.clone()
+	cur := root
+	for i := 0; i < enc.depth; i++ {
+		// Fields are added to the open namespace, so it is always the last one.
+		cur = cur.values[len(cur.values)-1].value.(*otlpKVList)
+	}
+	return &otlpObjectEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		root:          root,
+		cur:           cur,
+		depth:         enc.depth,
+		skip:          enc.skip,
+		ctx:           enc.ctx,
+	}
+}
+
+func (enc *otlpObjectEncoder) add(key string, value any) {
+	if enc.cur == enc.root && enc.skip[key] {
+		return
+	}
+	enc.cur.values = append(enc.cur.values, otlpKeyValue{key: key, value: value})
+}
+
+func (enc *otlpObjectEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &otlpArrayEncoder{config: enc.EncoderConfig}
+	err := marshaler.MarshalLogArray(arr)
+	enc.add(key, arr.values)
+	return err
+}
+
+func (enc *otlpObjectEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	nested := newOTLPObjectEncoder(enc.EncoderConfig)
+	err := marshaler.MarshalLogObject(nested)
+	enc.add(key, nested.root)
+	return err
+}
+
+func (enc *otlpObjectEncoder) AddBinary(key string, value []byte) {
+	b := make([]byte, len(value))
+	copy(b, value)
+	enc.add(key, b)
+}
+
+func (enc *otlpObjectEncoder) AddByteString(key string, value []byte) {
+	enc.add(key, string(value))
+}
+
+func (enc *otlpObjectEncoder) AddBool(key string, value bool) {
+	enc.add(key, value)
+}
+
+func (enc *otlpObjectEncoder) AddComplex128(key string, value complex128) {
+	enc.add(key, strconv.FormatComplex(value, 'f', -1, 128))
+}
+
+func (enc *otlpObjectEncoder) AddComplex64(key string, value complex64) {
+	enc.add(key, strconv.FormatComplex(complex128(value), 'f', -1, 64))
+}
+
+func (enc *otlpObjectEncoder) AddDuration(key string, value time.Duration) {
+	arr := &otlpArrayEncoder{config: enc.EncoderConfig}
+	arr.AppendDuration(value)
+	enc.add(key, arr.values[0])
+}
+
+func (enc *otlpObjectEncoder) AddFloat64(key string, value float64) {
+	enc.add(key, value)
+}
+
+func (enc *otlpObjectEncoder) AddFloat32(key string, value float32) {
+	enc.add(key, float64(value))
+}
+
+func (enc *otlpObjectEncoder) AddInt(key string, value int)     { enc.AddInt64(key, int64(value)) }
+func (enc *otlpObjectEncoder) AddInt64(key string, value int64) { enc.add(key, value) }
+func (enc *otlpObjectEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+func (enc *otlpObjectEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+func (enc *otlpObjectEncoder) AddInt8(key string, value int8)   { enc.AddInt64(key, int64(value)) }
+
+func (enc *otlpObjectEncoder) AddString(key, value string) {
+	enc.add(key, value)
+}
+
+func (enc *otlpObjectEncoder) AddTime(key string, value time.Time) {
+	arr := &otlpArrayEncoder{config: enc.EncoderConfig}
+	arr.AppendTime(value)
+	enc.add(key, arr.values[0])
+}
+
+func (enc *otlpObjectEncoder) AddUint(key string, value uint)     { enc.AddUint64(key, uint64(value)) }
+func (enc *otlpObjectEncoder) AddUint64(key string, value uint64) { enc.add(key, otlpUint(value)) }
+func (enc *otlpObjectEncoder) AddUint32(key string, value uint32) { enc.AddUint64(key, uint64(value)) }
+func (enc *otlpObjectEncoder) AddUint16(key string, value uint16) { enc.AddUint64(key, uint64(value)) }
+func (enc *otlpObjectEncoder) AddUint8(key string, value uint8)   { enc.AddUint64(key, uint64(value)) }
+func (enc *otlpObjectEncoder) AddUintptr(key string, value uintptr) {
+	enc.AddUint64(key, uint64(value))
+}
+
+func (enc *otlpObjectEncoder) AddReflected(key string, value any) error {
+	if ctx, ok := value.(context.Context); ok {
+		enc.ctx = ctx
+		return nil
+	}
+	s, err := reflectedString(value)
+	if err != nil {
+		return err
+	}
+	enc.add(key, s)
+	return nil
+}
+
+func (enc *otlpObjectEncoder) OpenNamespace(key string) {
+	ns := &otlpKVList{}
+	enc.cur.values = append(enc.cur.values, otlpKeyValue{key: key, value: ns})
+	enc.cur = ns
+	enc.depth++
+}
+
+// otlpArrayEncoder collects array elements as values.
+type otlpArrayEncoder struct {
+	config *zapcore.EncoderConfig
+	values []any
+}
+
+func (arr *otlpArrayEncoder) AppendBool(v bool) { arr.values = append(arr.values, v) }
+
+func (arr *otlpArrayEncoder) AppendByteString(v []byte) { arr.values = append(arr.values, string(v)) }
+
+func (arr *otlpArrayEncoder) AppendComplex128(v complex128) {
+	arr.values = append(arr.values, strconv.FormatComplex(v, 'f', -1, 128))
+}
+
+func (arr *otlpArrayEncoder) AppendComplex64(v complex64) {
+	arr.values = append(arr.values, strconv.FormatComplex(complex128(v), 'f', -1, 64))
+}
+
+func (arr *otlpArrayEncoder) AppendFloat64(v float64) { arr.values = append(arr.values, v) }
+func (arr *otlpArrayEncoder) AppendFloat32(v float32) { arr.values = append(arr.values, float64(v)) }
+
+func (arr *otlpArrayEncoder) AppendInt(v int)     { arr.AppendInt64(int64(v)) }
+func (arr *otlpArrayEncoder) AppendInt64(v int64) { arr.values = append(arr.values, v) }
+func (arr *otlpArrayEncoder) AppendInt32(v int32) { arr.AppendInt64(int64(v)) }
+func (arr *otlpArrayEncoder) AppendInt16(v int16) { arr.AppendInt64(int64(v)) }
+func (arr *otlpArrayEncoder) AppendInt8(v int8)   { arr.AppendInt64(int64(v)) }
+
+func (arr *otlpArrayEncoder) AppendString(v string) { arr.values = append(arr.values, v) }
+
+func (arr *otlpArrayEncoder) AppendUint(v uint)       { arr.AppendUint64(uint64(v)) }
+func (arr *otlpArrayEncoder) AppendUint64(v uint64)   { arr.values = append(arr.values, otlpUint(v)) }
+func (arr *otlpArrayEncoder) AppendUint32(v uint32)   { arr.AppendUint64(uint64(v)) }
+func (arr *otlpArrayEncoder) AppendUint16(v uint16)   { arr.AppendUint64(uint64(v)) }
+func (arr *otlpArrayEncoder) AppendUint8(v uint8)     { arr.AppendUint64(uint64(v)) }
+func (arr *otlpArrayEncoder) AppendUintptr(v uintptr) { arr.AppendUint64(uint64(v)) }
+
+func (arr *otlpArrayEncoder) AppendDuration(v time.Duration) {
+	cur := len(arr.values)
+	if arr.config.EncodeDuration != nil {
+		arr.config.EncodeDuration(v, arr)
+	}
+	if cur == len(arr.values) {
+		arr.values = append(arr.values, v.String())
+	}
+}
+
+func (arr *otlpArrayEncoder) AppendTime(v time.Time) {
+	cur := len(arr.values)
+	if arr.config.EncodeTime != nil {
+		arr.config.EncodeTime(v, arr)
+	}
+	if cur == len(arr.values) {
+		arr.values = append(arr.values, v.Format(time.RFC3339Nano))
+	}
+}
+
+func (arr *otlpArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := &otlpArrayEncoder{config: arr.config}
+	err := marshaler.MarshalLogArray(nested)
+	arr.values = append(arr.values, nested.values)
+	return err
+}
+
+func (arr *otlpArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	enc := newOTLPObjectEncoder(arr.config)
+	err := marshaler.MarshalLogObject(enc)
+	arr.values = append(arr.values, enc.root)
+	return err
+}
+
+func (arr *otlpArrayEncoder) AppendReflected(value any) error {
+	s, err := reflectedString(value)
+	if err != nil {
+		return err
+	}
+	arr.values = append(arr.values, s)
+	return nil
+}
+
+// otlpHTTPSink sends entries to an OpenTelemetry collector with OTLP/HTTP in JSON, in batches.
+//
+// The output path is like:
+//
+//	otlp+http://127.0.0.1:4318/v1/logs
+//
+// Entries are posted to the output path with the scheme http or https, to /v1/logs if the path is empty.
+// Batching, retries and spilling are configured by HTTPOptions, as http outputs.
+// Entries are written in the OpenTelemetry log data model, rather than in the encoding configured in Options,
+// with global fields as attributes of the resource.
+type otlpHTTPSink struct {
+	*httpSink
+	resource []logging.Field
+	// attributes are the resource attributes encoded.
+	attributes []byte
+}
+
+func newOTLPHTTPSink(u *url.URL, o *Options) (zap.Sink, error) {
+	endpoint := *u
+	endpoint.Scheme = strings.TrimPrefix(u.Scheme, "otlp+")
+	if endpoint.Path == "" {
+		endpoint.Path = "/v1/logs"
+	}
+	config := o.encoderConfig()
+	buf := otlpPool.Get()
+	defer buf.Free()
+	appendOTLPKeyValues(buf, otlpAttributes(&config, o.globalFields).values)
+	s := &otlpHTTPSink{resource: o.globalFields, attributes: append([]byte(nil), buf.Bytes()...)}
+	sink, err := openHTTPSink(u, endpoint.String(), o, "application/json", s.writeRequest)
+	if err != nil {
+		return nil, err
+	}
+	s.httpSink = sink
+	return s, nil
+}
+
+func (s *otlpHTTPSink) newEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return newOTLPEncoder(&config, s.resource, true)
+}
+
+// writeRequest writes the records as an ExportLogsServiceRequest, grouped by their scopes.
+func (s *otlpHTTPSink) writeRequest(w io.Writer, entries [][]byte) error {
+	var scopes []string
+	records := map[string][][]byte{}
+	for _, entry := range entries {
+		entry = bytes.TrimRight(entry, "\r\n")
+		// A tab is always escaped in the JSON string of the scope name.
+		i := bytes.IndexByte(entry, '\t')
+		if i == -1 {
+			continue
+		}
+		scope := string(entry[:i])
+		if _, ok := records[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+		records[scope] = append(records[scope], entry[i+1:])
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"resourceLogs":[{"resource":{"attributes":`)
+	buf.Write(s.attributes)
+	buf.WriteString(`},"scopeLogs":[`)
+	for i, scope := range scopes {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"scope":{"name":`)
+		buf.WriteString(scope)
+		buf.WriteString(`},"logRecords":[`)
+		buf.Write(bytes.Join(records[scope], []byte{','}))
+		buf.WriteString(`]}`)
+	}
+	buf.WriteString(`]}]}`)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,366 @@
+package zap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func decodeOTLP(t *testing.T, p []byte) map[string]any {
+	t.Helper()
+	m := map[string]any{}
+	if err := json.Unmarshal(p, &m); err != nil {
+		t.Fatalf("invalid OTLP/JSON %q: %v", p, err)
+	}
+	return m
+}
+
+// otlpRecords returns the resource attributes, the scope names and the log records of the ExportLogsServiceRequest.
+func otlpRecords(t *testing.T, req map[string]any) (resource any, scopes []any, records [][]any) {
+	t.Helper()
+	resourceLogs := req["resourceLogs"].([]any)
+	assert.Len(t, resourceLogs, 1)
+	rl := resourceLogs[0].(map[string]any)
+	resource = rl["resource"].(map[string]any)["attributes"]
+	for _, sl := range rl["scopeLogs"].([]any) {
+		sl := sl.(map[string]any)
+		scopes = append(scopes, sl["scope"].(map[string]any)["name"])
+		records = append(records, sl["logRecords"].([]any))
+	}
+	return resource, scopes, records
+}
+
+func TestOTLPEncoder_EncodeEntry(t *testing.T) {
+	config := testLogfmtEncoderConfig()
+	enc := newOTLPEncoder(&config, []logging.Field{logging.String("service.name", "demo")}, false)
+	// Global fields are accumulated by loggers, and written as resource attributes only.
+	enc.AddString("service.name", "demo")
+	enc.AddString("k", "v")
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Unix(1700000000, 123),
+		LoggerName: "foo",
+		Message:    "abc",
+		Caller:     zapcore.NewEntryCaller(0, "/a/b/c.go", 12, true),
+		Stack:      "goroutine 1",
+	}
+	ent.Caller.Function = "main.main"
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{
+		zap.Int("count", 3),
+		zap.Float64("nan", math.NaN()),
+		zap.Bool("ok", true),
+		zap.Binary("bin", []byte{1, 2}),
+		zap.Uint64("big", math.MaxUint64),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.Object("http", fieldsMarshaler{logging.String("method", "GET")}),
+		zap.String("trace_id", "0AF7651916CD43DD8448EB211C80319C"),
+		zap.String("span_id", "b7ad6b7169203331"),
+		zap.Error(errors.New("boom")),
+	})
+	assert.Nil(t, err)
+	req := decodeOTLP(t, buf.Bytes())
+	resource, scopes, records := otlpRecords(t, req)
+	assert.Equal(t, []any{
+		map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "demo"}},
+	}, resource)
+	assert.Equal(t, []any{"foo"}, scopes)
+	assert.Equal(t, map[string]any{
+		"timeUnixNano":         "1700000000000000123",
+		"observedTimeUnixNano": "1700000000000000123",
+		"severityNumber":       float64(13),
+		"severityText":         "WARN",
+		"body":                 map[string]any{"stringValue": "abc"},
+		"traceId":              "0af7651916cd43dd8448eb211c80319c",
+		"spanId":               "b7ad6b7169203331",
+		"attributes": []any{
+			map[string]any{"key": "code.filepath", "value": map[string]any{"stringValue": "/a/b/c.go"}},
+			map[string]any{"key": "code.lineno", "value": map[string]any{"intValue": "12"}},
+			map[string]any{"key": "code.function", "value": map[string]any{"stringValue": "main.main"}},
+			map[string]any{"key": "exception.stacktrace", "value": map[string]any{"stringValue": "goroutine 1"}},
+			map[string]any{"key": "exception.message", "value": map[string]any{"stringValue": "boom"}},
+			map[string]any{"key": "exception.type", "value": map[string]any{"stringValue": "*errors.errorString"}},
+			map[string]any{"key": "k", "value": map[string]any{"stringValue": "v"}},
+			map[string]any{"key": "count", "value": map[string]any{"intValue": "3"}},
+			map[string]any{"key": "nan", "value": map[string]any{"doubleValue": "NaN"}},
+			map[string]any{"key": "ok", "value": map[string]any{"boolValue": true}},
+			map[string]any{"key": "bin", "value": map[string]any{"bytesValue": "AQI="}},
+			map[string]any{"key": "big", "value": map[string]any{"stringValue": "18446744073709551615"}},
+			map[string]any{"key": "tags", "value": map[string]any{"arrayValue": map[string]any{"values": []any{
+				map[string]any{"stringValue": "a"},
+				map[string]any{"stringValue": "b"},
+			}}}},
+			map[string]any{"key": "http", "value": map[string]any{"kvlistValue": map[string]any{"values": []any{
+				map[string]any{"key": "method", "value": map[string]any{"stringValue": "GET"}},
+			}}}},
+		},
+	}, records[0][0])
+}
+
+func TestOTLPEncoder_namespace(t *testing.T) {
+	config := testLogfmtEncoderConfig()
+	enc := newOTLPEncoder(&config, nil, true)
+	enc.OpenNamespace("ns")
+	enc.AddInt("a", 1)
+	// Clones keep adding fields to the open namespace.
+	clone := enc.Clone().(*otlpEncoder)
+	clone.AddInt("b", 2)
+	buf, err := clone.EncodeEntry(zapcore.Entry{Message: "abc"}, []zapcore.Field{zap.Int("c", 3)})
+	assert.Nil(t, err)
+	assert.Equal(t, `""`+"\t"+`{"timeUnixNano":"0","observedTimeUnixNano":"0",`+
+		`"severityNumber":9,"severityText":"INFO","body":{"stringValue":"abc"},"attributes":[{"key":"ns","value":`+
+		`{"kvlistValue":{"values":[{"key":"a","value":{"intValue":"1"}},{"key":"b","value":{"intValue":"2"}},`+
+		`{"key":"c","value":{"intValue":"3"}}]}}}]}`+"\n", buf.String())
+
+	// The original encoder is not changed.
+	buf, err = enc.EncodeEntry(zapcore.Entry{Message: "abc"}, nil)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `"values":[{"key":"a","value":{"intValue":"1"}}]`)
+}
+
+func Test_appendOTLPValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "string", value: "a\"b", want: `{"stringValue":"a\"b"}`},
+		{name: "int", value: int64(-1), want: `{"intValue":"-1"}`},
+		{name: "double", value: 1.5, want: `{"doubleValue":1.5}`},
+		{name: "large_double", value: 1e300, want: `{"doubleValue":1e+300}`},
+		{name: "inf", value: math.Inf(1), want: `{"doubleValue":"Infinity"}`},
+		{name: "negative_inf", value: math.Inf(-1), want: `{"doubleValue":"-Infinity"}`},
+		{name: "empty_array", value: []any{}, want: `{"arrayValue":{"values":[]}}`},
+		{name: "empty_kvlist", value: &otlpKVList{}, want: `{"kvlistValue":{"values":[]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &buffer.Buffer{}
+			appendOTLPValue(buf, tt.value)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func Test_otlpTraceContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   []otlpKeyValue
+		traceID string
+		spanID  string
+		rest    []otlpKeyValue
+	}{
+		{
+			name: "ecs_keys",
+			attrs: []otlpKeyValue{
+				{key: "trace.id", value: "0af7651916cd43dd8448eb211c80319c"},
+				{key: "span.id", value: "b7ad6b7169203331"},
+			},
+			traceID: "0af7651916cd43dd8448eb211c80319c",
+			spanID:  "b7ad6b7169203331",
+			rest:    []otlpKeyValue{},
+		},
+		{
+			name: "invalid",
+			attrs: []otlpKeyValue{
+				{key: "traceId", value: "xyz"},
+				{key: "spanID", value: "0000000000000000"},
+				{key: "trace_id", value: int64(1)},
+			},
+			rest: []otlpKeyValue{
+				{key: "traceId", value: "xyz"},
+				{key: "spanID", value: "0000000000000000"},
+				{key: "trace_id", value: int64(1)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, spanID, rest := otlpTraceContext(tt.attrs)
+			assert.Equal(t, tt.traceID, traceID)
+			assert.Equal(t, tt.spanID, spanID)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+}
+
+// tReflectedContext is a context not implementing fmt.Stringer, which zap adds by reflection.
+type tReflectedContext struct {
+	context.Context
+}
+
+func TestOTLPEncoder_spanContext(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x0a, 0xf7, 0x65, 0x19},
+		SpanID:  trace.SpanID{0xb7, 0xad, 0x6b, 0x71},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	tests := []struct {
+		name    string
+		fields  []zapcore.Field
+		traceID any
+		spanID  any
+		attrs   int
+	}{
+		{
+			name:    "stringer",
+			fields:  []zapcore.Field{mapZapField(logging.Any("ctx", ctx)), zap.String("a", "b")},
+			traceID: "0af76519000000000000000000000000",
+			spanID:  "b7ad6b7100000000",
+			attrs:   1,
+		},
+		{
+			name:    "reflected",
+			fields:  []zapcore.Field{zap.Reflect("ctx", tReflectedContext{ctx})},
+			traceID: "0af76519000000000000000000000000",
+			spanID:  "b7ad6b7100000000",
+		},
+		{
+			name: "explicit",
+			fields: []zapcore.Field{
+				zap.Any("ctx", ctx),
+				zap.String("trace_id", "0af7651916cd43dd8448eb211c80319c"),
+			},
+			traceID: "0af7651916cd43dd8448eb211c80319c",
+			spanID:  "b7ad6b7100000000",
+		},
+		{
+			name:   "no_span",
+			fields: []zapcore.Field{zap.Any("ctx", context.Background())},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := NewOTLPEncoder(zapcore.EncoderConfig{}).EncodeEntry(zapcore.Entry{Message: "m"}, tt.fields)
+			assert.Nil(t, err)
+			_, _, records := otlpRecords(t, decodeOTLP(t, buf.Bytes()))
+			record := records[0][0].(map[string]any)
+			assert.Equal(t, tt.traceID, record["traceId"])
+			assert.Equal(t, tt.spanID, record["spanId"])
+			if tt.attrs == 0 {
+				assert.Nil(t, record["attributes"])
+			} else {
+				assert.Len(t, record["attributes"], tt.attrs)
+			}
+		})
+	}
+
+	// Contexts added to the encoder are kept by clones.
+	enc := NewOTLPEncoder(zapcore.EncoderConfig{})
+	assert.Nil(t, enc.AddReflected("ctx", tReflectedContext{ctx}))
+	buf, err := enc.Clone().EncodeEntry(zapcore.Entry{Message: "m"}, nil)
+	assert.Nil(t, err)
+	_, _, records := otlpRecords(t, decodeOTLP(t, buf.Bytes()))
+	assert.Equal(t, "b7ad6b7100000000", records[0][0].(map[string]any)["spanId"])
+}
+
+// tOTLPCollector records the ExportLogsServiceRequests posted to it.
+type tOTLPCollector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	paths    []string
+	types    []string
+	requests []map[string]any
+}
+
+func newTOTLPCollector(t *testing.T) *tOTLPCollector {
+	c := &tOTLPCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.paths = append(c.paths, r.URL.Path)
+		c.types = append(c.types, r.Header.Get("Content-Type"))
+		c.requests = append(c.requests, decodeOTLP(t, body))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	return c
+}
+
+func TestOTLPHTTPSink(t *testing.T) {
+	collector := newTOTLPCollector(t)
+	defer collector.Close()
+	out, err := NewOptions(
+		OutputPaths("otlp+"+collector.URL),
+		GlobalFields(logging.String("service.name", "demo")),
+		HTTP(HTTPOptions{MaxInFlight: 1}),
+		DisableCaller(true),
+	).openOutputs()
+	assert.Nil(t, err)
+	out.zapLogger("foo").Info("a", zap.String("service.name", "demo"))
+	out.zapLogger("bar").Warn("b")
+	out.zapLogger("foo").Error("c")
+	assert.Nil(t, out.close())
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	assert.Equal(t, []string{"/v1/logs"}, collector.paths)
+	assert.Equal(t, []string{"application/json"}, collector.types)
+	resource, scopes, records := otlpRecords(t, collector.requests[0])
+	assert.Equal(t, []any{
+		map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "demo"}},
+	}, resource)
+	assert.Equal(t, []any{"foo", "bar"}, scopes)
+	assert.Len(t, records[0], 2)
+	assert.Len(t, records[1], 1)
+	assert.Equal(t, map[string]any{"stringValue": "a"}, records[0][0].(map[string]any)["body"])
+	// Fields of entries are always written as attributes.
+	assert.Equal(t, []any{
+		map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "demo"}},
+	}, records[0][0].(map[string]any)["attributes"])
+	assert.Equal(t, "ERROR", records[0][1].(map[string]any)["severityText"])
+	assert.Equal(t, "WARN", records[1][0].(map[string]any)["severityText"])
+}
+
+func Test_newOTLPHTTPSink(t *testing.T) {
+	u, _ := url.Parse("otlp+http://")
+	_, err := newOTLPHTTPSink(u, NewOptions())
+	assert.NotNil(t, err)
+
+	u, _ = url.Parse("otlp+https://collector.example.com:4318/custom")
+	sink, err := newOTLPHTTPSink(u, NewOptions())
+	assert.Nil(t, err)
+	defer func() {
+		_ = sink.Close()
+	}()
+	assert.Equal(t, "https://collector.example.com:4318/custom", sink.(*otlpHTTPSink).url)
+}
+
+func TestOTLP_encoding(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-otlp", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-otlp://"),
+		Encoding("otlp"),
+		GlobalFields(logging.String("service.name", "demo")),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	factory.Logger("foo").Infow("abc", logging.String("k", "v"))
+	assert.Len(t, sink.lines, 1)
+	resource, scopes, records := otlpRecords(t, decodeOTLP(t, []byte(sink.lines[0])))
+	assert.Equal(t, []any{
+		map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "demo"}},
+	}, resource)
+	assert.Equal(t, []any{"foo"}, scopes)
+	attrs := records[0][0].(map[string]any)["attributes"].([]any)
+	assert.Equal(t, map[string]any{"key": "k", "value": map[string]any{"stringValue": "v"}}, attrs[len(attrs)-1])
+	for _, attr := range attrs {
+		assert.NotEqual(t, "service.name", attr.(map[string]any)["key"])
+	}
+}
@@ -0,0 +1,384 @@
+package zap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"syscall"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// entryWriter is implemented by sinks which need the log entry besides its encoded form,
+// e.g. to map the level of the entry to the severity of their protocol.
+type entryWriter interface {
+	WriteEntry(ent zapcore.Entry, p []byte) error
+}
+
+// encoderSink is implemented by sinks which write entries in their own encodings,
+// rather than the encoding configured in Options.
+type encoderSink interface {
+	newEncoder(config zapcore.EncoderConfig) zapcore.Encoder
+}
+
+// optionsEncoder is implemented by encoders configured by Options besides the encoder config,
+// e.g. to write global fields apart from other fields.
+type optionsEncoder interface {
+	withOptions(o *Options) zapcore.Encoder
+}
+
+// sinkFactory opens a sink of an output path handled by this package.
+type sinkFactory func(u *url.URL, o *Options) (zap.Sink, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]sinkFactory{}
+)
+
+// registerSink registers the sink factory of the scheme to this package.
+//
+// The schemes are not registered to zap, where sinks would be opened without Options,
+// and which would conflict with other packages registering the same schemes.
+func registerSink(scheme string, factory sinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	sinkFactories[scheme] = factory
+}
+
+// openSink opens the sink of the output path.
+func (o *Options) openSink(path string) (zap.Sink, error) {
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		sinkFactoriesMu.RLock()
+		factory, ok := sinkFactories[u.Scheme]
+		sinkFactoriesMu.RUnlock()
+		if ok {
+			return factory(u, o)
+		}
+	}
+	ws, closeFunc, err := zap.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &zapSink{WriteSyncer: ws, close: closeFunc}, nil
+}
+
+// zapSink is a sink opened by zap.
+type zapSink struct {
+	zapcore.WriteSyncer
+	close func()
+}
+
+func (s *zapSink) Close() error {
+	s.close()
+	return nil
+}
+
+// openSinks opens sinks of the output paths.
+func (o *Options) openSinks(paths []string) ([]zap.Sink, error) {
+	sinks := make([]zap.Sink, 0, len(paths))
+	var openErr error
+	for _, path := range paths {
+		sink, err := o.openSink(path)
+		if err != nil {
+			openErr = multierr.Append(openErr, fmt.Errorf("couldn't open sink %q: %w", path, err))
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	if openErr != nil {
+		for _, sink := range sinks {
+			_ = sink.Close()
+		}
+		return nil, openErr
+	}
+	return sinks, nil
+}
+
+// outputs are sinks opened with Options, shared by zap loggers of all names.
+type outputs struct {
+	options      *Options
+	core         zapcore.Core
+	stop         func()
+	closing      *closingState
+	errSink      zapcore.WriteSyncer
+	sinks        []zap.Sink
+	closeErrSink func()
+}
+
+// openOutputs opens the output paths and the error output paths of the Options.
+func (o *Options) openOutputs() (*outputs, error) {
+	if _, err := o.schema(); err != nil {
+		return nil, err
+	}
+	if _, err := o.hooks(); err != nil {
+		return nil, err
+	}
+	encoderConfig := o.encoderConfig()
+	encoding := o.encoding()
+	var encoder zapcore.Encoder
+	if hasEncoder(encoding) {
+		var err error
+		if encoder, err = newEncoder(encoding, encoderConfig); err != nil {
+			return nil, err
+		}
+		if oe, ok := encoder.(optionsEncoder); ok {
+			encoder = oe.withOptions(o)
+		}
+	}
+	sinks, err := o.openSinks(o.OutputPaths)
+	if err != nil {
+		return nil, err
+	}
+	closeSinks := func() {
+		for _, sink := range sinks {
+			_ = sink.Close()
+		}
+	}
+	var output zapcore.Core
+	if encoder != nil {
+		output = newOutputCore(encoder, encoderConfig, sinks, zapcore.DebugLevel)
+	} else {
+		ws := make([]zapcore.WriteSyncer, len(sinks))
+		for i, sink := range sinks {
+			ws[i] = sink
+		}
+		if output, err = newZapEncodingCore(encoding, encoderConfig, zap.CombineWriteSyncers(ws...)); err != nil {
+			closeSinks()
+			return nil, err
+		}
+	}
+	errSink, closeErrSink, err := zap.Open(o.ErrorOutputPaths...)
+	if err != nil {
+		closeSinks()
+		return nil, err
+	}
+	closing := &closingState{}
+	core, stop := o.wrapCore(&closingCore{Core: output, state: closing})
+	return &outputs{
+		options:      o,
+		core:         core,
+		stop:         stop,
+		closing:      closing,
+		errSink:      errSink,
+		sinks:        sinks,
+		closeErrSink: closeErrSink,
+	}, nil
+}
+
+// nopOutputs are outputs discarding all entries, used when the outputs of the Options fail to open.
+func nopOutputs(o *Options) *outputs {
+	return &outputs{
+		options:      o,
+		core:         zapcore.NewNopCore(),
+		stop:         func() {},
+		closing:      &closingState{},
+		errSink:      zapcore.AddSync(io.Discard),
+		closeErrSink: func() {},
+	}
+}
+
+// close syncs and closes the sinks, after writes in flight finish.
+// Loggers still holding the outputs fail to write after that, which are counted as WriteFailed by Metrics.
+func (out *outputs) close() error {
+	out.stop()
+	err := out.core.Sync()
+	out.closing.close()
+	for _, sink := range out.sinks {
+		err = multierr.Append(err, sink.Close())
+	}
+	out.closeErrSink()
+	return err
+}
+
+// errOutputsClosed is the error of writing entries to closed outputs.
+var errOutputsClosed = errors.New("outputs closed")
+
+// closingState tracks writes to outputs, so that outputs are closed after writes in flight.
+type closingState struct {
+	mu     sync.RWMutex
+	closed bool
+}
+
+// close waits for writes in flight, and fails writes after that.
+func (s *closingState) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// isClosed reports whether the outputs are closed, false for nil states.
+func (s *closingState) isClosed() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// closingCore writes entries unless the outputs are closed.
+type closingCore struct {
+	zapcore.Core
+	state *closingState
+}
+
+func (c *closingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &closingCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *closingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *closingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.state.mu.RLock()
+	defer c.state.mu.RUnlock()
+	if c.state.closed {
+		return errOutputsClosed
+	}
+	return c.Core.Write(ent, fields)
+}
+
+func (c *closingCore) Sync() error {
+	c.state.mu.RLock()
+	defer c.state.mu.RUnlock()
+	if c.state.closed {
+		return nil
+	}
+	return c.Core.Sync()
+}
+
+// newOutputCore creates a core writing entries to the sinks.
+//
+// Sinks which are entryWriters or encoderSinks are written by their own cores, others share one zap io core.
+func newOutputCore(
+	enc zapcore.Encoder, config zapcore.EncoderConfig, sinks []zap.Sink, enab zapcore.LevelEnabler,
+) zapcore.Core {
+	var cores []zapcore.Core
+	var plain []zapcore.WriteSyncer
+	for _, sink := range sinks {
+		sinkEnc := enc
+		es, ok := sink.(encoderSink)
+		if ok {
+			sinkEnc = es.newEncoder(config)
+		}
+		if ew, ok := sink.(entryWriter); ok {
+			cores = append(cores, &entryCore{LevelEnabler: enab, enc: sinkEnc.Clone(), out: ew, sync: sink})
+			continue
+		}
+		if ok {
+			cores = append(cores, zapcore.NewCore(sinkEnc, sink, enab))
+			continue
+		}
+		plain = append(plain, sink)
+	}
+	if len(plain) != 0 || len(cores) == 0 {
+		cores = append(cores, zapcore.NewCore(enc, zap.CombineWriteSyncers(plain...), enab))
+	}
+	return zapcore.NewTee(cores...)
+}
+
+// entryCore is a core writing encoded entries to an entryWriter.
+type entryCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	out  entryWriter
+	sync zapcore.WriteSyncer
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &entryCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), out: c.out, sync: c.sync}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	err = c.out.WriteEntry(ent, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		_ = c.Sync()
+	}
+	return nil
+}
+
+func (c *entryCore) Sync() error {
+	return c.sync.Sync()
+}
+
+// redialConn is a connection dialed again once when writing fails,
+// as the peer, usually a local daemon, may have restarted.
+// It is not dialed again after closed.
+type redialConn struct {
+	conn   net.Conn
+	dial   func() (net.Conn, error)
+	mu     sync.Mutex
+	closed bool
+}
+
+func newRedialConn(dial func() (net.Conn, error)) (*redialConn, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	return &redialConn{conn: conn, dial: dial}, nil
+}
+
+func (c *redialConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	var err error
+	if c.conn != nil {
+		var n int
+		// Datagrams too large to send do not break the connection.
+		if n, err = c.conn.Write(p); err == nil || errors.Is(err, syscall.EMSGSIZE) {
+			return n, err
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	conn, dialErr := c.dial()
+	if dialErr != nil {
+		return 0, multierr.Append(err, dialErr)
+	}
+	c.conn = conn
+	return c.conn.Write(p)
+}
+
+func (c *redialConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
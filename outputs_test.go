@@ -0,0 +1,176 @@
+package zap
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type tEntrySink struct {
+	entries []zapcore.Entry
+	lines   []string
+	synced  int
+	closed  bool
+}
+
+func (s *tEntrySink) Write(p []byte) (int, error) {
+	s.lines = append(s.lines, string(p))
+	return len(p), nil
+}
+
+func (s *tEntrySink) WriteEntry(ent zapcore.Entry, p []byte) error {
+	s.entries = append(s.entries, ent)
+	s.lines = append(s.lines, string(p))
+	return nil
+}
+
+func (s *tEntrySink) Sync() error {
+	s.synced++
+	return nil
+}
+
+func (s *tEntrySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestOptions_openSink(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-entry", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	o := NewOptions()
+	got, err := o.openSink("test-entry://foo")
+	assert.Nil(t, err)
+	assert.Same(t, sink, got)
+
+	// Not registered to zap.
+	_, _, err = zap.Open("test-entry://foo")
+	assert.NotNil(t, err)
+
+	got, err = o.openSink("stderr")
+	assert.Nil(t, err)
+	assert.IsType(t, &zapSink{}, got)
+	assert.Nil(t, got.Close())
+
+	_, err = o.openSink("unknown-scheme://foo")
+	assert.NotNil(t, err)
+}
+
+func TestOptions_openSinks(t *testing.T) {
+	o := NewOptions()
+	sinks, err := o.openSinks([]string{"stdout", "stderr"})
+	assert.Nil(t, err)
+	assert.Len(t, sinks, 2)
+
+	sinks, err = o.openSinks([]string{"stdout", "unknown-scheme://foo"})
+	assert.NotNil(t, err)
+	assert.Nil(t, sinks)
+}
+
+func Test_newOutputCore(t *testing.T) {
+	sink := &tEntrySink{}
+	plain := &tEntrySink{}
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level",
+		EncodeLevel: zapcore.CapitalLevelEncoder})
+	core := newOutputCore(enc, zapcore.EncoderConfig{}, []zap.Sink{sink, &zapSink{WriteSyncer: zapcore.AddSync(plain)}}, zapcore.InfoLevel)
+	l := zap.New(core).With(zap.String("a", "b"))
+	l.Debug("ignored")
+	l.Info("hello")
+	l.DPanic("oops")
+	assert.Len(t, sink.entries, 2)
+	assert.Equal(t, zapcore.InfoLevel, sink.entries[0].Level)
+	assert.Equal(t, `{"level":"INFO","msg":"hello","a":"b"}`+"\n", sink.lines[0])
+	assert.Equal(t, sink.lines, plain.lines)
+	assert.Equal(t, 1, sink.synced)
+	assert.Nil(t, core.Sync())
+	assert.Equal(t, 2, sink.synced)
+
+	// Without any sink.
+	core = newOutputCore(enc, zapcore.EncoderConfig{}, nil, zapcore.InfoLevel)
+	assert.Nil(t, core.Write(zapcore.Entry{}, nil))
+}
+
+func Test_redialConn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redial.sock")
+	listener := listenUnixgram(t, path)
+	dials := 0
+	conn, err := newRedialConn(func() (net.Conn, error) {
+		dials++
+		return net.Dial("unixgram", path)
+	})
+	assert.Nil(t, err)
+	_, err = conn.Write([]byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a", readDatagram(t, listener))
+
+	_ = listener.Close()
+	_ = os.Remove(path)
+	_, err = conn.Write([]byte("b"))
+	assert.NotNil(t, err)
+
+	listener = listenUnixgram(t, path)
+	defer func() {
+		_ = listener.Close()
+	}()
+	_, err = conn.Write([]byte("c"))
+	assert.Nil(t, err)
+	assert.Equal(t, "c", readDatagram(t, listener))
+	assert.Equal(t, 3, dials)
+	assert.Nil(t, conn.Close())
+	assert.Nil(t, conn.Close())
+	// Not dialed again after closed.
+	_, err = conn.Write([]byte("d"))
+	assert.ErrorIs(t, err, net.ErrClosed)
+	assert.Equal(t, 3, dials)
+
+	_, err = newRedialConn(func() (net.Conn, error) {
+		return nil, errors.New("oops")
+	})
+	assert.NotNil(t, err)
+}
+
+type tBlockingCore struct {
+	zapcore.Core
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (c *tBlockingCore) Write(zapcore.Entry, []zapcore.Field) error {
+	close(c.writing)
+	<-c.release
+	return nil
+}
+
+func TestClosingCore(t *testing.T) {
+	state := &closingState{}
+	inner := &tBlockingCore{Core: zapcore.NewNopCore(), writing: make(chan struct{}), release: make(chan struct{})}
+	core := &closingCore{Core: inner, state: state}
+	go func() {
+		_ = core.Write(zapcore.Entry{}, nil)
+	}()
+	<-inner.writing
+	closed := make(chan struct{})
+	go func() {
+		state.close()
+		close(closed)
+	}()
+	// closing waits for the write in flight.
+	select {
+	case <-closed:
+		t.Fatal("closed before the write in flight finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(inner.release)
+	<-closed
+	assert.Equal(t, errOutputsClosed, core.Write(zapcore.Entry{}, nil))
+	assert.Nil(t, core.Sync())
+}
@@ -0,0 +1,45 @@
+// Package printflogging adapts a logging.Factory to Printf-style logger interfaces,
+// e.g. sarama.StdLogger or the ones of the standard log package.
+package printflogging
+
+import (
+	"fmt"
+
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+)
+
+// Logger writes entries of Print, Printf and Println at a level, e.g.
+//
+//	sarama.Logger = printflogging.New(factory, "sarama", logging.InfoLevel)
+type Logger struct {
+	logger logging.Logger
+	level  logging.Level
+}
+
+// New creates a Logger writing to the logger of the name at the level.
+func New(factory logging.Factory, name string, level logging.Level) *Logger {
+	return &Logger{logger: zaplogging.AddCallerSkip(factory.Logger(name), 1), level: level}
+}
+
+// Print writes the entry formatted as fmt.Sprint.
+func (l *Logger) Print(v ...any) {
+	if l.logger.Enabled(l.level) {
+		zaplogging.Logw(l.logger, l.level, fmt.Sprint(v...))
+	}
+}
+
+// Printf writes the entry formatted as fmt.Sprintf.
+func (l *Logger) Printf(format string, v ...any) {
+	if l.logger.Enabled(l.level) {
+		zaplogging.Logw(l.logger, l.level, fmt.Sprintf(format, v...))
+	}
+}
+
+// Println writes the entry formatted as fmt.Sprintln, without the trailing new line.
+func (l *Logger) Println(v ...any) {
+	if l.logger.Enabled(l.level) {
+		s := fmt.Sprintln(v...)
+		zaplogging.Logw(l.logger, l.level, s[:len(s)-1])
+	}
+}
@@ -0,0 +1,64 @@
+package printflogging
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	zaplogging "github.com/yimi-go/zap-logging"
+	"github.com/yimi-go/zap-logging/internal/logtest"
+)
+
+func TestLogger(t *testing.T) {
+	factory, rec := logtest.NewFactory(t, zaplogging.Levels(map[string]logging.Level{"": logging.DebugLevel}))
+	tests := []struct {
+		level logging.Level
+		want  string
+	}{
+		{level: logging.DebugLevel, want: "DEBUG"},
+		{level: logging.InfoLevel, want: "INFO"},
+		{level: logging.WarnLevel, want: "WARN"},
+		{level: logging.ErrorLevel, want: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			n := len(rec.Entries())
+			l := New(factory, "sarama", tt.level)
+			_, file, line, _ := runtime.Caller(0)
+			l.Print("a", 1)
+			l.Printf("b %d", 1)
+			l.Println("c", 1)
+			entries := rec.Entries()[n:]
+			assert.Len(t, entries, 3)
+			for i, msg := range []string{"a1", "b 1", "c 1"} {
+				assert.Equal(t, msg, entries[i]["msg"])
+				assert.Equal(t, tt.want, entries[i]["level"])
+				assert.Equal(t, "sarama", entries[i]["logger"])
+				assert.Equal(t, fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+1+i),
+					entries[i]["caller"])
+			}
+		})
+	}
+
+	New(factory, "sarama", logging.OffLevel).Print("d")
+	assert.Len(t, rec.Entries(), 12)
+}
+
+func TestLogger_sarama(t *testing.T) {
+	factory, rec := logtest.NewFactory(t)
+	var logger sarama.StdLogger = New(factory, "sarama", logging.InfoLevel)
+	old := sarama.Logger
+	sarama.Logger = logger
+	defer func() {
+		sarama.Logger = old
+	}()
+	sarama.Logger.Printf("client/metadata fetching metadata for %v", []string{"topic"})
+	entries := rec.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "client/metadata fetching metadata for [topic]", entries[0]["msg"])
+	assert.Equal(t, "sarama", entries[0]["logger"])
+}
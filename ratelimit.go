@@ -0,0 +1,288 @@
+package zap
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// rateLimitMaxBuckets is the number of buckets beyond which refilled buckets are dropped.
+const rateLimitMaxBuckets = 10000
+
+// TokenBucket is a token bucket limit of similar entries.
+type TokenBucket struct {
+	// Rate is the number of similar entries written per second, zero or negative to disable limiting.
+	Rate float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	// Burst is the number of similar entries written at once. Rate rounded up as default, at least 1.
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"`
+}
+
+func (b TokenBucket) burst() float64 {
+	if b.Burst > 0 {
+		return float64(b.Burst)
+	}
+	return math.Max(1, math.Ceil(b.Rate))
+}
+
+// RateLimitOptions configures rate limiting of similar entries,
+// which are entries of the same logger name, level and message or caller.
+//
+// Entries beyond the limits are dropped,
+// and a "suppressed N similar messages" entry is written for them every SummaryInterval.
+type RateLimitOptions struct {
+	// Limits are the limits mapped by logger name prefixes, which are resolved as Levels.
+	// "" for the root logger. No limits as default.
+	Limits map[string]TokenBucket `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Key is what identifies similar entries besides the logger name and level, "message" or "caller".
+	// "caller" groups entries of varying messages logged at the same line. "message" as default.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// SummaryInterval is the interval of writing summaries of suppressed entries. 10s as default.
+	SummaryInterval time.Duration `json:"summary_interval,omitempty" yaml:"summary_interval,omitempty"`
+}
+
+// Defaulted returns a new RateLimitOptions filling blank items with default values.
+func (r RateLimitOptions) Defaulted() RateLimitOptions {
+	limits := make(map[string]TokenBucket, len(r.Limits))
+	for name, limit := range r.Limits {
+		limits[strings.TrimSpace(name)] = limit
+	}
+	r.Limits = limits
+	r.Key = strings.TrimSpace(r.Key)
+	if r.Key == "" {
+		r.Key = "message"
+	}
+	if r.SummaryInterval <= 0 {
+		r.SummaryInterval = 10 * time.Second
+	}
+	return r
+}
+
+// limit returns the limit of the logger name, resolved as levels.
+func (r RateLimitOptions) limit(name string) TokenBucket {
+	name = strings.TrimSpace(name)
+	for {
+		if limit, ok := r.Limits[name]; ok {
+			return limit
+		}
+		li := strings.LastIndexAny(name, "./:")
+		if li == -1 {
+			if name == "" {
+				return TokenBucket{}
+			}
+			name = ""
+			continue
+		}
+		name = name[:li]
+	}
+}
+
+type rateLimitKey struct {
+	name  string
+	level zapcore.Level
+	key   string
+}
+
+type rateLimitBucket struct {
+	limit  TokenBucket
+	tokens float64
+	last   time.Time
+	// suppressed is the number of entries dropped since the last summary, first is the first of them.
+	suppressed int
+	first      zapcore.Entry
+}
+
+// rateLimitSummary is the summary of the entries suppressed in a bucket.
+type rateLimitSummary struct {
+	name       string
+	first      zapcore.Entry
+	suppressed int
+}
+
+// rateLimiter limits similar entries, and writes summaries of suppressed entries to the core.
+type rateLimiter struct {
+	core    zapcore.Core
+	options RateLimitOptions
+	metrics *Metrics
+	now     func() time.Time
+
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*rateLimitBucket
+	// ticking indicates whether summaries are being written periodically,
+	// which stops once there is nothing suppressed in an interval.
+	ticking bool
+	// stopped indicates whether the outputs are closed, closing done to stop ticking.
+	stopped bool
+	done    chan struct{}
+}
+
+func newRateLimiter(core zapcore.Core, options RateLimitOptions, metrics *Metrics) *rateLimiter {
+	return &rateLimiter{
+		core:    core,
+		options: options,
+		metrics: metrics,
+		now:     time.Now,
+		buckets: map[rateLimitKey]*rateLimitBucket{},
+		done:    make(chan struct{}),
+	}
+}
+
+// allow reports whether the entry of the logger name is written, taking a token of its bucket.
+func (l *rateLimiter) allow(name string, ent zapcore.Entry) bool {
+	limit := l.options.limit(name)
+	if limit.Rate <= 0 {
+		return true
+	}
+	k := rateLimitKey{name: name, level: ent.Level, key: ent.Message}
+	if l.options.Key == "caller" {
+		k.key = ent.Caller.String()
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[k]
+	if !ok {
+		if len(l.buckets) >= rateLimitMaxBuckets {
+			l.prune(now)
+		}
+		b = &rateLimitBucket{limit: limit, tokens: limit.burst(), last: now}
+		l.buckets[k] = b
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	l.metrics.rateLimited(name, ent)
+	if b.suppressed == 0 {
+		b.first = ent
+	}
+	b.suppressed++
+	if !l.ticking && !l.stopped {
+		l.ticking = true
+		go l.tick()
+	}
+	return false
+}
+
+func (b *rateLimitBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.limit.burst(), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// prune drops the buckets which are refilled and have nothing suppressed. It must be called with the lock held.
+func (l *rateLimiter) prune(now time.Time) {
+	for k, b := range l.buckets {
+		b.refill(now)
+		if b.suppressed == 0 && b.tokens >= b.limit.burst() {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// tick writes summaries every interval, until there is nothing suppressed in an interval.
+func (l *rateLimiter) tick() {
+	ticker := time.NewTicker(l.options.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !l.flush(true) {
+				return
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// stop writes summaries of suppressed entries, and stops writing them periodically,
+// before the outputs are closed.
+func (l *rateLimiter) stop() {
+	l.flush(false)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.stopped {
+		l.stopped, l.ticking = true, false
+		close(l.done)
+	}
+}
+
+// flush writes summaries of suppressed entries, reporting whether there were any.
+// Ticking stops if there were none and stop is true.
+func (l *rateLimiter) flush(stop bool) bool {
+	now := l.now()
+	l.mu.Lock()
+	var summaries []rateLimitSummary
+	for k, b := range l.buckets {
+		if b.suppressed != 0 {
+			summaries = append(summaries, rateLimitSummary{name: k.name, first: b.first, suppressed: b.suppressed})
+			b.suppressed = 0
+		}
+	}
+	l.prune(now)
+	if len(summaries) == 0 && stop {
+		l.ticking = false
+	}
+	l.mu.Unlock()
+
+	for _, b := range summaries {
+		ent := b.first
+		ent.Time = now
+		ent.Message = fmt.Sprintf("suppressed %d similar messages", b.suppressed)
+		ent.Stack = ""
+		_ = l.core.With([]zapcore.Field{loggerNameField(b.name)}).Write(ent, []zapcore.Field{
+			zap.Int("suppressed", b.suppressed),
+			zap.String("suppressed_message", b.first.Message),
+		})
+	}
+	return len(summaries) != 0
+}
+
+// rateLimitCore drops entries beyond the limits of the rate limiter.
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+	// name is the logger name taken from the fields added by With.
+	name string
+}
+
+func newRateLimitCore(core zapcore.Core, options RateLimitOptions, metrics *Metrics) *rateLimitCore {
+	return &rateLimitCore{Core: core, limiter: newRateLimiter(core, options, metrics)}
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	name, ok := fieldsLoggerName(fields)
+	if !ok {
+		name = c.name
+	}
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter, name: name}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry if it is allowed by the limiter.
+// Entries are limited here rather than in Check, as callers are added to entries after checked.
+func (c *rateLimitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.limiter.allow(c.name, ent) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// Sync writes summaries of suppressed entries before syncing.
+func (c *rateLimitCore) Sync() error {
+	c.limiter.flush(false)
+	return c.Core.Sync()
+}
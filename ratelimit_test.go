@@ -0,0 +1,246 @@
+package zap
+
+import (
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// tRecordCore records entries written, safe for concurrent use.
+type tRecordCore struct {
+	zapcore.LevelEnabler
+	mu      sync.Mutex
+	entries []zapcore.Entry
+	fields  [][]zapcore.Field
+}
+
+func (c *tRecordCore) With([]zapcore.Field) zapcore.Core { return c }
+
+func (c *tRecordCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *tRecordCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, ent)
+	c.fields = append(c.fields, fields)
+	return nil
+}
+
+func (c *tRecordCore) Sync() error { return nil }
+
+func (c *tRecordCore) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]string, len(c.entries))
+	for i, ent := range c.entries {
+		res[i] = ent.Message
+	}
+	return res
+}
+
+func TestRateLimitOptions_Defaulted(t *testing.T) {
+	assert.Equal(t, RateLimitOptions{
+		Limits:          map[string]TokenBucket{"a": {Rate: 1}},
+		Key:             "message",
+		SummaryInterval: 10 * time.Second,
+	}, RateLimitOptions{Limits: map[string]TokenBucket{" a ": {Rate: 1}}}.Defaulted())
+	assert.Equal(t, RateLimitOptions{
+		Limits:          map[string]TokenBucket{},
+		Key:             "caller",
+		SummaryInterval: time.Second,
+	}, RateLimitOptions{Key: " caller ", SummaryInterval: time.Second}.Defaulted())
+}
+
+func TestRateLimitOptions_limit(t *testing.T) {
+	r := RateLimitOptions{Limits: map[string]TokenBucket{
+		"":        {Rate: 1},
+		"foo":     {Rate: 2},
+		"foo.bar": {Rate: 0},
+	}}
+	tests := []struct {
+		name string
+		want TokenBucket
+	}{
+		{name: "", want: TokenBucket{Rate: 1}},
+		{name: "baz", want: TokenBucket{Rate: 1}},
+		{name: "foo", want: TokenBucket{Rate: 2}},
+		{name: "foo:baz", want: TokenBucket{Rate: 2}},
+		{name: "foo.bar.baz", want: TokenBucket{Rate: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.limit(tt.name))
+		})
+	}
+	assert.Equal(t, TokenBucket{}, RateLimitOptions{}.limit("foo"))
+}
+
+func TestTokenBucket_burst(t *testing.T) {
+	assert.Equal(t, 1.0, TokenBucket{Rate: 0.1}.burst())
+	assert.Equal(t, 3.0, TokenBucket{Rate: 2.5}.burst())
+	assert.Equal(t, 5.0, TokenBucket{Rate: 1, Burst: 5}.burst())
+}
+
+func TestRateLimitCore(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.DebugLevel}
+	options := RateLimitOptions{
+		Limits:          map[string]TokenBucket{"foo": {Rate: 1, Burst: 2}},
+		SummaryInterval: time.Hour,
+	}.Defaulted()
+	core := newRateLimitCore(rec, options, nil)
+	now := time.Date(2022, 7, 1, 8, 0, 0, 0, time.UTC)
+	core.limiter.now = func() time.Time { return now }
+	write := func(name string, level zapcore.Level, msg string) {
+		// entries are not named with DisableLogger, the name is taken from the field.
+		ce := core.With([]zapcore.Field{loggerNameField(name), zap.Int("i", 1)}).Check(zapcore.Entry{Level: level, Message: msg}, nil)
+		ce.Write(zap.Int("n", 1))
+	}
+
+	for i := 0; i < 5; i++ {
+		write("foo", zapcore.InfoLevel, "a")
+	}
+	write("foo", zapcore.WarnLevel, "a")
+	write("foo", zapcore.InfoLevel, "b")
+	// not limited.
+	write("bar", zapcore.InfoLevel, "a")
+	write("bar", zapcore.InfoLevel, "a")
+	assert.Equal(t, []string{"a", "a", "a", "b", "a", "a"}, rec.messages())
+
+	now = now.Add(time.Second)
+	write("foo", zapcore.InfoLevel, "a")
+	write("foo", zapcore.InfoLevel, "a")
+	assert.Len(t, rec.messages(), 7)
+
+	assert.Nil(t, core.Sync())
+	assert.Equal(t, "suppressed 4 similar messages", rec.entries[7].Message)
+	assert.Equal(t, zapcore.InfoLevel, rec.entries[7].Level)
+	assert.Equal(t, now, rec.entries[7].Time)
+	assert.Equal(t, []zapcore.Field{zap.Int("suppressed", 4), zap.String("suppressed_message", "a")}, rec.fields[7])
+
+	// summaries are written once.
+	assert.Nil(t, core.Sync())
+	assert.Len(t, rec.messages(), 8)
+}
+
+func TestRateLimitCore_caller(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.DebugLevel}
+	core := newRateLimitCore(rec, RateLimitOptions{
+		Limits: map[string]TokenBucket{"": {Rate: 1}},
+		Key:    "caller",
+	}.Defaulted(), nil)
+	for i, msg := range []string{"a", "b", "c"} {
+		caller := zapcore.NewEntryCaller(0, "a.go", 1, true)
+		if i == 2 {
+			caller.Line = 2
+		}
+		ent := zapcore.Entry{Message: msg, Caller: caller}
+		assert.Nil(t, core.Write(ent, nil))
+	}
+	assert.Equal(t, []string{"a", "c"}, rec.messages())
+	assert.Nil(t, core.Sync())
+	assert.Equal(t, "suppressed 1 similar messages", rec.entries[2].Message)
+	assert.Equal(t, "a.go:1", rec.entries[2].Caller.String())
+}
+
+func TestRateLimitCore_tick(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.DebugLevel}
+	core := newRateLimitCore(rec, RateLimitOptions{
+		Limits:          map[string]TokenBucket{"": {Rate: 0.001}},
+		SummaryInterval: 10 * time.Millisecond,
+	}.Defaulted(), nil)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, core.Write(zapcore.Entry{Message: "a"}, nil))
+	}
+	assert.Eventually(t, func() bool {
+		return len(rec.messages()) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "suppressed 2 similar messages", rec.messages()[1])
+	// ticking stops when there is nothing suppressed.
+	assert.Eventually(t, func() bool {
+		core.limiter.mu.Lock()
+		defer core.limiter.mu.Unlock()
+		return !core.limiter.ticking
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRateLimiter_stop(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.DebugLevel}
+	core := newRateLimitCore(rec, RateLimitOptions{
+		Limits:          map[string]TokenBucket{"": {Rate: 0.001}},
+		SummaryInterval: 10 * time.Millisecond,
+	}.Defaulted(), nil)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, core.Write(zapcore.Entry{Message: "a"}, nil))
+	}
+	core.limiter.stop()
+	assert.Equal(t, []string{"a", "suppressed 2 similar messages"}, rec.messages())
+	// nothing is written once stopped.
+	assert.Nil(t, core.Write(zapcore.Entry{Message: "a"}, nil))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, []string{"a", "suppressed 2 similar messages"}, rec.messages())
+	core.limiter.mu.Lock()
+	assert.False(t, core.limiter.ticking)
+	core.limiter.mu.Unlock()
+	// stop twice.
+	core.limiter.stop()
+}
+
+func TestRateLimiter_prune(t *testing.T) {
+	l := newRateLimiter(zapcore.NewNopCore(), RateLimitOptions{Limits: map[string]TokenBucket{"": {Rate: 1}}}.Defaulted(), nil)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	assert.True(t, l.allow("", zapcore.Entry{Message: "a"}))
+	assert.True(t, l.allow("", zapcore.Entry{Message: "b"}))
+	assert.False(t, l.allow("", zapcore.Entry{Message: "b"}))
+	now = now.Add(time.Second)
+	l.mu.Lock()
+	l.prune(now)
+	assert.Len(t, l.buckets, 1)
+	l.mu.Unlock()
+}
+
+func TestRateLimit_options(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-ratelimit", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-ratelimit://"),
+		RateLimit(RateLimitOptions{Limits: map[string]TokenBucket{"foo": {Rate: 0.001}}}),
+	))
+	logger := factory.Logger("foo")
+	for i := 0; i < 3; i++ {
+		logger.Errorw("failed", logging.Int("i", i))
+	}
+	assert.Len(t, sink.lines, 1)
+	factory.(*zapFactory).SwitchOptions(NewOptions())
+	assert.Len(t, sink.lines, 2)
+	assert.Contains(t, sink.lines[1], `"msg":"suppressed 2 similar messages"`)
+	assert.Contains(t, sink.lines[1], `"suppressed_message":"failed"`)
+}
+
+func TestRateLimit_disableLogger(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-ratelimit-nameless", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-ratelimit-nameless://"),
+		DisableLogger(true),
+		RateLimit(RateLimitOptions{Limits: map[string]TokenBucket{"foo": {Rate: 0.001}}}),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	for i := 0; i < 3; i++ {
+		factory.Logger("foo").Errorw("failed")
+		factory.Logger("bar").Errorw("failed")
+	}
+	assert.Len(t, sink.lines, 4)
+}
@@ -0,0 +1,186 @@
+package zap
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// RingBufferOptions configures buffering of entries below the levels of loggers in memory,
+// so that the detail before something goes wrong is not lost.
+//
+// Only entries of loggers created by RingBuffered, or by logging.WithContextField
+// with contexts of ContextWithRingBuffer, are buffered. Buffered entries are written to the outputs
+// when an Error level entry is written by a logger sharing the buffer, or by FlushRingBuffer.
+// Such loggers format entries below their levels, other loggers are not affected.
+// Entries buffered before the factory switches options are dropped, as the outputs they are for are closed.
+type RingBufferOptions struct {
+	// Size is the number of the latest entries kept per ring buffer.
+	// Zero or negative to disable buffering, as default.
+	Size int `json:"size,omitempty" yaml:"size,omitempty"`
+}
+
+// ringEntry is a buffered entry along with the core, which has the fields of the logger, to write it to.
+type ringEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+	// closing is the closing state of the outputs of the core.
+	closing *closingState
+}
+
+// ringBuffer keeps the latest entries.
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []ringEntry
+	// next is the index of the oldest entry once the buffer is full.
+	next int
+}
+
+// add adds the entry, dropping the oldest one if there are size entries already.
+func (r *ringBuffer) add(e ringEntry, size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < size {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// drain removes and returns the entries, the oldest first.
+func (r *ringBuffer) drain() []ringEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := append(r.entries[r.next:len(r.entries):len(r.entries)], r.entries[:r.next]...)
+	r.entries = nil
+	r.next = 0
+	return entries
+}
+
+// RingBuffered returns a child logger whose entries are buffered in its own ring buffer,
+// e.g. to keep the detail of a single request. Loggers derived from the child share its buffer.
+//
+// It takes effect with loggers created by NewFactory, when Options.RingBuffer is enabled.
+func RingBuffered(logger logging.Logger) logging.Logger {
+	return logger.WithField(newRingBufferField())
+}
+
+// ContextWithRingBuffer returns a context carrying a new ring buffer,
+// which loggers created by logging.WithContextField with the context share,
+// e.g. to keep the detail of a single request across the loggers handling it.
+//
+// It takes effect with loggers created by NewFactory, when Options.RingBuffer is enabled.
+func ContextWithRingBuffer(ctx context.Context) context.Context {
+	return logging.NewContext(ctx, newRingBufferField())
+}
+
+func newRingBufferField() logging.Field {
+	return field{typ: RingBufferType, val: &ringBuffer{}}
+}
+
+// FlushRingBuffer writes the entries buffered for the logger to the outputs.
+func FlushRingBuffer(logger logging.Logger) error {
+	zl, ok := logger.(*zapLogger)
+	if !ok {
+		return nil
+	}
+	if c, ok := zl.zap().Core().(*ringCore); ok {
+		return c.flush()
+	}
+	return nil
+}
+
+// ringCore buffers entries below the levels of loggers, and writes them before Error level entries.
+type ringCore struct {
+	zapcore.Core
+	options *Options
+	// closing is the closing state of the outputs, nil if not known.
+	closing *closingState
+	// name is the logger name, which the level is resolved by.
+	name string
+	// ring is the ring buffer of the logger created by RingBuffered, nil if entries are not buffered.
+	ring *ringBuffer
+	// override is the level of LevelOverride fields, nil if none.
+	override *logging.Level
+}
+
+func newRingCore(core zapcore.Core, options *Options, closing *closingState) *ringCore {
+	return &ringCore{Core: core, options: options, closing: closing}
+}
+
+// named returns a ringCore of the logger name.
+func (c *ringCore) named(name string) *ringCore {
+	clone := *c
+	clone.name = name
+	return &clone
+}
+
+// With adds the fields to the core, taking the ring buffer of RingBuffered and the level of LevelOverride from them.
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	rest := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if f.Type == zapcore.SkipType {
+			switch v := f.Interface.(type) {
+			case *ringBuffer:
+				clone.ring = v
+				continue
+			case levelOverride:
+				level := logging.Level(v)
+				clone.override = &level
+				continue
+			}
+		}
+		rest = append(rest, f)
+	}
+	clone.Core = c.Core.With(rest)
+	return &clone
+}
+
+// Check buffers entries below the level of the logger, and flushes the buffer before Error level entries.
+func (c *ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.ring == nil {
+		return c.Core.Check(ent, ce)
+	}
+	level := logging.Level(ent.Level)
+	if ent.Level > zapcore.ErrorLevel {
+		level = logging.ErrorLevel
+	}
+	if !c.options.level(c.name).Enabled(level) && (c.override == nil || !c.override.Enabled(level)) {
+		return ce.AddCore(ent, c)
+	}
+	if level == logging.ErrorLevel {
+		_ = c.flush()
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Write buffers the entry, which is checked to be below the level of the logger.
+func (c *ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.ring.add(ringEntry{core: c.Core, ent: ent, fields: fields, closing: c.closing}, c.options.RingBuffer.Size)
+	return nil
+}
+
+// flush writes the buffered entries to the outputs, dropping those of closed outputs.
+func (c *ringCore) flush() error {
+	if c.ring == nil {
+		return nil
+	}
+	var err error
+	for _, e := range c.ring.drain() {
+		if e.closing.isClosed() {
+			continue
+		}
+		write := e.core.Write
+		if h, ok := e.core.(*hookCore); ok {
+			write = h.writeThrough
+		}
+		err = multierr.Append(err, write(e.ent, e.fields))
+	}
+	return err
+}
@@ -0,0 +1,194 @@
+package zap
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRingBuffer_add(t *testing.T) {
+	tests := []struct {
+		name string
+		add  []string
+		want []string
+	}{
+		{name: "empty", add: nil, want: []string{}},
+		{name: "partial", add: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "full", add: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "wrapped", add: []string{"a", "b", "c", "d", "e"}, want: []string{"c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ringBuffer{}
+			for _, msg := range tt.add {
+				r.add(ringEntry{ent: zapcore.Entry{Message: msg}}, 3)
+			}
+			got := []string{}
+			for _, e := range r.drain() {
+				got = append(got, e.ent.Message)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Empty(t, r.drain())
+		})
+	}
+}
+
+func TestRingCore(t *testing.T) {
+	rec := &tRecordCore{LevelEnabler: zapcore.DebugLevel}
+	o := NewOptions(RingBuffer(RingBufferOptions{Size: 2})).Defaulted()
+	core := newRingCore(rec, o, nil).named("foo")
+	write := func(level zapcore.Level, msg string) {
+		ce := core.Check(zapcore.Entry{LoggerName: "foo", Level: level, Message: msg}, nil)
+		ce.Write(zap.Int("n", 1))
+	}
+	// entries are not buffered without ring buffers.
+	write(zapcore.DebugLevel, "x")
+	assert.Equal(t, []string{"x"}, rec.messages())
+	rec.entries, rec.fields = nil, nil
+
+	core = core.With([]zapcore.Field{mapZapField(newRingBufferField())}).(*ringCore)
+	write = func(level zapcore.Level, msg string) {
+		ce := core.Check(zapcore.Entry{LoggerName: "foo", Level: level, Message: msg}, nil)
+		ce.Write(zap.Int("n", 1))
+	}
+	write(zapcore.DebugLevel, "a")
+	write(zapcore.DebugLevel, "b")
+	write(zapcore.DebugLevel, "c")
+	write(zapcore.InfoLevel, "d")
+	assert.Equal(t, []string{"d"}, rec.messages())
+	write(zapcore.ErrorLevel, "e")
+	assert.Equal(t, []string{"d", "b", "c", "e"}, rec.messages())
+	assert.Equal(t, []zapcore.Field{zap.Int("n", 1)}, rec.fields[1])
+}
+
+func TestFlushRingBuffer(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-ringbuffer", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-ringbuffer://"),
+		Encoding("logfmt"),
+		DisableCaller(true),
+		RingBuffer(RingBufferOptions{Size: 10}),
+		Levels(map[string]logging.Level{"off": logging.OffLevel}),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	messages := func() []string {
+		var res []string
+		for _, ent := range sink.entries {
+			res = append(res, ent.Message)
+		}
+		return res
+	}
+
+	logger := factory.Logger("foo")
+	assert.False(t, logger.(*zapLogger).enabled(logging.DebugLevel))
+	request := RingBuffered(logger).WithField(logging.String("id", "1"))
+	assert.True(t, request.(*zapLogger).enabled(logging.DebugLevel))
+	logger.Debugw("a")
+	request.Debugw("b")
+	request.Errorw("c")
+	assert.Equal(t, []string{"b", "c"}, messages())
+	assert.True(t, strings.Contains(sink.lines[0], "id=1"))
+
+	// entries of loggers without ring buffers are not buffered.
+	assert.Nil(t, FlushRingBuffer(logger))
+	assert.Equal(t, []string{"b", "c"}, messages())
+
+	// entries of loggers turned off are buffered as well.
+	off := RingBuffered(factory.Logger("off"))
+	off.Errorw("d")
+	assert.Len(t, sink.entries, 2)
+	assert.Nil(t, FlushRingBuffer(off))
+	assert.Equal(t, []string{"b", "c", "d"}, messages())
+
+	assert.Nil(t, FlushRingBuffer(logging.NewNopLoggerFactory().Logger("foo")))
+}
+
+func TestRingBuffer_switchOptions(t *testing.T) {
+	var sinks []*tEntrySink
+	registerSink("test-ringbuffer-switch", func(u *url.URL, o *Options) (zap.Sink, error) {
+		sink := &tEntrySink{}
+		sinks = append(sinks, sink)
+		return sink, nil
+	})
+	options := func() *Options {
+		return NewOptions(OutputPaths("test-ringbuffer-switch://"), RingBuffer(RingBufferOptions{Size: 10}))
+	}
+	factory := NewFactory(options()).(*zapFactory)
+	defer factory.SwitchOptions(NewOptions())
+
+	request := RingBuffered(factory.Logger("foo"))
+	request.Debugw("a")
+	request.Infow("b")
+	assert.Len(t, sinks, 1)
+	factory.SwitchOptions(options())
+	assert.True(t, sinks[0].closed)
+
+	// Entries buffered for the closed outputs are dropped, rather than failing to write.
+	request.Debugw("c")
+	assert.Nil(t, FlushRingBuffer(request))
+	assert.Len(t, sinks, 2)
+	assert.Len(t, sinks[0].entries, 1)
+	assert.Len(t, sinks[1].entries, 1)
+	assert.Equal(t, "c", sinks[1].entries[0].Message)
+	assert.Empty(t, FactoryMetrics(factory).Snapshot()[0].WriteFailed)
+}
+
+func TestContextWithRingBuffer(t *testing.T) {
+	sink := &tEntrySink{}
+	registerSink("test-ringbuffer-context", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return sink, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-ringbuffer-context://"),
+		Encoding("logfmt"),
+		DisableCaller(true),
+		RingBuffer(RingBufferOptions{Size: 10}),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+
+	ctx := ContextWithRingBuffer(context.Background())
+	foo := logging.WithContextField(ctx, factory.Logger("foo"))
+	bar := logging.WithContextField(ctx, factory.Logger("bar"))
+	other := logging.WithContextField(ContextWithRingBuffer(context.Background()), factory.Logger("foo"))
+	foo.Debugw("a")
+	other.Debugw("b")
+	bar.Errorw("c")
+	var messages []string
+	for _, ent := range sink.entries {
+		messages = append(messages, ent.Message)
+	}
+	assert.Equal(t, []string{"a", "c"}, messages)
+	assert.Equal(t, "bar", sink.entries[1].LoggerName)
+}
+
+func TestRingBuffer_hooks(t *testing.T) {
+	registerSink("test-ringbuffer-hooks", func(u *url.URL, o *Options) (zap.Sink, error) {
+		return &tEntrySink{}, nil
+	})
+	factory := NewFactory(NewOptions(
+		OutputPaths("test-ringbuffer-hooks://"),
+		RingBuffer(RingBufferOptions{Size: 10}),
+	))
+	defer factory.(*zapFactory).SwitchOptions(NewOptions())
+	var messages []string
+	assert.Nil(t, AddHook(factory, Hook{Func: func(e Entry) error {
+		messages = append(messages, e.Message)
+		return nil
+	}, Level: logging.DebugLevel}))
+	request := RingBuffered(factory.Logger("foo"))
+	request.Debugw("a")
+	request.Infow("b")
+	// entries below the level are not passed to hooks while buffered.
+	assert.Equal(t, []string{"b"}, messages)
+	assert.Nil(t, FlushRingBuffer(request))
+	assert.Equal(t, []string{"b", "a"}, messages)
+}
@@ -0,0 +1,44 @@
+package zap
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// schema presets how entries are shaped for a log ingestion system, overriding FieldKeys.
+type schema struct {
+	// encoderConfig adjusts the encoder config built from Options.
+	encoderConfig func(config *zapcore.EncoderConfig)
+	// wrapCore wraps the core writing entries to outputs, to rewrite entries and fields.
+	wrapCore func(core zapcore.Core, o *Options) zapcore.Core
+}
+
+var schemas = map[string]schema{}
+
+// schema returns the schema configured in Options, nil if none.
+func (o *Options) schema() (*schema, error) {
+	if o.Schema == "" {
+		return nil, nil
+	}
+	s, ok := schemas[o.Schema]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", o.Schema)
+	}
+	return &s, nil
+}
+
+// splitNamespace splits the fields at the first namespace, all fields are nested if a namespace is open already.
+//
+// Cores of schemas write fields of their own, which must not be nested in namespaces of other fields.
+func splitNamespace(fields []zapcore.Field, open bool) (flat, nested []zapcore.Field) {
+	if open {
+		return nil, fields
+	}
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return fields[:i], fields[i:]
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,46 @@
+package zap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOptions_schema(t *testing.T) {
+	s, err := (&Options{}).schema()
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+	for _, name := range []string{"ecs", "gcp"} {
+		s, err = (&Options{Schema: name}).schema()
+		assert.Nil(t, err)
+		assert.NotNil(t, s)
+	}
+	_, err = (&Options{Schema: "unknown"}).schema()
+	assert.NotNil(t, err)
+	_, err = NewOptions(Schema("unknown")).openOutputs()
+	assert.NotNil(t, err)
+}
+
+func Test_splitNamespace(t *testing.T) {
+	a, b, ns := zap.String("a", "1"), zap.String("b", "2"), zap.Namespace("ns")
+	tests := []struct {
+		name   string
+		fields []zapcore.Field
+		open   bool
+		flat   []zapcore.Field
+		nested []zapcore.Field
+	}{
+		{name: "flat", fields: []zapcore.Field{a, b}, flat: []zapcore.Field{a, b}},
+		{name: "namespace", fields: []zapcore.Field{a, ns, b}, flat: []zapcore.Field{a}, nested: []zapcore.Field{ns, b}},
+		{name: "open", fields: []zapcore.Field{a, b}, open: true, nested: []zapcore.Field{a, b}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flat, nested := splitNamespace(tt.fields, tt.open)
+			assert.Equal(t, tt.flat, flat)
+			assert.Equal(t, tt.nested, nested)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package zap
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	registerSink("syslog", newSyslogSink)
+}
+
+// syslogFacilities are syslog facility codes by names.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverity maps zap levels, which logging levels map to, to syslog severities.
+func syslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7 // debug
+	case zapcore.InfoLevel:
+		return 6 // informational
+	case zapcore.WarnLevel:
+		return 4 // warning
+	case zapcore.ErrorLevel:
+		return 3 // error
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2 // critical
+	case zapcore.FatalLevel:
+		return 1 // alert
+	default:
+		return 5 // notice
+	}
+}
+
+// syslogSink writes entries to a syslog daemon.
+//
+// The output path is like:
+//
+//	syslog:///dev/log?facility=local0&app=demo
+//	syslog://127.0.0.1:514?network=tcp&format=rfc3164
+//
+// Query parameters:
+//   - network: "unixgram", "unix", "udp" or "tcp". Unix sockets when the host is empty, "udp" otherwise as default.
+//     For unix sockets without the network, "unixgram" is tried before "unix".
+//     Entries are newline-terminated on stream sockets, except rfc5424 ones over tcp, which are octet-counted.
+//   - format: "rfc5424" or "rfc3164". "rfc5424" as default.
+//   - facility: facility name like "local0", or facility code. "user" as default.
+//   - app: app-name, or tag for rfc3164. The executable name as default.
+//   - hostname: hostname of the log. The host name reported by the kernel as default.
+//   - sd_id: SD-ID of the structured data element containing global fields. "fields@32473" as default.
+//
+// Global fields are written as structured data with rfc5424, the encoded entries are written as messages.
+type syslogSink struct {
+	conn     *redialConn
+	hostname string
+	app      string
+	sdata    string
+	network  string
+	facility int
+	pid      int
+	rfc3164  bool
+}
+
+func newSyslogSink(u *url.URL, o *Options) (zap.Sink, error) {
+	query := u.Query()
+	s := &syslogSink{
+		app:      query.Get("app"),
+		hostname: query.Get("hostname"),
+		network:  query.Get("network"),
+		facility: 1,
+		pid:      os.Getpid(),
+	}
+	switch format := query.Get("format"); format {
+	case "", "rfc5424":
+	case "rfc3164":
+		s.rfc3164 = true
+	default:
+		return nil, fmt.Errorf("unknown syslog format: %q", format)
+	}
+	if facility := query.Get("facility"); facility != "" {
+		code, ok := syslogFacilities[strings.ToLower(facility)]
+		if !ok {
+			var err error
+			code, err = strconv.Atoi(facility)
+			if err != nil || code < 0 || code > 23 {
+				return nil, fmt.Errorf("unknown syslog facility: %q", facility)
+			}
+		}
+		s.facility = code
+	}
+	if s.app == "" {
+		s.app = filepath.Base(os.Args[0])
+	}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	sdID := query.Get("sd_id")
+	if sdID == "" {
+		sdID = "fields@32473"
+	}
+	s.sdata = syslogStructuredData(sdID, o.globalFields)
+
+	address := u.Host
+	if address == "" {
+		address = u.Path
+		if s.network == "" {
+			s.network = "unixgram"
+			if conn, err := net.Dial(s.network, address); err != nil {
+				s.network = "unix"
+			} else {
+				_ = conn.Close()
+			}
+		}
+	} else if s.network == "" {
+		s.network = "udp"
+	}
+	switch s.network {
+	case "unixgram", "unix", "udp", "tcp", "udp4", "udp6", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unknown syslog network: %q", s.network)
+	}
+	conn, err := newRedialConn(func() (net.Conn, error) {
+		return net.DialTimeout(s.network, address, 5*time.Second)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return s, nil
+}
+
+// syslogStructuredData formats the fields as a rfc5424 structured data element.
+func syslogStructuredData(id string, fields []logging.Field) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	sb := strings.Builder{}
+	sb.WriteByte('[')
+	sb.WriteString(syslogName(id, 32))
+	for _, f := range fields {
+		sb.WriteByte(' ')
+		sb.WriteString(syslogName(f.Key(), 32))
+		sb.WriteString(`="`)
+		value := fmt.Sprint(f.Value())
+		for _, r := range value {
+			if r == '"' || r == '\\' || r == ']' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		}
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// syslogName sanitizes the name to be printable US-ASCII without '=', ' ', ']' and '"', limited in length.
+func syslogName(name string, limit int) string {
+	if name == "" {
+		return "-"
+	}
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name) && len(b) < limit; i++ {
+		c := name[i]
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+func (s *syslogSink) Write(p []byte) (int, error) {
+	if err := s.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now()}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *syslogSink) WriteEntry(ent zapcore.Entry, p []byte) error {
+	_, err := s.conn.Write(s.format(ent, bytes.TrimRight(p, "\r\n")))
+	return err
+}
+
+func (s *syslogSink) format(ent zapcore.Entry, msg []byte) []byte {
+	pri := s.facility*8 + syslogSeverity(ent.Level)
+	buf := bytes.Buffer{}
+	if s.rfc3164 {
+		fmt.Fprintf(&buf, "<%d>%s %s %s[%d]: ", pri, ent.Time.Format(time.Stamp), syslogName(s.hostname, 255),
+			syslogName(s.app, 32), s.pid)
+	} else {
+		msgID := "-"
+		if ent.LoggerName != "" {
+			msgID = syslogName(ent.LoggerName, 32)
+		}
+		fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s %s ", pri, ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+			syslogName(s.hostname, 255), syslogName(s.app, 48), s.pid, msgID, s.sdata)
+	}
+	buf.Write(msg)
+	if s.network == "unixgram" || strings.HasPrefix(s.network, "udp") {
+		return buf.Bytes()
+	}
+	// Stream transports need framing: octet counting of RFC 6587 for rfc5424 over tcp,
+	// newlines otherwise, as local daemons expect on unix stream sockets.
+	if s.rfc3164 || s.network == "unix" {
+		buf.WriteByte('\n')
+		return buf.Bytes()
+	}
+	return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+}
+
+func (s *syslogSink) Sync() error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.conn.Close()
+}
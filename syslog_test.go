@@ -0,0 +1,161 @@
+package zap
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimi-go/logging"
+	"go.uber.org/zap/zapcore"
+)
+
+func listenUnixgram(t *testing.T, path string) *net.UnixConn {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func Test_syslogSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn := listenUnixgram(t, path)
+	defer func() {
+		_ = conn.Close()
+	}()
+	factory := NewFactory(NewOptions(
+		OutputPaths("syslog://"+path+"?facility=local0&app=demo&hostname=host"),
+		GlobalFields(logging.String("service", `a"b]`)),
+	))
+	logger := factory.Logger("foo")
+	logger.Infow("hello", logging.String("k", "v"))
+	msg := readDatagram(t, conn)
+	t.Log(msg)
+	assert.True(t, strings.HasPrefix(msg, "<134>1 "), msg)
+	assert.Contains(t, msg, ` host demo `)
+	assert.Contains(t, msg, ` foo [fields@32473 service="a\"b\]"] {`)
+	assert.Contains(t, msg, `"msg":"hello"`)
+	assert.False(t, strings.HasSuffix(msg, "\n"))
+
+	logger.Error("oops")
+	assert.True(t, strings.HasPrefix(readDatagram(t, conn), "<131>1 "))
+
+	// The daemon restarts.
+	_ = conn.Close()
+	_ = os.Remove(path)
+	conn = listenUnixgram(t, path)
+	logger.Warn("again")
+	msg = readDatagram(t, conn)
+	assert.True(t, strings.HasPrefix(msg, "<132>1 "), msg)
+	assert.Contains(t, msg, `"msg":"again"`)
+}
+
+func Test_syslogSink_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	lines := make(chan string, 2)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = c.Close()
+		}()
+		scanner := bufio.NewScanner(c)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	u, _ := url.Parse("syslog://" + ln.Addr().String() + "?network=tcp&format=rfc3164&facility=3&app=demo")
+	sink, err := newSyslogSink(u, NewOptions())
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer func() {
+		_ = sink.Close()
+	}()
+	now := time.Date(2022, 7, 1, 8, 0, 0, 0, time.Local)
+	assert.Nil(t, sink.(entryWriter).WriteEntry(zapcore.Entry{Level: zapcore.DebugLevel, Time: now}, []byte("hello\n")))
+	n, err := sink.Write([]byte("world"))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	assert.Nil(t, sink.Sync())
+	line := <-lines
+	assert.True(t, strings.HasPrefix(line, "<31>Jul  1 08:00:00 "), line)
+	assert.True(t, strings.HasSuffix(line, "]: hello"), line)
+	assert.Contains(t, line, " demo[")
+	line = <-lines
+	assert.True(t, strings.HasPrefix(line, "<30>"), line)
+}
+
+func Test_syslogSink_format(t *testing.T) {
+	s := &syslogSink{facility: 1, hostname: "h", app: "a", pid: 1, sdata: "-", network: "tcp"}
+	now := time.Date(2022, 7, 1, 8, 0, 0, 0, time.UTC)
+	msg := string(s.format(zapcore.Entry{Level: zapcore.FatalLevel, Time: now}, []byte("m")))
+	assert.Equal(t, "44 <9>1 2022-07-01T08:00:00.000000Z h a 1 - - m", msg)
+	s.network = "unix"
+	msg = string(s.format(zapcore.Entry{Level: zapcore.FatalLevel, Time: now}, []byte("m")))
+	assert.Equal(t, "<9>1 2022-07-01T08:00:00.000000Z h a 1 - - m\n", msg)
+	s.network = "udp"
+	msg = string(s.format(zapcore.Entry{Level: zapcore.PanicLevel, Time: now, LoggerName: "a b"}, []byte("m")))
+	assert.Equal(t, "<10>1 2022-07-01T08:00:00.000000Z h a 1 a_b - m", msg)
+	assert.Equal(t, 5, syslogSeverity(zapcore.Level(99)))
+}
+
+func Test_newSyslogSink_error(t *testing.T) {
+	for _, raw := range []string{
+		"syslog://127.0.0.1:514?format=bad",
+		"syslog://127.0.0.1:514?facility=bad",
+		"syslog://127.0.0.1:514?facility=24",
+		"syslog://127.0.0.1:514?network=ip",
+		"syslog:///not/exist.sock",
+	} {
+		u, _ := url.Parse(raw)
+		_, err := newSyslogSink(u, NewOptions())
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func Test_syslogName(t *testing.T) {
+	assert.Equal(t, "-", syslogName("", 32))
+	assert.Equal(t, "a_b_c_d_e", syslogName("a b=c]d\"e", 32))
+	assert.Equal(t, "ab", syslogName("abc", 2))
+}
+
+func Test_syslogSink_Close(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn := listenUnixgram(t, path)
+	defer func() {
+		_ = conn.Close()
+	}()
+	u, _ := url.Parse("syslog://" + path)
+	sink, err := newSyslogSink(u, NewOptions())
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Close())
+	assert.Nil(t, sink.Close())
+	// Writing after closing does not reconnect.
+	_, err = sink.Write([]byte("hello"))
+	assert.ErrorIs(t, err, net.ErrClosed)
+}